@@ -0,0 +1,87 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package testutil provides the helpers shared by the unit tests of the framework.
+package testutil
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/client-go/rest"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+)
+
+// FakeClient is a klient.Client backed by a controller runtime client, usually a fake one
+// returned by NewFakeClient
+type FakeClient struct {
+	Client cr.Client
+}
+
+// NewFakeClient returns a FakeClient backed by a fake controller runtime client holding the
+// objects, its operations being intercepted by funcs. Unless intercepted, the List operations
+// ignore the empty field selector set by Resources.List, which the fake client rejects.
+func NewFakeClient(funcs interceptor.Funcs, objs ...cr.Object) FakeClient {
+	if funcs.List == nil {
+		funcs.List = func(ctx context.Context, c cr.WithWatch, list cr.ObjectList, opts ...cr.ListOption) error {
+			options := (&cr.ListOptions{}).ApplyOptions(opts)
+			if options.FieldSelector != nil && options.FieldSelector.Empty() {
+				options.FieldSelector = nil
+			}
+			return c.List(ctx, list, options)
+		}
+	}
+	return FakeClient{Client: fake.NewClientBuilder().WithInterceptorFuncs(funcs).WithObjects(objs...).Build()}
+}
+
+// RESTConfig returns an empty *rest.Config
+func (c FakeClient) RESTConfig() *rest.Config {
+	return &rest.Config{}
+}
+
+// Resources returns the resources of the client, in the namespace if provided
+func (c FakeClient) Resources(namespace ...string) *resources.Resources {
+	res := resources.NewFromClient(c.RESTConfig(), c.Client)
+	if len(namespace) > 0 {
+		return res.WithNamespace(namespace[0])
+	}
+	return res
+}
+
+// Outcome is the outcome of a test run by RunIsolated
+type Outcome struct {
+	Failed  bool
+	Skipped bool
+}
+
+// RunIsolated runs f as a separate top-level test and returns its outcome, so that a failure
+// expected by the calling test, e.g. the one of a failing feature, does not fail it. The
+// subtests of f are reported under name.
+func RunIsolated(name string, f func(t *testing.T)) Outcome {
+	var outcome Outcome
+	testing.RunTests(func(_, _ string) (bool, error) { return true, nil }, []testing.InternalTest{{
+		Name: name,
+		F: func(t *testing.T) {
+			defer func() { outcome = Outcome{Failed: t.Failed(), Skipped: t.Skipped()} }()
+			f(t)
+		},
+	}})
+	return outcome
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package klient
+
+import (
+	"context"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+)
+
+// The operations reported by a counting client, see NewCountingClient
+const (
+	OperationGet         = "get"
+	OperationList        = "list"
+	OperationCreate      = "create"
+	OperationUpdate      = "update"
+	OperationPatch       = "patch"
+	OperationDelete      = "delete"
+	OperationDeleteAllOf = "deleteAllOf"
+)
+
+// NewCountingClient returns a Client performing its operations with the client c and calling
+// count with the kind of the objects and the name of the operation, such as OperationGet, for
+// each Get, List, Create, Update, Patch, Delete and DeleteAllOf operation of its Resources,
+// whether it succeeds or not, e.g. to report the kinds exercised by a test suite. The kind of a
+// list is the kind of its items. The operations themselves are left unchanged, and the ones on
+// the subresources of the objects are not counted. Wrapping a client already counting its
+// operations returns it unchanged, so that the operations are counted once.
+func NewCountingClient(c Client, count func(gvk schema.GroupVersionKind, operation string)) Client {
+	inner := c.Resources().GetControllerRuntimeClient()
+	if wraps[*countingCRClient](inner) {
+		return c
+	}
+	return &countingClient{
+		cfg:    c.RESTConfig(),
+		client: &countingCRClient{Client: inner, count: count},
+	}
+}
+
+// countingClient is the Client returned by NewCountingClient
+type countingClient struct {
+	cfg    *rest.Config
+	client cr.Client
+}
+
+// RESTConfig returns the *rest.Config value associated with this client.
+func (c *countingClient) RESTConfig() *rest.Config {
+	return c.cfg
+}
+
+// Resources returns *Resources value to access CRUD object operations, the operations
+// being counted. It takes 0 or, at most, 1 namespace, or panics.
+func (c *countingClient) Resources(namespace ...string) *resources.Resources {
+	res := resources.NewFromClient(c.cfg, c.client)
+	switch len(namespace) {
+	case 0:
+		return res
+	case 1:
+		return res.WithNamespace(namespace[0])
+	default:
+		panic("too many namespaces provided")
+	}
+}
+
+// countingCRClient is a controller runtime client counting its operations per kind
+type countingCRClient struct {
+	cr.Client
+	count func(gvk schema.GroupVersionKind, operation string)
+}
+
+func (c *countingCRClient) unwrap() cr.Client {
+	return c.Client
+}
+
+func (c *countingCRClient) rewrap(inner cr.Client) cr.Client {
+	return &countingCRClient{Client: inner, count: c.count}
+}
+
+// record counts the operation on the object, or the list of objects
+func (c *countingCRClient) record(obj runtime.Object, operation string) {
+	c.count(objectKind(c.Client, obj), operation)
+}
+
+// objectKind returns the kind of the object according to the scheme of the client, the kind
+// of a list being the kind of its items
+func objectKind(c cr.Client, obj runtime.Object) schema.GroupVersionKind {
+	gvk, err := c.GroupVersionKindFor(obj)
+	if err != nil {
+		// the kind is not registered in the scheme, e.g. for unstructured objects
+		gvk = obj.GetObjectKind().GroupVersionKind()
+	}
+	if _, isList := obj.(cr.ObjectList); isList {
+		gvk.Kind = strings.TrimSuffix(gvk.Kind, "List")
+	}
+	return gvk
+}
+
+func (c *countingCRClient) Get(ctx context.Context, key cr.ObjectKey, obj cr.Object, opts ...cr.GetOption) error {
+	c.record(obj, OperationGet)
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+func (c *countingCRClient) List(ctx context.Context, list cr.ObjectList, opts ...cr.ListOption) error {
+	c.record(list, OperationList)
+	return c.Client.List(ctx, list, opts...)
+}
+
+func (c *countingCRClient) Create(ctx context.Context, obj cr.Object, opts ...cr.CreateOption) error {
+	c.record(obj, OperationCreate)
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *countingCRClient) Update(ctx context.Context, obj cr.Object, opts ...cr.UpdateOption) error {
+	c.record(obj, OperationUpdate)
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func (c *countingCRClient) Patch(ctx context.Context, obj cr.Object, patch cr.Patch, opts ...cr.PatchOption) error {
+	c.record(obj, OperationPatch)
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func (c *countingCRClient) Delete(ctx context.Context, obj cr.Object, opts ...cr.DeleteOption) error {
+	c.record(obj, OperationDelete)
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+func (c *countingCRClient) DeleteAllOf(ctx context.Context, obj cr.Object, opts ...cr.DeleteAllOfOption) error {
+	c.record(obj, OperationDeleteAllOf)
+	return c.Client.DeleteAllOf(ctx, obj, opts...)
+}
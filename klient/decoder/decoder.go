@@ -60,7 +60,7 @@ type HandlerFunc func(ctx context.Context, obj k8s.Object) error
 
 // DecodeEachFile resolves files at the filesystem matching the pattern, decoding JSON or YAML files. Supports multi-document files.
 //
-// If handlerFn returns an error, decoding is halted.
+// If handlerFn returns an error or ctx is done, decoding is halted.
 // Options may be provided to configure the behavior of the decoder.
 func DecodeEachFile(ctx context.Context, fsys fs.FS, pattern string, handlerFn HandlerFunc, options ...DecodeOption) error {
 	files, err := fs.Glob(fsys, pattern)
@@ -68,6 +68,9 @@ func DecodeEachFile(ctx context.Context, fsys fs.FS, pattern string, handlerFn H
 		return err
 	}
 	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		f, err := fsys.Open(file)
 		if err != nil {
 			return err
@@ -112,11 +115,15 @@ func DeleteWithManifestDir(ctx context.Context, r *resources.Resources, dirPath,
 // Decode a stream of documents of any Kind using either the innate typing of the scheme.
 // Falls back to the unstructured.Unstructured type if a matching type cannot be found for the Kind.
 //
-// If handlerFn returns an error, decoding is halted.
+// If handlerFn returns an error or ctx is done, decoding is halted.
 // Options may be provided to configure the behavior of the decoder.
 func DecodeEach(ctx context.Context, manifest io.Reader, handlerFn HandlerFunc, options ...DecodeOption) error {
 	decoder := yaml.NewYAMLReader(bufio.NewReader(manifest))
 	for {
+		// stop decoding promptly once the context is done
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		b, err := decoder.Read()
 		if errors.Is(err, io.EOF) {
 			break
@@ -18,6 +18,7 @@ package decoder_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -217,6 +218,23 @@ func TestDecodeEach(t *testing.T) {
 	}
 }
 
+func TestDecodeEach_CancelledContext(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "example-multidoc-1.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	err = decoder.DecodeEach(ctx, f, func(ctx context.Context, obj k8s.Object) error {
+		t.Error("handler called with a cancelled context")
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled error, got: %v", err)
+	}
+}
+
 func TestDecodeAll(t *testing.T) {
 	for _, file := range []string{"example-multidoc-1.yaml", "example-multidoc-emptyitemcomment.yaml"} {
 		t.Run(fmt.Sprintf("Testing multi doc with %s", file), func(t *testing.T) {
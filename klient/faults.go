@@ -0,0 +1,190 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package klient
+
+import (
+	"context"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+)
+
+// FaultRule describes the error injected in some operations of a client, see WithFaultInjection
+type FaultRule struct {
+	// Operation is the operation failing, such as OperationUpdate, any operation when empty
+	Operation string
+	// GVK is the kind of the objects whose operations fail, its empty group, version or kind
+	// matching any value. The kind of a list is the kind of its items.
+	GVK schema.GroupVersionKind
+	// Occurrence is the operation failing among the ones matched by the rule, starting at 1,
+	// e.g. 2 for the second one. All the matched operations fail when it is 0.
+	Occurrence int
+	// Err is the error returned by the failing operations, e.g. one of k8s.io/apimachinery/pkg/api/errors
+	Err error
+}
+
+// matches indicates if the rule applies to the operation on an object of the kind
+func (r FaultRule) matches(gvk schema.GroupVersionKind, operation string) bool {
+	return (r.Operation == "" || r.Operation == operation) &&
+		(r.GVK.Group == "" || r.GVK.Group == gvk.Group) &&
+		(r.GVK.Version == "" || r.GVK.Version == gvk.Version) &&
+		(r.GVK.Kind == "" || r.GVK.Kind == gvk.Kind)
+}
+
+// WithFaultInjection returns a Client performing its operations with the client c, except the
+// Get, List, Create, Update, Patch, Delete and DeleteAllOf operations of its Resources matched
+// by one of the rules, which fail with the error of the first matching rule without reaching
+// the API server. The operations are counted per rule, so that e.g. the following client fails
+// the second update of a ConfigMap with a conflict:
+//
+//	klient.WithFaultInjection(cfg.Client(), klient.FaultRule{
+//		Operation:  klient.OperationUpdate,
+//		GVK:        corev1.SchemeGroupVersion.WithKind("ConfigMap"),
+//		Occurrence: 2,
+//		Err:        apierrors.NewConflict(corev1.Resource("configmaps"), "name", errors.New("injected")),
+//	})
+//
+// It can be set as the client of an envconf.Config with its WithClient method. Note that a
+// client retrying the operations failing with a transient error, see NewRetryingClient, retries
+// the injected errors when it wraps the returned client.
+func WithFaultInjection(c Client, rules ...FaultRule) Client {
+	return &faultClient{
+		cfg: c.RESTConfig(),
+		client: &faultCRClient{
+			Client: c.Resources().GetControllerRuntimeClient(),
+			rules:  rules,
+			mu:     &sync.Mutex{},
+			counts: make([]int, len(rules)),
+		},
+	}
+}
+
+// faultClient is the Client returned by WithFaultInjection
+type faultClient struct {
+	cfg    *rest.Config
+	client cr.Client
+}
+
+// RESTConfig returns the *rest.Config value associated with this client.
+func (c *faultClient) RESTConfig() *rest.Config {
+	return c.cfg
+}
+
+// Resources returns *Resources value to access CRUD object operations, the operations matched
+// by the fault rules failing. It takes 0 or, at most, 1 namespace, or panics.
+func (c *faultClient) Resources(namespace ...string) *resources.Resources {
+	res := resources.NewFromClient(c.cfg, c.client)
+	switch len(namespace) {
+	case 0:
+		return res
+	case 1:
+		return res.WithNamespace(namespace[0])
+	default:
+		panic("too many namespaces provided")
+	}
+}
+
+// faultCRClient is a controller runtime client failing the operations matched by its rules
+type faultCRClient struct {
+	cr.Client
+	rules []FaultRule
+
+	// mu guards the counts of the operations matched by each rule, shared by the copies of the client
+	mu     *sync.Mutex
+	counts []int
+}
+
+func (c *faultCRClient) unwrap() cr.Client {
+	return c.Client
+}
+
+func (c *faultCRClient) rewrap(inner cr.Client) cr.Client {
+	return &faultCRClient{Client: inner, rules: c.rules, mu: c.mu, counts: c.counts}
+}
+
+// fault counts the operation on the object, or the list of objects, for each rule matching it and
+// returns the error of the first rule failing it, if any
+func (c *faultCRClient) fault(obj runtime.Object, operation string) error {
+	gvk := objectKind(c.Client, obj)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var err error
+	for i, rule := range c.rules {
+		if !rule.matches(gvk, operation) {
+			continue
+		}
+		c.counts[i]++
+		if err == nil && (rule.Occurrence == 0 || rule.Occurrence == c.counts[i]) {
+			err = rule.Err
+		}
+	}
+	return err
+}
+
+func (c *faultCRClient) Get(ctx context.Context, key cr.ObjectKey, obj cr.Object, opts ...cr.GetOption) error {
+	if err := c.fault(obj, OperationGet); err != nil {
+		return err
+	}
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+func (c *faultCRClient) List(ctx context.Context, list cr.ObjectList, opts ...cr.ListOption) error {
+	if err := c.fault(list, OperationList); err != nil {
+		return err
+	}
+	return c.Client.List(ctx, list, opts...)
+}
+
+func (c *faultCRClient) Create(ctx context.Context, obj cr.Object, opts ...cr.CreateOption) error {
+	if err := c.fault(obj, OperationCreate); err != nil {
+		return err
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *faultCRClient) Update(ctx context.Context, obj cr.Object, opts ...cr.UpdateOption) error {
+	if err := c.fault(obj, OperationUpdate); err != nil {
+		return err
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func (c *faultCRClient) Patch(ctx context.Context, obj cr.Object, patch cr.Patch, opts ...cr.PatchOption) error {
+	if err := c.fault(obj, OperationPatch); err != nil {
+		return err
+	}
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func (c *faultCRClient) Delete(ctx context.Context, obj cr.Object, opts ...cr.DeleteOption) error {
+	if err := c.fault(obj, OperationDelete); err != nil {
+		return err
+	}
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+func (c *faultCRClient) DeleteAllOf(ctx context.Context, obj cr.Object, opts ...cr.DeleteAllOfOption) error {
+	if err := c.fault(obj, OperationDeleteAllOf); err != nil {
+		return err
+	}
+	return c.Client.DeleteAllOf(ctx, obj, opts...)
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package klient
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"sigs.k8s.io/e2e-framework/internal/testutil"
+)
+
+func TestWithFaultInjection(t *testing.T) {
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "default"}}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "secret", Namespace: "default"}}
+	conflict := apierrors.NewConflict(corev1.Resource("configmaps"), cm.Name, errors.New("injected"))
+	unavailable := apierrors.NewServiceUnavailable("injected")
+
+	c := WithFaultInjection(testutil.NewFakeClient(interceptor.Funcs{}, cm, secret),
+		FaultRule{Operation: OperationUpdate, GVK: corev1.SchemeGroupVersion.WithKind("ConfigMap"), Occurrence: 2, Err: conflict},
+		FaultRule{Operation: OperationList, GVK: corev1.SchemeGroupVersion.WithKind("Secret"), Err: unavailable},
+	)
+	res := c.Resources()
+	ctx := context.TODO()
+
+	for i, want := range []error{nil, conflict, nil} {
+		if err := res.Update(ctx, cm); !errors.Is(err, want) {
+			t.Errorf("update %d of the config map: got error %v, want %v", i+1, err, want)
+		}
+	}
+	if err := res.Update(ctx, secret); err != nil {
+		t.Errorf("update of the secret: unexpected error %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := res.List(ctx, &corev1.SecretList{}); !errors.Is(err, unavailable) {
+			t.Errorf("list %d of the secrets: got error %v, want %v", i+1, err, unavailable)
+		}
+	}
+	if err := res.List(ctx, &corev1.ConfigMapList{}); err != nil {
+		t.Errorf("list of the config maps: unexpected error %v", err)
+	}
+}
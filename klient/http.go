@@ -0,0 +1,301 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package klient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+)
+
+// endpointTimeout bounds each request of HTTPGet, so that an unreachable endpoint does not
+// prevent the next ones from being tried
+const endpointTimeout = 10 * time.Second
+
+// HTTPGet performs an HTTP GET request of path on a Service or an Ingress, obj being a *corev1.Service
+// or a *networkingv1.Ingress identified by its name and namespace, and returns the response. The body
+// of the response is read before returning, so that it remains readable once the endpoint is released.
+//
+// As how a Service can be reached from the machine running the tests depends on the cluster, the
+// following endpoints of the Service are tried in order, until one of them responds:
+//
+//   - the address of its load balancer, for a Service of type LoadBalancer provisioned by the provider
+//   - the address of a node along with its node port, e.g. on kind where the nodes are reachable
+//   - a port-forward to a ready pod selected by the Service, e.g. for a Service of type ClusterIP
+//
+// For a Service, port is the port of the Service. For an Ingress, the address of its load balancer is
+// tried first, port being the port it listens on, usually 80, and the Host header being set to the host
+// of its first rule. The endpoints of the Service backing its default backend, or its first path
+// otherwise, are tried next.
+func HTTPGet(ctx context.Context, cfg *rest.Config, obj k8s.Object, port int32, path string) (*http.Response, error) {
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("http get: %w", err)
+	}
+	var endpoints []httpEndpoint
+	switch obj.(type) {
+	case *corev1.Service:
+		endpoints, err = serviceEndpoints(ctx, cfg, clientset, obj.GetNamespace(), obj.GetName(), networkingv1.ServiceBackendPort{Number: port})
+	case *networkingv1.Ingress:
+		endpoints, err = ingressEndpoints(ctx, cfg, clientset, obj.GetNamespace(), obj.GetName(), port)
+	default:
+		return nil, fmt.Errorf("http get: unsupported type %T, expected a Service or an Ingress", obj)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("http get: %w", err)
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	var errs []error
+	for _, endpoint := range endpoints {
+		resp, err := endpoint.get(ctx, path)
+		if err == nil {
+			return resp, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", endpoint.name, err))
+	}
+	return nil, fmt.Errorf("http get: no endpoint of %T %s/%s responded: %w", obj, obj.GetNamespace(), obj.GetName(), errors.Join(errs...))
+}
+
+// httpEndpoint is an endpoint a Service or an Ingress can be reached at
+type httpEndpoint struct {
+	// name describes the endpoint in errors
+	name string
+	// host is the Host header of the requests, if set
+	host string
+	// resolve returns the base URL of the endpoint and a function releasing it
+	resolve func(ctx context.Context) (string, func(), error)
+}
+
+// addressEndpoint returns the endpoint reachable at the address and port
+func addressEndpoint(kind, address string, port int32) httpEndpoint {
+	baseURL := "http://" + net.JoinHostPort(address, strconv.Itoa(int(port)))
+	return httpEndpoint{
+		name: fmt.Sprintf("%s %s", kind, baseURL),
+		resolve: func(context.Context) (string, func(), error) {
+			return baseURL, func() {}, nil
+		},
+	}
+}
+
+func (ep httpEndpoint) get(ctx context.Context, path string) (*http.Response, error) {
+	baseURL, release, err := ep.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if ep.host != "" {
+		req.Host = ep.host
+	}
+	resp, err := (&http.Client{Timeout: endpointTimeout}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// serviceEndpoints returns the endpoints the port of the Service can be reached at, in order of preference
+func serviceEndpoints(ctx context.Context, cfg *rest.Config, clientset kubernetes.Interface, namespace, name string, port networkingv1.ServiceBackendPort) ([]httpEndpoint, error) {
+	svc, err := clientset.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var svcPort *corev1.ServicePort
+	for i, p := range svc.Spec.Ports {
+		if (port.Name != "" && p.Name == port.Name) || (port.Name == "" && p.Port == port.Number) {
+			svcPort = &svc.Spec.Ports[i]
+			break
+		}
+	}
+	if svcPort == nil {
+		return nil, fmt.Errorf("service %s/%s has no port %s", namespace, name, backendPortString(port))
+	}
+
+	var endpoints []httpEndpoint
+	if svc.Spec.Type == corev1.ServiceTypeLoadBalancer {
+		for _, lb := range svc.Status.LoadBalancer.Ingress {
+			if address := loadBalancerAddress(lb); address != "" {
+				endpoints = append(endpoints, addressEndpoint("load balancer", address, svcPort.Port))
+			}
+		}
+	}
+	if svcPort.NodePort != 0 {
+		nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		if address := nodeAddress(nodes.Items); address != "" {
+			endpoints = append(endpoints, addressEndpoint("node port", address, svcPort.NodePort))
+		}
+	}
+	endpoints = append(endpoints, httpEndpoint{
+		name: fmt.Sprintf("port-forward to service %s/%s", namespace, name),
+		resolve: func(ctx context.Context) (string, func(), error) {
+			pod, targetPort, err := servicePod(ctx, clientset, svc, svcPort)
+			if err != nil {
+				return "", nil, err
+			}
+			localPort, stop, err := portForward(ctx, cfg, clientset, namespace, pod, targetPort)
+			if err != nil {
+				return "", nil, err
+			}
+			return fmt.Sprintf("http://localhost:%d", localPort), stop, nil
+		},
+	})
+	return endpoints, nil
+}
+
+// ingressEndpoints returns the endpoints the Ingress can be reached at, in order of preference
+func ingressEndpoints(ctx context.Context, cfg *rest.Config, clientset kubernetes.Interface, namespace, name string, port int32) ([]httpEndpoint, error) {
+	ingress, err := clientset.NetworkingV1().Ingresses(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var host string
+	if len(ingress.Spec.Rules) > 0 {
+		host = ingress.Spec.Rules[0].Host
+	}
+	var endpoints []httpEndpoint
+	for _, lb := range ingress.Status.LoadBalancer.Ingress {
+		address := lb.IP
+		if address == "" {
+			address = lb.Hostname
+		}
+		if address != "" {
+			endpoint := addressEndpoint("ingress load balancer", address, port)
+			endpoint.host = host
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+
+	backend := ingress.Spec.DefaultBackend
+	if backend == nil && len(ingress.Spec.Rules) > 0 && ingress.Spec.Rules[0].HTTP != nil && len(ingress.Spec.Rules[0].HTTP.Paths) > 0 {
+		backend = &ingress.Spec.Rules[0].HTTP.Paths[0].Backend
+	}
+	if backend == nil || backend.Service == nil {
+		if len(endpoints) == 0 {
+			return nil, fmt.Errorf("ingress %s/%s has neither a load balancer address nor a backend service", namespace, name)
+		}
+		return endpoints, nil
+	}
+	svcEndpoints, err := serviceEndpoints(ctx, cfg, clientset, namespace, backend.Service.Name, backend.Service.Port)
+	if err != nil {
+		return nil, err
+	}
+	return append(endpoints, svcEndpoints...), nil
+}
+
+// loadBalancerAddress returns the IP or hostname of the load balancer
+func loadBalancerAddress(lb corev1.LoadBalancerIngress) string {
+	if lb.IP != "" {
+		return lb.IP
+	}
+	return lb.Hostname
+}
+
+// nodeAddress returns the address of the first node having one, external addresses being preferred
+func nodeAddress(nodes []corev1.Node) string {
+	for _, addressType := range []corev1.NodeAddressType{corev1.NodeExternalIP, corev1.NodeInternalIP} {
+		for _, node := range nodes {
+			for _, address := range node.Status.Addresses {
+				if address.Type == addressType && address.Address != "" {
+					return address.Address
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// servicePod returns the name of a ready pod selected by the Service and the container port the
+// port of the Service targets on that pod
+func servicePod(ctx context.Context, clientset kubernetes.Interface, svc *corev1.Service, svcPort *corev1.ServicePort) (string, int, error) {
+	if len(svc.Spec.Selector) == 0 {
+		return "", 0, fmt.Errorf("service %s/%s has no selector", svc.Namespace, svc.Name)
+	}
+	pods, err := clientset.CoreV1().Pods(svc.Namespace).List(ctx, metav1.ListOptions{LabelSelector: labels.SelectorFromSet(svc.Spec.Selector).String()})
+	if err != nil {
+		return "", 0, err
+	}
+	for _, pod := range pods.Items {
+		if !podReady(pod) {
+			continue
+		}
+		switch {
+		case svcPort.TargetPort.Type == intstr.String:
+			for _, container := range pod.Spec.Containers {
+				for _, p := range container.Ports {
+					if p.Name == svcPort.TargetPort.StrVal {
+						return pod.Name, int(p.ContainerPort), nil
+					}
+				}
+			}
+		case svcPort.TargetPort.IntValue() != 0:
+			return pod.Name, svcPort.TargetPort.IntValue(), nil
+		default:
+			return pod.Name, int(svcPort.Port), nil
+		}
+	}
+	return "", 0, fmt.Errorf("no ready pod of service %s/%s exposes its port %d", svc.Namespace, svc.Name, svcPort.Port)
+}
+
+func podReady(pod corev1.Pod) bool {
+	if pod.Status.Phase != corev1.PodRunning {
+		return false
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func backendPortString(port networkingv1.ServiceBackendPort) string {
+	if port.Name != "" {
+		return port.Name
+	}
+	return strconv.Itoa(int(port.Number))
+}
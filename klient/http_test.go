@@ -0,0 +1,360 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package klient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+)
+
+func TestNodeAddress(t *testing.T) {
+	node := func(addresses ...corev1.NodeAddress) corev1.Node {
+		return corev1.Node{Status: corev1.NodeStatus{Addresses: addresses}}
+	}
+	tests := []struct {
+		name     string
+		nodes    []corev1.Node
+		expected string
+	}{
+		{
+			name:  "no nodes",
+			nodes: nil,
+		},
+		{
+			name:     "internal address",
+			nodes:    []corev1.Node{node(corev1.NodeAddress{Type: corev1.NodeHostName, Address: "node"}, corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: "10.0.0.1"})},
+			expected: "10.0.0.1",
+		},
+		{
+			name: "external address preferred over the internal address of a previous node",
+			nodes: []corev1.Node{
+				node(corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: "10.0.0.1"}),
+				node(corev1.NodeAddress{Type: corev1.NodeExternalIP, Address: "203.0.113.1"}),
+			},
+			expected: "203.0.113.1",
+		},
+		{
+			name:  "empty addresses ignored",
+			nodes: []corev1.Node{node(corev1.NodeAddress{Type: corev1.NodeExternalIP})},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			if address := nodeAddress(test.nodes); address != test.expected {
+				t.Errorf("expected address %q, got %q", test.expected, address)
+			}
+		})
+	}
+}
+
+// readyPod returns a running pod labeled app=web, ready or not, exposing the named container port
+func readyPod(name string, ready bool, portName string, port int32) *corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": "web"}},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name:  "web",
+			Ports: []corev1.ContainerPort{{Name: portName, ContainerPort: port}},
+		}}},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+		},
+	}
+}
+
+func TestServicePod(t *testing.T) {
+	svc := func(selector map[string]string) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec:       corev1.ServiceSpec{Selector: selector},
+		}
+	}
+	selector := map[string]string{"app": "web"}
+	tests := []struct {
+		name         string
+		svc          *corev1.Service
+		svcPort      corev1.ServicePort
+		pods         []*corev1.Pod
+		expectedPod  string
+		expectedPort int
+		wantErr      bool
+	}{
+		{
+			name:         "named target port",
+			svc:          svc(selector),
+			svcPort:      corev1.ServicePort{Port: 80, TargetPort: intstr.FromString("http")},
+			pods:         []*corev1.Pod{readyPod("not-ready", false, "http", 8080), readyPod("ready", true, "http", 8080)},
+			expectedPod:  "ready",
+			expectedPort: 8080,
+		},
+		{
+			name:         "numeric target port",
+			svc:          svc(selector),
+			svcPort:      corev1.ServicePort{Port: 80, TargetPort: intstr.FromInt(9090)},
+			pods:         []*corev1.Pod{readyPod("ready", true, "http", 8080)},
+			expectedPod:  "ready",
+			expectedPort: 9090,
+		},
+		{
+			name:         "service port without target port",
+			svc:          svc(selector),
+			svcPort:      corev1.ServicePort{Port: 80},
+			pods:         []*corev1.Pod{readyPod("ready", true, "http", 8080)},
+			expectedPod:  "ready",
+			expectedPort: 80,
+		},
+		{
+			name:    "named target port not exposed",
+			svc:     svc(selector),
+			svcPort: corev1.ServicePort{Port: 80, TargetPort: intstr.FromString("metrics")},
+			pods:    []*corev1.Pod{readyPod("ready", true, "http", 8080)},
+			wantErr: true,
+		},
+		{
+			name:    "no ready pod",
+			svc:     svc(selector),
+			svcPort: corev1.ServicePort{Port: 80},
+			pods:    []*corev1.Pod{readyPod("not-ready", false, "http", 8080)},
+			wantErr: true,
+		},
+		{
+			name:    "service without selector",
+			svc:     svc(nil),
+			svcPort: corev1.ServicePort{Port: 80},
+			pods:    []*corev1.Pod{readyPod("ready", true, "http", 8080)},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset()
+			for _, pod := range test.pods {
+				if _, err := clientset.CoreV1().Pods(pod.Namespace).Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			pod, port, err := servicePod(context.TODO(), clientset, test.svc, &test.svcPort)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if pod != test.expectedPod || port != test.expectedPort {
+				t.Errorf("expected pod %q and port %d, got pod %q and port %d", test.expectedPod, test.expectedPort, pod, port)
+			}
+		})
+	}
+}
+
+// endpointNames returns the names of the endpoints
+func endpointNames(endpoints []httpEndpoint) []string {
+	names := make([]string, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		names = append(names, endpoint.name)
+	}
+	return names
+}
+
+func TestServiceEndpoints(t *testing.T) {
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: corev1.ServiceSpec{
+			Type:     corev1.ServiceTypeLoadBalancer,
+			Selector: map[string]string{"app": "web"},
+			Ports: []corev1.ServicePort{
+				{Name: "http", Port: 80, NodePort: 30080},
+				{Name: "metrics", Port: 9090},
+			},
+		},
+		Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{Ingress: []corev1.LoadBalancerIngress{{IP: "203.0.113.10"}, {Hostname: "lb.example.com"}}}},
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node"},
+		Status:     corev1.NodeStatus{Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.0.0.1"}}},
+	}
+	tests := []struct {
+		name     string
+		port     networkingv1.ServiceBackendPort
+		expected []string
+		wantErr  bool
+	}{
+		{
+			name: "port by number",
+			port: networkingv1.ServiceBackendPort{Number: 80},
+			expected: []string{
+				"load balancer http://203.0.113.10:80",
+				"load balancer http://lb.example.com:80",
+				"node port http://10.0.0.1:30080",
+				"port-forward to service default/web",
+			},
+		},
+		{
+			name: "port by name without node port",
+			port: networkingv1.ServiceBackendPort{Name: "metrics"},
+			expected: []string{
+				"load balancer http://203.0.113.10:9090",
+				"load balancer http://lb.example.com:9090",
+				"port-forward to service default/web",
+			},
+		},
+		{
+			name:    "unknown port",
+			port:    networkingv1.ServiceBackendPort{Number: 8080},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(svc, node)
+			endpoints, err := serviceEndpoints(context.TODO(), &rest.Config{}, clientset, "default", "web", test.port)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if names := endpointNames(endpoints); !test.wantErr && !reflect.DeepEqual(names, test.expected) {
+				t.Errorf("Expected:\n%v but got result:\n%v", test.expected, names)
+			}
+		})
+	}
+}
+
+func TestIngressEndpoints(t *testing.T) {
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 80}}},
+	}
+	backend := networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{Name: "web", Port: networkingv1.ServiceBackendPort{Name: "http"}}}
+	ingress := func(name string, spec networkingv1.IngressSpec, lbs ...networkingv1.IngressLoadBalancerIngress) *networkingv1.Ingress {
+		return &networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       spec,
+			Status:     networkingv1.IngressStatus{LoadBalancer: networkingv1.IngressLoadBalancerStatus{Ingress: lbs}},
+		}
+	}
+	rule := networkingv1.IngressRule{
+		Host: "web.example.com",
+		IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+			Paths: []networkingv1.HTTPIngressPath{{Path: "/", Backend: backend}},
+		}},
+	}
+	tests := []struct {
+		name         string
+		ingress      *networkingv1.Ingress
+		expected     []string
+		expectedHost string
+		wantErr      bool
+	}{
+		{
+			name:         "load balancer and rule backend",
+			ingress:      ingress("rule", networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{rule}}, networkingv1.IngressLoadBalancerIngress{IP: "203.0.113.20"}),
+			expected:     []string{"ingress load balancer http://203.0.113.20:80", "port-forward to service default/web"},
+			expectedHost: "web.example.com",
+		},
+		{
+			name:     "default backend",
+			ingress:  ingress("default", networkingv1.IngressSpec{DefaultBackend: &backend}),
+			expected: []string{"port-forward to service default/web"},
+		},
+		{
+			name:     "load balancer without backend",
+			ingress:  ingress("lb-only", networkingv1.IngressSpec{}, networkingv1.IngressLoadBalancerIngress{Hostname: "lb.example.com"}),
+			expected: []string{"ingress load balancer http://lb.example.com:80"},
+		},
+		{
+			name:    "neither load balancer nor backend",
+			ingress: ingress("empty", networkingv1.IngressSpec{}),
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(svc, test.ingress)
+			endpoints, err := ingressEndpoints(context.TODO(), &rest.Config{}, clientset, "default", test.ingress.Name, 80)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.wantErr {
+				return
+			}
+			if names := endpointNames(endpoints); !reflect.DeepEqual(names, test.expected) {
+				t.Errorf("Expected:\n%v but got result:\n%v", test.expected, names)
+			}
+			if endpoints[0].host != test.expectedHost {
+				t.Errorf("expected the Host header %q, got %q", test.expectedHost, endpoints[0].host)
+			}
+		})
+	}
+}
+
+func TestHTTPEndpoint_Get(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.Host, r.URL.Path)
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, portString, err := net.SplitHostPort(serverURL.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(portString)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endpoint := addressEndpoint("test", host, int32(port))
+	endpoint.host = "web.example.com"
+	resp, err := endpoint.get(context.TODO(), "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the body was read before the endpoint was released, and remains readable
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "web.example.com /healthz" {
+		t.Errorf("unexpected response body: %q", body)
+	}
+
+	server.Close()
+	if _, err := endpoint.get(context.TODO(), "/healthz"); err == nil {
+		t.Error("expected an error from an endpoint that does not respond")
+	}
+}
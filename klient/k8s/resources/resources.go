@@ -51,6 +51,21 @@ type Resources struct {
 
 	// namespace for namespaced object requests
 	namespace string
+
+	// opTimeout bounds each operation when opTimeoutSet is true, a zero timeout meaning no bound
+	opTimeout    time.Duration
+	opTimeoutSet bool
+}
+
+// opTimeoutKey is the context key of the timeout of an operation set with WithOpTimeout
+type opTimeoutKey struct{}
+
+// OpTimeoutFromContext returns the timeout set with WithOpTimeout on the Resources performing the
+// operation of the context, if any. This lets the clients bounding the operations with a default
+// timeout give precedence to the timeout of the operation.
+func OpTimeoutFromContext(ctx context.Context) (time.Duration, bool) {
+	timeout, ok := ctx.Value(opTimeoutKey{}).(time.Duration)
+	return timeout, ok
 }
 
 // New instantiates the controller runtime client
@@ -76,6 +91,17 @@ func New(cfg *rest.Config) (*Resources, error) {
 	return res, nil
 }
 
+// NewFromClient creates a Resources performing its operations with the controller runtime
+// client cl, e.g. a client wrapped to observe the operations, cfg being the configuration
+// the client was created with.
+func NewFromClient(cfg *rest.Config, cl cr.Client) *Resources {
+	return &Resources{
+		config: cfg,
+		scheme: cl.Scheme(),
+		client: cl,
+	}
+}
+
 // GetConfig hepls to get config type *rest.Config
 func (r *Resources) GetConfig() *rest.Config {
 	return r.config
@@ -86,13 +112,43 @@ func (r *Resources) WithNamespace(ns string) *Resources {
 	return r
 }
 
+// WithOpTimeout returns a copy of the Resources whose operations are each bounded by the timeout,
+// e.g. to give a long list more time than the default timeout of the client, if any, see
+// klient.NewTimeoutClient. The timeout takes precedence over the default timeout of the client,
+// and a zero timeout disables both. The context of an operation still bounds it as well. Watch
+// is not bounded, as it returns before the watch starts.
+func (r *Resources) WithOpTimeout(timeout time.Duration) *Resources {
+	rc := *r
+	rc.opTimeout = timeout
+	rc.opTimeoutSet = true
+	return &rc
+}
+
+// opContext returns the context of an operation, bounded by the timeout of the operation, if any
+func (r *Resources) opContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if !r.opTimeoutSet {
+		return ctx, func() {}
+	}
+	ctx = context.WithValue(ctx, opTimeoutKey{}, r.opTimeout)
+	if r.opTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, r.opTimeout)
+}
+
 func (r *Resources) Get(ctx context.Context, name, namespace string, obj k8s.Object) error {
+	ctx, cancel := r.opContext(ctx)
+	defer cancel()
+
 	return r.client.Get(ctx, cr.ObjectKey{Namespace: namespace, Name: name}, obj)
 }
 
 type CreateOption func(*metav1.CreateOptions)
 
 func (r *Resources) Create(ctx context.Context, obj k8s.Object, opts ...CreateOption) error {
+	ctx, cancel := r.opContext(ctx)
+	defer cancel()
+
 	createOptions := &metav1.CreateOptions{}
 	for _, fn := range opts {
 		fn(createOptions)
@@ -110,6 +166,9 @@ func (r *Resources) Create(ctx context.Context, obj k8s.Object, opts ...CreateOp
 type UpdateOption func(*metav1.UpdateOptions)
 
 func (r *Resources) Update(ctx context.Context, obj k8s.Object, opts ...UpdateOption) error {
+	ctx, cancel := r.opContext(ctx)
+	defer cancel()
+
 	updateOptions := &metav1.UpdateOptions{}
 	for _, fn := range opts {
 		fn(updateOptions)
@@ -125,6 +184,9 @@ func (r *Resources) Update(ctx context.Context, obj k8s.Object, opts ...UpdateOp
 
 // UpdateSubresource updates the subresource of the object
 func (r *Resources) UpdateSubresource(ctx context.Context, obj k8s.Object, subresource string, opts ...UpdateOption) error {
+	ctx, cancel := r.opContext(ctx)
+	defer cancel()
+
 	updateOptions := &metav1.UpdateOptions{}
 	for _, fn := range opts {
 		fn(updateOptions)
@@ -143,6 +205,9 @@ func (r *Resources) UpdateStatus(ctx context.Context, obj k8s.Object, opts ...Up
 type DeleteOption func(*metav1.DeleteOptions)
 
 func (r *Resources) Delete(ctx context.Context, obj k8s.Object, opts ...DeleteOption) error {
+	ctx, cancel := r.opContext(ctx)
+	defer cancel()
+
 	deleteOptions := &metav1.DeleteOptions{}
 	for _, fn := range opts {
 		fn(deleteOptions)
@@ -171,6 +236,9 @@ func WithDeletePropagation(prop string) DeleteOption {
 type ListOption func(*metav1.ListOptions)
 
 func (r *Resources) List(ctx context.Context, objs k8s.ObjectList, opts ...ListOption) error {
+	ctx, cancel := r.opContext(ctx)
+	defer cancel()
+
 	listOptions := &metav1.ListOptions{}
 
 	for _, fn := range opts {
@@ -218,6 +286,9 @@ type PatchOption func(*metav1.PatchOptions)
 
 // Patch patches portion of object `obj` with data from object `patch`
 func (r *Resources) Patch(ctx context.Context, obj k8s.Object, patch k8s.Patch, opts ...PatchOption) error {
+	ctx, cancel := r.opContext(ctx)
+	defer cancel()
+
 	patchOptions := &metav1.PatchOptions{}
 
 	for _, fn := range opts {
@@ -237,6 +308,9 @@ func (r *Resources) Patch(ctx context.Context, obj k8s.Object, patch k8s.Patch,
 
 // PatchSubresource patches portion of object `obj` with data from object `patch`
 func (r *Resources) PatchSubresource(ctx context.Context, obj k8s.Object, subresource string, patch k8s.Patch, opts ...PatchOption) error {
+	ctx, cancel := r.opContext(ctx)
+	defer cancel()
+
 	patchOptions := &metav1.PatchOptions{}
 
 	for _, fn := range opts {
@@ -291,6 +365,9 @@ func (r *Resources) Watch(object k8s.ObjectList, opts ...ListOption) *watcher.Ev
 }
 
 func (r *Resources) ExecInPod(ctx context.Context, namespaceName, podName, containerName string, command []string, stdout, stderr *bytes.Buffer) error {
+	ctx, cancel := r.opContext(ctx)
+	defer cancel()
+
 	clientset, err := kubernetes.NewForConfig(r.config)
 	if err != nil {
 		return err
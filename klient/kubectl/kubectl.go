@@ -0,0 +1,272 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package kubectl provides a kubectl-like facade over the operations of klient, which eases the
+// migration of the test suites written as shell scripts.
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	apimachinerywait "k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	"sigs.k8s.io/e2e-framework/klient"
+	"sigs.k8s.io/e2e-framework/klient/decoder"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+)
+
+// FieldManager is the field manager of the server-side apply operations of Apply
+const FieldManager = "e2e-framework"
+
+// Kubectl performs kubectl-like operations with a client, e.g. Apply for kubectl apply. The
+// operations are performed through the client, except the ones of Run, which shells out to
+// kubectl with the kubeconfig file of the client. All the operations honor the cancellation
+// of their context.
+type Kubectl struct {
+	client     klient.Client
+	kubeconfig string
+	namespace  string
+	path       string
+}
+
+// New returns a Kubectl performing its operations with the client, the kubeconfig file the
+// client was created from being passed to the kubectl commands of Run
+func New(client klient.Client, kubeconfig string) *Kubectl {
+	return &Kubectl{client: client, kubeconfig: kubeconfig, path: "kubectl"}
+}
+
+// WithNamespace returns a copy of the Kubectl performing its operations in the namespace, as
+// the --namespace flag of kubectl does. The namespaced objects of the manifests that do not
+// have a namespace are applied or deleted in it.
+func (k *Kubectl) WithNamespace(namespace string) *Kubectl {
+	kc := *k
+	kc.namespace = namespace
+	return &kc
+}
+
+// WithPath returns a copy of the Kubectl running the kubectl binary at path, kubectl being
+// looked up in the PATH otherwise
+func (k *Kubectl) WithPath(path string) *Kubectl {
+	kc := *k
+	kc.path = path
+	return &kc
+}
+
+// Apply applies the objects of the manifest, in YAML or JSON, with server-side apply as the
+// FieldManager field manager, as kubectl apply --server-side --force-conflicts does. The
+// objects are applied in order, Apply stopping at the first error.
+func (k *Kubectl) Apply(ctx context.Context, manifest io.Reader) error {
+	return decoder.DecodeEach(ctx, manifest, func(ctx context.Context, obj k8s.Object) error {
+		if err := k.defaultNamespace(obj); err != nil {
+			return fmt.Errorf("apply: %w", err)
+		}
+		if err := k.apply(ctx, obj); err != nil {
+			return fmt.Errorf("apply: %s %s: %w", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName(), err)
+		}
+		return nil
+	})
+}
+
+// apply applies the object with server-side apply, forcing the conflicts
+func (k *Kubectl) apply(ctx context.Context, obj k8s.Object) error {
+	res := k.client.Resources()
+	// the apply patch must carry the kind of the object, which typed objects usually omit
+	gvk, err := apiutil.GVKForObject(obj, res.GetScheme())
+	if err != nil {
+		return err
+	}
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+	obj.SetManagedFields(nil)
+	obj.SetResourceVersion("")
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	force := true
+	return res.Patch(ctx, obj, k8s.Patch{PatchType: types.ApplyPatchType, Data: data}, func(o *metav1.PatchOptions) {
+		o.FieldManager = FieldManager
+		o.Force = &force
+	})
+}
+
+// Delete deletes the objects of the manifest, in YAML or JSON, the objects not found being
+// ignored, as kubectl delete --ignore-not-found does. Delete does not wait for the objects to
+// be deleted, see wait.ForResourceDeleted. All the objects are deleted, the errors being aggregated.
+func (k *Kubectl) Delete(ctx context.Context, manifest io.Reader) error {
+	var errs []error
+	err := decoder.DecodeEach(ctx, manifest, func(ctx context.Context, obj k8s.Object) error {
+		if err := k.defaultNamespace(obj); err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		if err := k.client.Resources().Delete(ctx, obj); err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("%s %s: %w", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName(), err))
+		}
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("delete: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// defaultNamespace sets the namespace of the Kubectl on the object when it is namespaced and has none
+func (k *Kubectl) defaultNamespace(obj k8s.Object) error {
+	if k.namespace == "" || obj.GetNamespace() != "" {
+		return nil
+	}
+	namespaced, err := k.client.Resources().GetControllerRuntimeClient().IsObjectNamespaced(obj)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName(), err)
+	}
+	if namespaced {
+		obj.SetNamespace(k.namespace)
+	}
+	return nil
+}
+
+// Get retrieves the object with the name, in the namespace of the Kubectl unless the kind of
+// the object is cluster scoped, as kubectl get does
+func (k *Kubectl) Get(ctx context.Context, name string, obj k8s.Object) error {
+	if err := k.client.Resources().Get(ctx, name, k.namespace, obj); err != nil {
+		return fmt.Errorf("get %s: %w", name, err)
+	}
+	return nil
+}
+
+// Wait waits for the condition of the status of the object, such as "Available" for a Deployment
+// or "Ready" for a Pod, to be true, as kubectl wait --for=condition=<condition> does. The types
+// of the conditions are compared case insensitively. The object is retrieved by its name and
+// namespace, and updated with its last observed state. Use wait.ForResourceDeleted to wait for the
+// deletion of objects.
+func (k *Kubectl) Wait(ctx context.Context, obj k8s.Object, condition string, timeout time.Duration) error {
+	var status string
+	err := wait.For(func(ctx context.Context) (bool, error) {
+		if err := k.client.Resources().Get(ctx, obj.GetName(), obj.GetNamespace(), obj); err != nil {
+			if apierrors.IsNotFound(err) {
+				status = "not found"
+				return false, nil
+			}
+			return false, err
+		}
+		var err error
+		status, err = conditionStatus(obj, condition)
+		if err != nil {
+			return false, err
+		}
+		return status == string(metav1.ConditionTrue), nil
+	}, wait.WithContext(ctx), wait.WithTimeout(timeout), wait.WithImmediate())
+	if err != nil && apimachinerywait.Interrupted(err) {
+		return fmt.Errorf("wait for condition %s of %s: last status %q: %w", condition, obj.GetName(), status, err)
+	}
+	if err != nil {
+		return fmt.Errorf("wait for condition %s of %s: %w", condition, obj.GetName(), err)
+	}
+	return nil
+}
+
+// conditionStatus returns the status of the condition of the object, "unknown" if the object does not have it
+func conditionStatus(obj k8s.Object, condition string) (string, error) {
+	var content map[string]interface{}
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		content = u.Object
+	} else {
+		var err error
+		if content, err = runtime.DefaultUnstructuredConverter.ToUnstructured(obj); err != nil {
+			return "", err
+		}
+	}
+	conditions, _, err := unstructured.NestedSlice(content, "status", "conditions")
+	if err != nil {
+		return "", err
+	}
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if condType, _ := cond["type"].(string); strings.EqualFold(condType, condition) {
+			status, _ := cond["status"].(string)
+			return status, nil
+		}
+	}
+	return "unknown", nil
+}
+
+// RolloutStatus waits for the rollout of the Deployment, StatefulSet or DaemonSet to complete,
+// as kubectl rollout status does: the controller must have observed the last generation of the
+// object and all its replicas must be updated and available. The object is retrieved by its name
+// and namespace, and updated with its last observed state. RolloutStatus fails without waiting
+// for the timeout when the progress deadline of a Deployment is exceeded.
+func (k *Kubectl) RolloutStatus(ctx context.Context, obj k8s.Object, timeout time.Duration) error {
+	var reason string
+	err := wait.For(func(ctx context.Context) (bool, error) {
+		if err := k.client.Resources().Get(ctx, obj.GetName(), obj.GetNamespace(), obj); err != nil {
+			return false, err
+		}
+		var done bool
+		var err error
+		done, reason, err = wait.RolloutComplete(obj)
+		return done, err
+	}, wait.WithContext(ctx), wait.WithTimeout(timeout), wait.WithImmediate())
+	if err != nil && apimachinerywait.Interrupted(err) {
+		return fmt.Errorf("rollout status of %s: %s: %w", obj.GetName(), reason, err)
+	}
+	if err != nil {
+		return fmt.Errorf("rollout status of %s: %w", obj.GetName(), err)
+	}
+	return nil
+}
+
+// Run runs kubectl with the arguments, e.g. "logs", "deploy/app", against the cluster of the
+// kubeconfig file, in the namespace of the Kubectl if any, and returns its standard output. The
+// error returned when kubectl fails carries its standard error. kubectl is killed when the
+// context is done.
+func (k *Kubectl) Run(ctx context.Context, args ...string) (string, error) {
+	var flags []string
+	if k.kubeconfig != "" {
+		flags = append(flags, "--kubeconfig", k.kubeconfig)
+	}
+	if k.namespace != "" {
+		flags = append(flags, "--namespace", k.namespace)
+	}
+	cmd := exec.CommandContext(ctx, k.path, append(flags, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return stdout.String(), fmt.Errorf("kubectl %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
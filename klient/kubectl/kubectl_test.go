@@ -0,0 +1,390 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta/testrestmapper"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"sigs.k8s.io/e2e-framework/internal/testutil"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+)
+
+func TestRolloutComplete(t *testing.T) {
+	replicas := int32(2)
+	partition := int32(1)
+	tests := []struct {
+		name   string
+		obj    k8s.Object
+		done   bool
+		reason string
+		err    string
+	}{
+		{
+			name:   "deployment generation not observed",
+			obj:    &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Generation: 2}, Status: appsv1.DeploymentStatus{ObservedGeneration: 1}},
+			reason: "waiting for the deployment spec update to be observed",
+		},
+		{
+			name: "deployment progress deadline exceeded",
+			obj: &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "app"}, Status: appsv1.DeploymentStatus{Conditions: []appsv1.DeploymentCondition{
+				{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded"},
+			}}},
+			err: "deployment app exceeded its progress deadline",
+		},
+		{
+			name:   "deployment old replicas pending termination",
+			obj:    &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Replicas: &replicas}, Status: appsv1.DeploymentStatus{Replicas: 3, UpdatedReplicas: 2, AvailableReplicas: 2}},
+			reason: "1 old replicas are pending termination",
+		},
+		{
+			name: "deployment rolled out",
+			obj:  &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Replicas: &replicas}, Status: appsv1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2}},
+			done: true,
+		},
+		{
+			name: "statefulset partitioned rollout",
+			obj: &appsv1.StatefulSet{
+				Spec: appsv1.StatefulSetSpec{Replicas: &replicas, UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+					Type:          appsv1.RollingUpdateStatefulSetStrategyType,
+					RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{Partition: &partition},
+				}},
+				Status: appsv1.StatefulSetStatus{ReadyReplicas: 2, UpdatedReplicas: 1, CurrentRevision: "v1", UpdateRevision: "v2"},
+			},
+			done: true,
+		},
+		{
+			name: "statefulset revision not rolled out",
+			obj: &appsv1.StatefulSet{
+				Spec:   appsv1.StatefulSetSpec{Replicas: &replicas, UpdateStrategy: appsv1.StatefulSetUpdateStrategy{Type: appsv1.RollingUpdateStatefulSetStrategyType}},
+				Status: appsv1.StatefulSetStatus{ReadyReplicas: 2, UpdatedReplicas: 1, CurrentRevision: "v1", UpdateRevision: "v2"},
+			},
+			reason: "1 of 2 pods have been updated to revision v2",
+		},
+		{
+			name: "daemonset on delete strategy",
+			obj:  &appsv1.DaemonSet{Spec: appsv1.DaemonSetSpec{UpdateStrategy: appsv1.DaemonSetUpdateStrategy{Type: appsv1.OnDeleteDaemonSetStrategyType}}},
+			err:  "rollout status is only available for the RollingUpdate strategy type",
+		},
+		{
+			name: "daemonset updated pods not available",
+			obj: &appsv1.DaemonSet{
+				Spec:   appsv1.DaemonSetSpec{UpdateStrategy: appsv1.DaemonSetUpdateStrategy{Type: appsv1.RollingUpdateDaemonSetStrategyType}},
+				Status: appsv1.DaemonSetStatus{DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 2},
+			},
+			reason: "2 of 3 updated pods are available",
+		},
+		{
+			name: "unsupported kind",
+			obj:  &corev1.Pod{},
+			err:  "rollout status is not supported for *v1.Pod",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			done, reason, err := wait.RolloutComplete(test.obj)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("expected error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil || done != test.done || reason != test.reason {
+				t.Errorf("expected done=%t reason=%q, got done=%t reason=%q (error: %v)", test.done, test.reason, done, reason, err)
+			}
+		})
+	}
+}
+
+func TestKubectl_Wait(t *testing.T) {
+	ctx := context.Background()
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Status: corev1.PodStatus{Conditions: []corev1.PodCondition{
+			{Type: corev1.PodScheduled, Status: corev1.ConditionTrue},
+			{Type: corev1.PodReady, Status: corev1.ConditionFalse},
+		}},
+	}
+	k := New(testutil.NewFakeClient(interceptor.Funcs{}, pod), "").WithNamespace("default")
+
+	if err := k.Wait(ctx, pod.DeepCopy(), "podscheduled", time.Second); err != nil {
+		t.Errorf("expected the condition to be met, got %v", err)
+	}
+	err := k.Wait(ctx, pod.DeepCopy(), "Ready", 100*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), `last status "False"`) {
+		t.Errorf("expected the wait to time out with the last status of the condition, got %v", err)
+	}
+	var got corev1.Pod
+	if err := k.Get(ctx, "app", &got); err != nil || got.Name != "app" {
+		t.Errorf("expected the pod to be found, got %v", err)
+	}
+}
+
+// mappedClient returns a fake client holding the objects, its operations being intercepted by
+// funcs, which knows the scope of the built-in kinds to default the namespace of the objects
+func mappedClient(funcs interceptor.Funcs, objs ...cr.Object) testutil.FakeClient {
+	return testutil.FakeClient{Client: fake.NewClientBuilder().
+		WithRESTMapper(testrestmapper.TestOnlyStaticRESTMapper(scheme.Scheme)).
+		WithInterceptorFuncs(funcs).
+		WithObjects(objs...).
+		Build()}
+}
+
+// appliedPatch is a patch received by the fake client
+type appliedPatch struct {
+	key          string
+	patchType    types.PatchType
+	data         map[string]interface{}
+	fieldManager string
+	force        bool
+}
+
+func TestKubectl_Apply(t *testing.T) {
+	ctx := context.Background()
+	var patches []appliedPatch
+	client := mappedClient(interceptor.Funcs{
+		Patch: func(ctx context.Context, c cr.WithWatch, obj cr.Object, patch cr.Patch, opts ...cr.PatchOption) error {
+			if obj.GetName() == "broken" {
+				return errors.New("patch refused")
+			}
+			data, err := patch.Data(obj)
+			if err != nil {
+				return err
+			}
+			var content map[string]interface{}
+			if err := json.Unmarshal(data, &content); err != nil {
+				return err
+			}
+			options := (&cr.PatchOptions{}).ApplyOptions(opts)
+			patches = append(patches, appliedPatch{
+				key:          obj.GetNamespace() + "/" + obj.GetName(),
+				patchType:    patch.Type(),
+				data:         content,
+				fieldManager: options.FieldManager,
+				force:        options.Force != nil && *options.Force,
+			})
+			return nil
+		},
+	})
+	k := New(client, "").WithNamespace("test")
+
+	manifest := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: defaulted
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: explicit
+  namespace: other
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: cluster-scoped
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: broken
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: skipped
+`
+	err := k.Apply(ctx, strings.NewReader(manifest))
+	if err == nil || !strings.Contains(err.Error(), "ConfigMap broken: patch refused") {
+		t.Errorf("expected the apply to stop at the broken object, got %v", err)
+	}
+	var keys []string
+	for _, patch := range patches {
+		keys = append(keys, patch.key)
+		if patch.patchType != types.ApplyPatchType || patch.fieldManager != FieldManager || !patch.force {
+			t.Errorf("%s: expected a forced server-side apply by %s, got %+v", patch.key, FieldManager, patch)
+		}
+	}
+	if expected := []string{"test/defaulted", "other/explicit", "/cluster-scoped"}; !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected the objects %v to be applied, got %v", expected, keys)
+	}
+
+	// the kind of the typed objects, usually omitted, is set on the apply patch
+	patches = nil
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "typed", ResourceVersion: "42", ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "other"}}},
+		Data:       map[string]string{"key": "value"},
+	}
+	if err := k.apply(ctx, cm); err != nil {
+		t.Fatal(err)
+	}
+	if len(patches) != 1 {
+		t.Fatalf("expected a single patch, got %d", len(patches))
+	}
+	data := patches[0].data
+	if data["apiVersion"] != "v1" || data["kind"] != "ConfigMap" {
+		t.Errorf("expected the patch to carry the kind of the object, got %v", data)
+	}
+	metadata, _ := data["metadata"].(map[string]interface{})
+	if _, ok := metadata["resourceVersion"]; ok {
+		t.Errorf("expected the resource version to be cleared, got %v", metadata)
+	}
+	if _, ok := metadata["managedFields"]; ok {
+		t.Errorf("expected the managed fields to be cleared, got %v", metadata)
+	}
+}
+
+func TestKubectl_Delete(t *testing.T) {
+	ctx := context.Background()
+	existing := func(name string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test"}}
+	}
+	client := mappedClient(interceptor.Funcs{
+		Delete: func(ctx context.Context, c cr.WithWatch, obj cr.Object, opts ...cr.DeleteOption) error {
+			if strings.HasPrefix(obj.GetName(), "broken") {
+				return apierrors.NewForbidden(schema.GroupResource{Resource: "configmaps"}, obj.GetName(), errors.New("denied"))
+			}
+			return c.Delete(ctx, obj, opts...)
+		},
+	}, existing("deleted"), existing("broken-1"), existing("broken-2"), existing("kept"))
+	k := New(client, "").WithNamespace("test")
+
+	manifest := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: broken-1
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: missing
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: deleted
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: broken-2
+`
+	err := k.Delete(ctx, strings.NewReader(manifest))
+	if err == nil {
+		t.Fatal("expected the errors to be reported")
+	}
+	for _, name := range []string{"ConfigMap broken-1", "ConfigMap broken-2"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("expected error %q to report %s", err, name)
+		}
+	}
+	if strings.Contains(err.Error(), "missing") {
+		t.Errorf("expected the missing object to be ignored, got %v", err)
+	}
+	if err := client.Resources().Get(ctx, "deleted", "test", &corev1.ConfigMap{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected the object following an error to be deleted, got %v", err)
+	}
+	if err := client.Resources().Get(ctx, "kept", "test", &corev1.ConfigMap{}); err != nil {
+		t.Errorf("expected the object not in the manifest to be kept, got %v", err)
+	}
+}
+
+func TestKubectl_Run(t *testing.T) {
+	// the fake kubectl prints its arguments, and fails when asked to
+	path := filepath.Join(t.TempDir(), "kubectl")
+	script := `#!/bin/sh
+echo "$@"
+case "$*" in
+*fail*) echo "error: failed" >&2; exit 1 ;;
+esac
+`
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	k := New(testutil.NewFakeClient(interceptor.Funcs{}), "/tmp/kubeconfig").WithPath(path)
+
+	tests := []struct {
+		name     string
+		k        *Kubectl
+		args     []string
+		expected string
+		err      string
+	}{
+		{
+			name:     "kubeconfig flag",
+			k:        k,
+			args:     []string{"get", "pods"},
+			expected: "--kubeconfig /tmp/kubeconfig get pods\n",
+		},
+		{
+			name:     "namespace flag",
+			k:        k.WithNamespace("test"),
+			args:     []string{"logs", "deploy/app"},
+			expected: "--kubeconfig /tmp/kubeconfig --namespace test logs deploy/app\n",
+		},
+		{
+			name:     "no kubeconfig",
+			k:        New(testutil.NewFakeClient(interceptor.Funcs{}), "").WithPath(path),
+			args:     []string{"version"},
+			expected: "version\n",
+		},
+		{
+			name:     "failure",
+			k:        k,
+			args:     []string{"fail"},
+			expected: "--kubeconfig /tmp/kubeconfig fail\n",
+			err:      "kubectl fail: exit status 1: error: failed",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			out, err := test.k.Run(context.Background(), test.args...)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("expected error %q, got %v", test.err, err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if out != test.expected {
+				t.Errorf("expected output %q, got %q", test.expected, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package klient
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// logSearchInterval is the delay between two listings of the pods searched by SearchPodLogs
+var logSearchInterval = 2 * time.Second
+
+// LogSearchOptions configures SearchPodLogs
+type LogSearchOptions struct {
+	// Container restricts the search to the container of the pods with this name, when set.
+	// All the containers of the pods are searched otherwise.
+	Container string
+	// Timeout bounds the duration of the search, when set. The context passed to
+	// SearchPodLogs bounds it as well.
+	Timeout time.Duration
+}
+
+// LogMatch is the log line found by SearchPodLogs
+type LogMatch struct {
+	// Pod is the name of the pod that logged the line
+	Pod string
+	// Container is the name of the container of the pod that logged the line
+	Container string
+	// Line is the log line, without its trailing newline
+	Line string
+}
+
+// SearchPodLogs searches the logs of the pods of the namespace matching the label selector for a
+// line accepted by the matcher, e.g. to verify that at least one replica of a deployment logged a
+// message. The logs of the containers of the pods are followed concurrently, from their start,
+// and SearchPodLogs returns the first line matched and the pod that logged it. As the logs are
+// followed concurrently, the matcher must be safe for concurrent use.
+//
+// The pods are listed again periodically during the search, so that the pods created after the
+// search started, such as the replacements of deleted pods, are searched as well, the logs that
+// cannot be streamed yet, such as the ones of a pending pod, are retried, and the logs of the
+// restarted containers are followed again from the start of their new instance. SearchPodLogs
+// returns an error listing the searched pods once the timeout of the options expires or the
+// context is done without any line being matched.
+func SearchPodLogs(ctx context.Context, cfg *rest.Config, namespace, selector string, matcher func(line string) bool, opts LogSearchOptions) (LogMatch, error) {
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return LogMatch{}, fmt.Errorf("search pod logs: %w", err)
+	}
+	return searchPodLogs(ctx, clientset, namespace, selector, matcher, opts)
+}
+
+func searchPodLogs(ctx context.Context, clientset kubernetes.Interface, namespace, selector string, matcher func(line string) bool, opts LogSearchOptions) (LogMatch, error) {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	search := &logSearch{
+		clientset: clientset,
+		namespace: namespace,
+		matcher:   matcher,
+		container: opts.Container,
+		streams:   make(map[string]bool),
+		searched:  make(map[string]struct{}),
+		matches:   make(chan LogMatch, 1),
+	}
+	// stop the streams still following the logs once the search completes
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer func() {
+		cancel()
+		search.wg.Wait()
+	}()
+
+	var lastErr error
+	for {
+		pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			lastErr = err
+		} else {
+			for _, pod := range pods.Items {
+				search.follow(streamCtx, pod)
+			}
+		}
+
+		timer := time.NewTimer(logSearchInterval)
+		select {
+		case match := <-search.matches:
+			timer.Stop()
+			return match, nil
+		case <-ctx.Done():
+			timer.Stop()
+			msg := fmt.Sprintf("no log line matched in the pods of namespace %s matching %q: %s", namespace, selector, ctx.Err())
+			if searched := search.searchedPods(); len(searched) > 0 {
+				msg += ": searched pods: " + strings.Join(searched, ", ")
+			}
+			if lastErr != nil {
+				return LogMatch{}, fmt.Errorf("%s: last error: %w", msg, lastErr)
+			}
+			return LogMatch{}, errors.New(msg)
+		case <-timer.C:
+		}
+	}
+}
+
+// logSearch is the state of a SearchPodLogs call
+type logSearch struct {
+	clientset kubernetes.Interface
+	namespace string
+	matcher   func(line string) bool
+	container string
+	matches   chan LogMatch
+	wg        sync.WaitGroup
+
+	mu sync.Mutex
+	// streams indicates, by pod UID, container and restart count, if the logs are being followed,
+	// or if they were read to the end, the streams failing being removed to be retried. A restarted
+	// container has a new key, so that the logs of its new instance are followed as well.
+	streams  map[string]bool
+	searched map[string]struct{}
+}
+
+// follow starts following the logs of the containers of the pod that are not followed yet
+func (s *logSearch) follow(ctx context.Context, pod corev1.Pod) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	restarts := make(map[string]int32, len(pod.Status.ContainerStatuses))
+	for _, status := range pod.Status.ContainerStatuses {
+		restarts[status.Name] = status.RestartCount
+	}
+	for _, container := range pod.Spec.Containers {
+		if s.container != "" && container.Name != s.container {
+			continue
+		}
+		key := fmt.Sprintf("%s/%s/%d", pod.UID, container.Name, restarts[container.Name])
+		if _, found := s.streams[key]; found {
+			continue
+		}
+		s.streams[key] = true
+		s.searched[pod.Name] = struct{}{}
+		s.wg.Add(1)
+		go func(name, container, key string) {
+			defer s.wg.Done()
+			done := s.stream(ctx, name, container)
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if done {
+				s.streams[key] = false
+			} else {
+				delete(s.streams, key)
+			}
+		}(pod.Name, container.Name, key)
+	}
+}
+
+// stream follows the logs of the container of the pod until a line is matched, the logs end or
+// the context is done, and returns true if the logs were read to the end
+func (s *logSearch) stream(ctx context.Context, pod, container string) bool {
+	logs, err := s.clientset.CoreV1().Pods(s.namespace).GetLogs(pod, &corev1.PodLogOptions{Container: container, Follow: true}).Stream(ctx)
+	if err != nil {
+		return false
+	}
+	defer logs.Close()
+	scanner := bufio.NewScanner(logs)
+	for scanner.Scan() {
+		if line := scanner.Text(); s.matcher(line) {
+			select {
+			case s.matches <- LogMatch{Pod: pod, Container: container, Line: line}:
+			default:
+			}
+			return true
+		}
+	}
+	return scanner.Err() == nil
+}
+
+// searchedPods returns the sorted names of the pods whose logs were searched
+func (s *logSearch) searchedPods() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.searched))
+	for name := range s.searched {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package klient
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// logPod returns a pod labeled app=web with the containers, whose restart count is restarts
+func logPod(name string, restarts int32, containers ...string) *corev1.Pod {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:      name,
+		Namespace: "default",
+		UID:       types.UID(name + "-uid"),
+		Labels:    map[string]string{"app": "web"},
+	}}
+	for _, container := range containers {
+		pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: container})
+		pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, corev1.ContainerStatus{Name: container, RestartCount: restarts})
+	}
+	return pod
+}
+
+func TestLogSearch_Follow(t *testing.T) {
+	// the logs of the fake clientset are a single line, read to the end once streamed
+	var streamed atomic.Int32
+	search := &logSearch{
+		clientset: fake.NewSimpleClientset(),
+		namespace: "default",
+		matcher:   func(string) bool { streamed.Add(1); return false },
+		container: "app",
+		streams:   make(map[string]bool),
+		searched:  make(map[string]struct{}),
+		matches:   make(chan LogMatch, 1),
+	}
+
+	steps := []struct {
+		name     string
+		pod      *corev1.Pod
+		expected int32
+	}{
+		{name: "new container followed", pod: logPod("web", 0, "app", "sidecar"), expected: 1},
+		{name: "logs read to the end not followed again", pod: logPod("web", 0, "app", "sidecar"), expected: 1},
+		{name: "restarted container followed again", pod: logPod("web", 1, "app", "sidecar"), expected: 2},
+		{name: "new pod followed", pod: logPod("web-2", 1, "app"), expected: 3},
+	}
+	for _, step := range steps {
+		search.follow(context.TODO(), *step.pod)
+		search.wg.Wait()
+		if got := streamed.Load(); got != step.expected {
+			t.Errorf("%s: expected %d streams, got %d", step.name, step.expected, got)
+		}
+	}
+	if searched := search.searchedPods(); strings.Join(searched, ",") != "web,web-2" {
+		t.Errorf("unexpected searched pods: %v", searched)
+	}
+}
+
+func TestSearchPodLogs(t *testing.T) {
+	interval := logSearchInterval
+	logSearchInterval = 10 * time.Millisecond
+	t.Cleanup(func() { logSearchInterval = interval })
+
+	t.Run("line matched", func(t *testing.T) {
+		clientset := fake.NewSimpleClientset(logPod("web", 0, "app"))
+		match, err := searchPodLogs(context.TODO(), clientset, "default", "app=web", func(line string) bool { return line == "fake logs" }, LogSearchOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := (LogMatch{Pod: "web", Container: "app", Line: "fake logs"}); match != expected {
+			t.Errorf("expected match %+v, got %+v", expected, match)
+		}
+	})
+
+	t.Run("restarted container searched again", func(t *testing.T) {
+		clientset := fake.NewSimpleClientset(logPod("web", 0, "app"))
+		var calls atomic.Int32
+		matcher := func(string) bool {
+			if calls.Add(1) > 1 {
+				return true
+			}
+			// the container restarts once its first instance logged its line
+			if _, err := clientset.CoreV1().Pods("default").UpdateStatus(context.TODO(), logPod("web", 1, "app"), metav1.UpdateOptions{}); err != nil {
+				t.Error(err)
+			}
+			return false
+		}
+		if _, err := searchPodLogs(context.TODO(), clientset, "default", "app=web", matcher, LogSearchOptions{Timeout: 5 * time.Second}); err != nil {
+			t.Fatal(err)
+		}
+		if got := calls.Load(); got != 2 {
+			t.Errorf("expected the logs of both instances to be searched, got %d lines", got)
+		}
+	})
+
+	t.Run("no line matched", func(t *testing.T) {
+		clientset := fake.NewSimpleClientset(logPod("web", 0, "app"), logPod("db", 0, "db"))
+		_, err := searchPodLogs(context.TODO(), clientset, "default", "app=web", func(string) bool { return false }, LogSearchOptions{Timeout: 50 * time.Millisecond})
+		if err == nil || !strings.Contains(err.Error(), "searched pods: db, web") {
+			t.Errorf("expected an error listing the searched pods, got %v", err)
+		}
+	})
+}
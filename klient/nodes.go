@@ -0,0 +1,192 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package klient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// mirrorPodAnnotation is set on the mirror pods of the static pods, which cannot be evicted
+const mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+// drainInterval is the delay between two eviction rounds of DrainNode
+var drainInterval = 2 * time.Second
+
+// DrainOptions configures DrainNode
+type DrainOptions struct {
+	// Timeout bounds the duration of the drain, when set. The context passed to
+	// DrainNode bounds it as well.
+	Timeout time.Duration
+	// GracePeriodSeconds overrides the termination grace period of the evicted pods, when set
+	GracePeriodSeconds *int64
+	// Progress, when set, is called after each eviction round with the namespaced names of
+	// the pods still running on the node
+	Progress func(pending []string)
+}
+
+// CordonNode marks the node as unschedulable, so that no new pod is scheduled on it
+func CordonNode(ctx context.Context, cfg *rest.Config, name string) error {
+	return setUnschedulable(ctx, cfg, name, true)
+}
+
+// UncordonNode marks the node as schedulable again, undoing CordonNode or DrainNode
+func UncordonNode(ctx context.Context, cfg *rest.Config, name string) error {
+	return setUnschedulable(ctx, cfg, name, false)
+}
+
+func setUnschedulable(ctx context.Context, cfg *rest.Config, name string, unschedulable bool) error {
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("node %s: %w", name, err)
+	}
+	return patchUnschedulable(ctx, clientset, name, unschedulable)
+}
+
+func patchUnschedulable(ctx context.Context, clientset kubernetes.Interface, name string, unschedulable bool) error {
+	patch := fmt.Sprintf(`{"spec":{"unschedulable":%t}}`, unschedulable)
+	if _, err := clientset.CoreV1().Nodes().Patch(ctx, name, types.StrategicMergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("node %s: %w", name, err)
+	}
+	return nil
+}
+
+// DrainNode cordons the node and evicts its pods, as kubectl drain does, to test the resilience of
+// workloads to node disruptions. The pods are evicted through the Eviction API, which respects their
+// PodDisruptionBudgets: the evictions refused by a budget are retried until the budget allows them.
+// The pods managed by a DaemonSet, the mirror pods of static pods and the completed pods are left on
+// the node. DrainNode returns once the evicted pods are deleted, or an error listing the pods still
+// running on the node, and the last eviction error, if the drain stalls until the timeout of the
+// options or the cancellation of the context. The node stays cordoned once drained, see UncordonNode,
+// but it is uncordoned when the drain fails, so that a failed drain leaves no unschedulable node behind.
+func DrainNode(ctx context.Context, cfg *rest.Config, name string, opts DrainOptions) error {
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("drain node %s: %w", name, err)
+	}
+	return drainNode(ctx, clientset, name, opts)
+}
+
+func drainNode(ctx context.Context, clientset kubernetes.Interface, name string, opts DrainOptions) error {
+	if err := patchUnschedulable(ctx, clientset, name, true); err != nil {
+		return fmt.Errorf("drain: %w", err)
+	}
+	if err := evictNodePods(ctx, clientset, name, opts); err != nil {
+		// the context may be done already: the node is uncordoned regardless of its cancellation
+		if uncordonErr := patchUnschedulable(context.WithoutCancel(ctx), clientset, name, false); uncordonErr != nil {
+			return errors.Join(err, fmt.Errorf("uncordon: %w", uncordonErr))
+		}
+		return err
+	}
+	return nil
+}
+
+// evictNodePods evicts the drainable pods of the node until none is left running on it
+func evictNodePods(ctx context.Context, clientset kubernetes.Interface, name string, opts DrainOptions) error {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	var lastErr error
+	for {
+		pods, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+			FieldSelector: fields.OneTermEqualSelector("spec.nodeName", name).String(),
+		})
+		if err != nil {
+			lastErr = err
+		}
+		var pending []string
+		if pods != nil {
+			for _, pod := range drainablePods(pods.Items) {
+				pending = append(pending, pod.Namespace+"/"+pod.Name)
+				if pod.DeletionTimestamp != nil {
+					continue
+				}
+				if err := evictPod(ctx, clientset, pod, opts.GracePeriodSeconds); err != nil {
+					lastErr = fmt.Errorf("evict pod %s/%s: %w", pod.Namespace, pod.Name, err)
+				}
+			}
+		}
+		if opts.Progress != nil && err == nil {
+			opts.Progress(pending)
+		}
+		if err == nil && len(pending) == 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(drainInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			msg := fmt.Sprintf("drain node %s stalled: %s", name, ctx.Err())
+			if len(pending) > 0 {
+				msg += ": pods still running: " + strings.Join(pending, ", ")
+			}
+			if lastErr != nil {
+				return fmt.Errorf("%s: last error: %w", msg, lastErr)
+			}
+			return errors.New(msg)
+		case <-timer.C:
+		}
+	}
+}
+
+// evictPod evicts the pod through the Eviction API, a pod already deleted not being an error
+func evictPod(ctx context.Context, clientset kubernetes.Interface, pod corev1.Pod, gracePeriodSeconds *int64) error {
+	eviction := &policyv1.Eviction{
+		ObjectMeta:    metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+		DeleteOptions: &metav1.DeleteOptions{GracePeriodSeconds: gracePeriodSeconds},
+	}
+	err := clientset.PolicyV1().Evictions(pod.Namespace).Evict(ctx, eviction)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// drainablePods returns the pods evicted by a drain: the pods neither completed nor managed by a
+// DaemonSet nor mirroring a static pod
+func drainablePods(pods []corev1.Pod) []corev1.Pod {
+	var result []corev1.Pod
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if _, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+			continue
+		}
+		if owner := metav1.GetControllerOf(&pod); owner != nil && owner.Kind == "DaemonSet" {
+			continue
+		}
+		result = append(result, pod)
+	}
+	return result
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package klient
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestDrainablePods(t *testing.T) {
+	pod := func(name string, mutate func(*corev1.Pod)) corev1.Pod {
+		p := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}, Status: corev1.PodStatus{Phase: corev1.PodRunning}}
+		if mutate != nil {
+			mutate(&p)
+		}
+		return p
+	}
+	controller := true
+	pods := []corev1.Pod{
+		pod("regular", nil),
+		pod("succeeded", func(p *corev1.Pod) { p.Status.Phase = corev1.PodSucceeded }),
+		pod("failed", func(p *corev1.Pod) { p.Status.Phase = corev1.PodFailed }),
+		pod("mirror", func(p *corev1.Pod) { p.Annotations = map[string]string{mirrorPodAnnotation: "hash"} }),
+		pod("daemon", func(p *corev1.Pod) {
+			p.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "ds", Controller: &controller}}
+		}),
+		pod("replica", func(p *corev1.Pod) {
+			p.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "rs", Controller: &controller}}
+		}),
+		pod("pending", func(p *corev1.Pod) { p.Status.Phase = corev1.PodPending }),
+	}
+
+	var names []string
+	for _, p := range drainablePods(pods) {
+		names = append(names, p.Name)
+	}
+	if expected := []string{"regular", "replica", "pending"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected drainable pods %v, got %v", expected, names)
+	}
+}
+
+// drainClientset returns a fake clientset holding the node and its pods, whose evictions delete the
+// pods unless refused by refuse, which mimics the PodDisruptionBudgets
+func drainClientset(node string, refuse func(pod string) bool, pods ...string) (*fake.Clientset, *[]string) {
+	objs := []runtime.Object{&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: node}}}
+	for _, name := range pods {
+		objs = append(objs, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       corev1.PodSpec{NodeName: node},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		})
+	}
+	clientset := fake.NewSimpleClientset(objs...)
+	var evicted []string
+	clientset.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+		if refuse != nil && refuse(eviction.Name) {
+			return true, nil, apierrors.NewTooManyRequests("cannot evict pod as it would violate the pod's disruption budget", 0)
+		}
+		evicted = append(evicted, eviction.Name)
+		gvr := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+		return true, nil, clientset.Tracker().Delete(gvr, eviction.Namespace, eviction.Name)
+	})
+	return clientset, &evicted
+}
+
+func unschedulable(t *testing.T, clientset *fake.Clientset, name string) bool {
+	t.Helper()
+	node, err := clientset.CoreV1().Nodes().Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return node.Spec.Unschedulable
+}
+
+func TestDrainNode(t *testing.T) {
+	interval := drainInterval
+	drainInterval = 10 * time.Millisecond
+	t.Cleanup(func() { drainInterval = interval })
+
+	t.Run("pods evicted", func(t *testing.T) {
+		clientset, evicted := drainClientset("node", nil, "a", "b")
+		var progress [][]string
+		opts := DrainOptions{Progress: func(pending []string) { progress = append(progress, pending) }}
+		if err := drainNode(context.TODO(), clientset, "node", opts); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if expected := []string{"a", "b"}; !reflect.DeepEqual(*evicted, expected) {
+			t.Errorf("expected evicted pods %v, got %v", expected, *evicted)
+		}
+		if expected := [][]string{{"default/a", "default/b"}, nil}; !reflect.DeepEqual(progress, expected) {
+			t.Errorf("expected progress %v, got %v", expected, progress)
+		}
+		if !unschedulable(t, clientset, "node") {
+			t.Error("expected the drained node to stay cordoned")
+		}
+	})
+
+	t.Run("evictions refused by a budget retried", func(t *testing.T) {
+		refusals := 2
+		refuse := func(pod string) bool {
+			if refusals > 0 {
+				refusals--
+				return true
+			}
+			return false
+		}
+		clientset, evicted := drainClientset("node", refuse, "a")
+		if err := drainNode(context.TODO(), clientset, "node", DrainOptions{}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if expected := []string{"a"}; !reflect.DeepEqual(*evicted, expected) {
+			t.Errorf("expected evicted pods %v, got %v", expected, *evicted)
+		}
+	})
+
+	t.Run("stalled drain uncordons the node", func(t *testing.T) {
+		clientset, _ := drainClientset("node", func(string) bool { return true }, "a")
+		err := drainNode(context.TODO(), clientset, "node", DrainOptions{Timeout: 50 * time.Millisecond})
+		if err == nil {
+			t.Fatal("expected the drain to stall")
+		}
+		for _, part := range []string{"pods still running: default/a", "disruption budget"} {
+			if !strings.Contains(err.Error(), part) {
+				t.Errorf("expected error %q to contain %q", err, part)
+			}
+		}
+		if unschedulable(t, clientset, "node") {
+			t.Error("expected the node to be uncordoned after a failed drain")
+		}
+	})
+
+	t.Run("missing node", func(t *testing.T) {
+		clientset, _ := drainClientset("other", nil)
+		if err := drainNode(context.TODO(), clientset, "node", DrainOptions{}); !apierrors.IsNotFound(err) {
+			t.Errorf("expected a not found error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package klient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// portForward forwards a random local port to the port of the pod and returns the local port.
+// The port-forward is closed by calling the returned stop function.
+func portForward(ctx context.Context, cfg *rest.Config, clientset kubernetes.Interface, namespace, pod string, port int) (uint16, func(), error) {
+	transport, upgrader, err := spdy.RoundTripperFor(cfg)
+	if err != nil {
+		return 0, nil, err
+	}
+	req := clientset.CoreV1().RESTClient().Post().Resource("pods").Namespace(namespace).Name(pod).SubResource("portforward")
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, req.URL())
+
+	stopCh, readyCh := make(chan struct{}), make(chan struct{})
+	forwarder, err := portforward.New(dialer, []string{fmt.Sprintf("0:%d", port)}, stopCh, readyCh, io.Discard, io.Discard)
+	if err != nil {
+		close(stopCh)
+		return 0, nil, fmt.Errorf("port-forward to %s/%s: %w", namespace, pod, err)
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- forwarder.ForwardPorts()
+	}()
+	select {
+	case <-readyCh:
+	case err := <-errCh:
+		close(stopCh)
+		return 0, nil, fmt.Errorf("port-forward to %s/%s: %w", namespace, pod, err)
+	case <-ctx.Done():
+		close(stopCh)
+		return 0, nil, fmt.Errorf("port-forward to %s/%s: %w", namespace, pod, ctx.Err())
+	}
+	ports, err := forwarder.GetPorts()
+	if err != nil {
+		close(stopCh)
+		return 0, nil, fmt.Errorf("port-forward to %s/%s: %w", namespace, pod, err)
+	}
+	return ports[0].Local, func() { close(stopCh) }, nil
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package klient
+
+import (
+	"context"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/client-go/rest"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+)
+
+// RetryPolicy configures the retries of the operations failing with a transient API error,
+// see NewRetryingClient
+type RetryPolicy struct {
+	// Attempts is the maximum number of attempts of an operation, including the first one.
+	// The operations are not retried when it is lower than 2.
+	Attempts int
+	// Backoff is the delay before the first retry, doubled at each subsequent retry
+	Backoff time.Duration
+	// MaxBackoff caps the delay between two attempts, when set
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy is a retry policy suitable for most test suites
+var DefaultRetryPolicy = RetryPolicy{Attempts: 5, Backoff: 200 * time.Millisecond, MaxBackoff: 5 * time.Second}
+
+// IsTransientError indicates if the error returned by an API operation is transient, i.e. if
+// the operation may succeed when retried as is: a server timeout or a connection error. A
+// conflict is not transient, as the operation keeps failing until the object is refreshed.
+func IsTransientError(err error) bool {
+	return apierrors.IsServerTimeout(err) ||
+		utilnet.IsConnectionRefused(err) ||
+		utilnet.IsConnectionReset(err) ||
+		utilnet.IsProbableEOF(err)
+}
+
+// isNotSentError indicates if the error returned by an API operation shows that the request
+// never reached the API server, so that the operation was not performed
+func isNotSentError(err error) bool {
+	return utilnet.IsConnectionRefused(err)
+}
+
+// NewRetryingClient returns a Client performing its operations with the client c and retrying
+// the Get, List, Update, Patch and Delete operations of its Resources failing with a transient
+// error, see IsTransientError, according to the policy. As a Create that failed otherwise may
+// have been persisted, it is only retried when its request never reached the API server, e.g.
+// when the connection was refused. The retries stop when the context of the operation is done,
+// the last error being returned.
+//
+// Wrapping a client already retrying its operations replaces its policy.
+func NewRetryingClient(c Client, policy RetryPolicy) Client {
+	inner := c.Resources().GetControllerRuntimeClient()
+	client, replaced := replaceWrapper(inner, func(retrying *retryingCRClient) cr.Client {
+		return &retryingCRClient{Client: retrying.Client, policy: policy}
+	})
+	if !replaced {
+		client = &retryingCRClient{Client: inner, policy: policy}
+	}
+	return &retryingClient{
+		cfg:    c.RESTConfig(),
+		client: client,
+	}
+}
+
+// retryingClient is the Client returned by NewRetryingClient
+type retryingClient struct {
+	cfg    *rest.Config
+	client cr.Client
+}
+
+// RESTConfig returns the *rest.Config value associated with this client.
+func (c *retryingClient) RESTConfig() *rest.Config {
+	return c.cfg
+}
+
+// Resources returns *Resources value to access CRUD object operations, the operations
+// failing with a transient error being retried. It takes 0 or, at most, 1 namespace, or panics.
+func (c *retryingClient) Resources(namespace ...string) *resources.Resources {
+	res := resources.NewFromClient(c.cfg, c.client)
+	switch len(namespace) {
+	case 0:
+		return res
+	case 1:
+		return res.WithNamespace(namespace[0])
+	default:
+		panic("too many namespaces provided")
+	}
+}
+
+// wrappedCRClient is implemented by the controller runtime clients of this package wrapping another one
+type wrappedCRClient interface {
+	// unwrap returns the wrapped client
+	unwrap() cr.Client
+	// rewrap returns a copy of the client wrapping c instead
+	rewrap(c cr.Client) cr.Client
+}
+
+var (
+	_ wrappedCRClient = (*countingCRClient)(nil)
+	_ wrappedCRClient = (*faultCRClient)(nil)
+	_ wrappedCRClient = (*retryingCRClient)(nil)
+	_ wrappedCRClient = (*timeoutCRClient)(nil)
+	_ wrappedCRClient = (*trackingCRClient)(nil)
+)
+
+// wraps indicates if the controller runtime client, or one of the clients it wraps, is a T
+func wraps[T cr.Client](c cr.Client) bool {
+	for c != nil {
+		if _, ok := c.(T); ok {
+			return true
+		}
+		wrapped, ok := c.(wrappedCRClient)
+		if !ok {
+			return false
+		}
+		c = wrapped.unwrap()
+	}
+	return false
+}
+
+// replaceWrapper returns a copy of the controller runtime client c in which the first T of the
+// chain of wrapped clients is replaced by the client returned by replace, and true, or c and
+// false if c does not wrap a T
+func replaceWrapper[T cr.Client](c cr.Client, replace func(T) cr.Client) (cr.Client, bool) {
+	if t, ok := c.(T); ok {
+		return replace(t), true
+	}
+	wrapped, ok := c.(wrappedCRClient)
+	if !ok {
+		return c, false
+	}
+	inner, replaced := replaceWrapper(wrapped.unwrap(), replace)
+	if !replaced {
+		return c, false
+	}
+	return wrapped.rewrap(inner), true
+}
+
+// retryingCRClient is a controller runtime client retrying the operations failing with a transient error
+type retryingCRClient struct {
+	cr.Client
+	policy RetryPolicy
+}
+
+func (c *retryingCRClient) unwrap() cr.Client {
+	return c.Client
+}
+
+func (c *retryingCRClient) rewrap(inner cr.Client) cr.Client {
+	return &retryingCRClient{Client: inner, policy: c.policy}
+}
+
+// retry executes the operation until it succeeds, fails with an error that is not retriable, the
+// attempts of the policy are exhausted or the context is done
+func (c *retryingCRClient) retry(ctx context.Context, retriable func(error) bool, op func() error) error {
+	backoff := c.policy.Backoff
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || !retriable(err) || attempt >= c.policy.Attempts {
+			return err
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff *= 2
+		if c.policy.MaxBackoff > 0 && backoff > c.policy.MaxBackoff {
+			backoff = c.policy.MaxBackoff
+		}
+	}
+}
+
+func (c *retryingCRClient) Get(ctx context.Context, key cr.ObjectKey, obj cr.Object, opts ...cr.GetOption) error {
+	return c.retry(ctx, IsTransientError, func() error { return c.Client.Get(ctx, key, obj, opts...) })
+}
+
+func (c *retryingCRClient) List(ctx context.Context, list cr.ObjectList, opts ...cr.ListOption) error {
+	return c.retry(ctx, IsTransientError, func() error { return c.Client.List(ctx, list, opts...) })
+}
+
+func (c *retryingCRClient) Create(ctx context.Context, obj cr.Object, opts ...cr.CreateOption) error {
+	return c.retry(ctx, isNotSentError, func() error { return c.Client.Create(ctx, obj, opts...) })
+}
+
+func (c *retryingCRClient) Update(ctx context.Context, obj cr.Object, opts ...cr.UpdateOption) error {
+	return c.retry(ctx, IsTransientError, func() error { return c.Client.Update(ctx, obj, opts...) })
+}
+
+func (c *retryingCRClient) Patch(ctx context.Context, obj cr.Object, patch cr.Patch, opts ...cr.PatchOption) error {
+	return c.retry(ctx, IsTransientError, func() error { return c.Client.Patch(ctx, obj, patch, opts...) })
+}
+
+func (c *retryingCRClient) Delete(ctx context.Context, obj cr.Object, opts ...cr.DeleteOption) error {
+	return c.retry(ctx, IsTransientError, func() error { return c.Client.Delete(ctx, obj, opts...) })
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package klient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"sigs.k8s.io/e2e-framework/internal/testutil"
+)
+
+func TestNewRetryingClient(t *testing.T) {
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "default"}}
+	refused := fmt.Errorf("dial: %w", syscall.ECONNREFUSED)
+	timeout := apierrors.NewServerTimeout(corev1.Resource("configmaps"), "create", 1)
+	conflict := apierrors.NewConflict(corev1.Resource("configmaps"), cm.Name, errors.New("modified"))
+	policy := RetryPolicy{Attempts: 3, Backoff: time.Millisecond}
+
+	tests := []struct {
+		name     string
+		err      error
+		op       func(ctx context.Context, c Client) error
+		attempts int
+	}{
+		{
+			name:     "create refused connection is retried",
+			err:      refused,
+			op:       func(ctx context.Context, c Client) error { return c.Resources().Create(ctx, cm.DeepCopy()) },
+			attempts: 3,
+		},
+		{
+			name:     "create server timeout is not retried",
+			err:      timeout,
+			op:       func(ctx context.Context, c Client) error { return c.Resources().Create(ctx, cm.DeepCopy()) },
+			attempts: 1,
+		},
+		{
+			name:     "update server timeout is retried",
+			err:      timeout,
+			op:       func(ctx context.Context, c Client) error { return c.Resources().Update(ctx, cm.DeepCopy()) },
+			attempts: 3,
+		},
+		{
+			name:     "update conflict is not retried",
+			err:      conflict,
+			op:       func(ctx context.Context, c Client) error { return c.Resources().Update(ctx, cm.DeepCopy()) },
+			attempts: 1,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			var attempts int
+			fail := func() error {
+				attempts++
+				return test.err
+			}
+			client := NewRetryingClient(testutil.NewFakeClient(interceptor.Funcs{
+				Create: func(context.Context, cr.WithWatch, cr.Object, ...cr.CreateOption) error { return fail() },
+				Update: func(context.Context, cr.WithWatch, cr.Object, ...cr.UpdateOption) error { return fail() },
+			}), policy)
+			if err := test.op(context.TODO(), client); !errors.Is(err, test.err) {
+				t.Errorf("expected error %v, got %v", test.err, err)
+			}
+			if attempts != test.attempts {
+				t.Errorf("expected %d attempts, got %d", test.attempts, attempts)
+			}
+		})
+	}
+}
+
+func TestNewRetryingClient_ReplacesPolicy(t *testing.T) {
+	var attempts int
+	base := testutil.NewFakeClient(interceptor.Funcs{
+		Get: func(context.Context, cr.WithWatch, cr.ObjectKey, cr.Object, ...cr.GetOption) error {
+			attempts++
+			return apierrors.NewServerTimeout(corev1.Resource("configmaps"), "get", 1)
+		},
+	})
+	retrying := NewRetryingClient(base, RetryPolicy{Attempts: 2, Backoff: time.Millisecond})
+
+	for _, c := range []Client{
+		NewRetryingClient(retrying, RetryPolicy{Attempts: 4, Backoff: time.Millisecond}),
+		// the retrying client is also replaced when wrapped by another client
+		NewRetryingClient(NewTrackingClient(retrying), RetryPolicy{Attempts: 4, Backoff: time.Millisecond}),
+	} {
+		attempts = 0
+		_ = c.Resources().Get(context.TODO(), "config", "default", &corev1.ConfigMap{})
+		if attempts != 4 {
+			t.Errorf("expected the policy to be replaced, got %d attempts", attempts)
+		}
+	}
+}
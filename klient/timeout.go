@@ -0,0 +1,130 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package klient
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/client-go/rest"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+)
+
+// NewTimeoutClient returns a Client performing its operations with the client c and bounding
+// each Get, List, Create, Update, Patch and Delete operation of its Resources by the timeout,
+// the context of the operation still bounding it as well.
+//
+// The timeout is a default: the timeout of an operation performed with a Resources returned by
+// WithOpTimeout takes precedence over it, a zero timeout leaving the operation unbounded. A
+// client wrapping c to retry its operations, see NewRetryingClient, bounds each attempt. Wrapping
+// a client returned by NewTimeoutClient replaces its timeout.
+func NewTimeoutClient(c Client, timeout time.Duration) Client {
+	inner := c.Resources().GetControllerRuntimeClient()
+	if bounded, ok := inner.(*timeoutCRClient); ok {
+		inner = bounded.unwrap()
+	}
+	return &timeoutClient{
+		cfg:    c.RESTConfig(),
+		client: &timeoutCRClient{Client: inner, timeout: timeout},
+	}
+}
+
+// timeoutClient is the Client returned by NewTimeoutClient
+type timeoutClient struct {
+	cfg    *rest.Config
+	client cr.Client
+}
+
+// RESTConfig returns the *rest.Config value associated with this client.
+func (c *timeoutClient) RESTConfig() *rest.Config {
+	return c.cfg
+}
+
+// Resources returns *Resources value to access CRUD object operations, each operation being
+// bounded by the default timeout. It takes 0 or, at most, 1 namespace, or panics.
+func (c *timeoutClient) Resources(namespace ...string) *resources.Resources {
+	res := resources.NewFromClient(c.cfg, c.client)
+	switch len(namespace) {
+	case 0:
+		return res
+	case 1:
+		return res.WithNamespace(namespace[0])
+	default:
+		panic("too many namespaces provided")
+	}
+}
+
+// timeoutCRClient is a controller runtime client bounding its operations by a default timeout
+type timeoutCRClient struct {
+	cr.Client
+	timeout time.Duration
+}
+
+func (c *timeoutCRClient) unwrap() cr.Client {
+	return c.Client
+}
+
+func (c *timeoutCRClient) rewrap(inner cr.Client) cr.Client {
+	return &timeoutCRClient{Client: inner, timeout: c.timeout}
+}
+
+// opContext returns the context of an operation, bounded by the default timeout unless the
+// operation has its own timeout
+func (c *timeoutCRClient) opContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := resources.OpTimeoutFromContext(ctx); ok || c.timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+func (c *timeoutCRClient) Get(ctx context.Context, key cr.ObjectKey, obj cr.Object, opts ...cr.GetOption) error {
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+func (c *timeoutCRClient) List(ctx context.Context, list cr.ObjectList, opts ...cr.ListOption) error {
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+	return c.Client.List(ctx, list, opts...)
+}
+
+func (c *timeoutCRClient) Create(ctx context.Context, obj cr.Object, opts ...cr.CreateOption) error {
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *timeoutCRClient) Update(ctx context.Context, obj cr.Object, opts ...cr.UpdateOption) error {
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func (c *timeoutCRClient) Patch(ctx context.Context, obj cr.Object, patch cr.Patch, opts ...cr.PatchOption) error {
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func (c *timeoutCRClient) Delete(ctx context.Context, obj cr.Object, opts ...cr.DeleteOption) error {
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+	return c.Client.Delete(ctx, obj, opts...)
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package klient
+
+import (
+	"context"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+)
+
+// CreatedObject identifies an object created through a TrackingClient or a recording client
+type CreatedObject struct {
+	GVK       schema.GroupVersionKind
+	Namespace string
+	Name      string
+	// Applied is set when the object was created or updated with server-side apply
+	Applied bool
+}
+
+// NewRecordingClient returns a Client performing its operations with the client c and
+// calling record for each object created with the Create operation of its Resources, or
+// applied with server-side apply. The operations themselves are left unchanged.
+func NewRecordingClient(c Client, record func(CreatedObject)) Client {
+	return &recordingClient{
+		cfg:    c.RESTConfig(),
+		client: &trackingCRClient{Client: c.Resources().GetControllerRuntimeClient(), record: record},
+	}
+}
+
+// recordingClient is the Client returned by NewRecordingClient
+type recordingClient struct {
+	cfg    *rest.Config
+	client cr.Client
+}
+
+// RESTConfig returns the *rest.Config value associated with this client.
+func (c *recordingClient) RESTConfig() *rest.Config {
+	return c.cfg
+}
+
+// Resources returns *Resources value to access CRUD object operations, the objects
+// created with it being recorded. It takes 0 or, at most, 1 namespace, or panics.
+func (c *recordingClient) Resources(namespace ...string) *resources.Resources {
+	res := resources.NewFromClient(c.cfg, c.client)
+	switch len(namespace) {
+	case 0:
+		return res
+	case 1:
+		return res.WithNamespace(namespace[0])
+	default:
+		panic("too many namespaces provided")
+	}
+}
+
+// TrackingClient is a Client recording the objects created with the Create operation of its
+// Resources, e.g. to verify that the objects created by a test are deleted once it completes.
+// The objects created by other means, such as server-side apply, are not recorded.
+type TrackingClient struct {
+	*recordingClient
+
+	mu      sync.Mutex
+	created []CreatedObject
+}
+
+// NewTrackingClient returns a TrackingClient performing its operations with the client c
+func NewTrackingClient(c Client) *TrackingClient {
+	tc := &TrackingClient{}
+	tc.recordingClient = NewRecordingClient(c, func(obj CreatedObject) {
+		if !obj.Applied {
+			tc.record(obj)
+		}
+	}).(*recordingClient)
+	return tc
+}
+
+// Created returns the objects created through the client, in the order of their creation
+func (c *TrackingClient) Created() []CreatedObject {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]CreatedObject(nil), c.created...)
+}
+
+func (c *TrackingClient) record(obj CreatedObject) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.created = append(c.created, obj)
+}
+
+// trackingCRClient is a controller runtime client recording the objects it creates or applies
+type trackingCRClient struct {
+	cr.Client
+	record func(CreatedObject)
+}
+
+func (c *trackingCRClient) unwrap() cr.Client {
+	return c.Client
+}
+
+func (c *trackingCRClient) rewrap(inner cr.Client) cr.Client {
+	return &trackingCRClient{Client: inner, record: c.record}
+}
+
+func (c *trackingCRClient) Create(ctx context.Context, obj cr.Object, opts ...cr.CreateOption) error {
+	if err := c.Client.Create(ctx, obj, opts...); err != nil {
+		return err
+	}
+	c.record(CreatedObject{GVK: c.gvkFor(obj), Namespace: obj.GetNamespace(), Name: obj.GetName()})
+	return nil
+}
+
+func (c *trackingCRClient) Patch(ctx context.Context, obj cr.Object, patch cr.Patch, opts ...cr.PatchOption) error {
+	if err := c.Client.Patch(ctx, obj, patch, opts...); err != nil {
+		return err
+	}
+	if patch.Type() == types.ApplyPatchType && !isDryRun(opts) {
+		c.record(CreatedObject{GVK: c.gvkFor(obj), Namespace: obj.GetNamespace(), Name: obj.GetName(), Applied: true})
+	}
+	return nil
+}
+
+func (c *trackingCRClient) gvkFor(obj cr.Object) schema.GroupVersionKind {
+	gvk, err := c.Client.GroupVersionKindFor(obj)
+	if err != nil {
+		// the object was created, only its kind is not registered in the scheme
+		gvk = obj.GetObjectKind().GroupVersionKind()
+	}
+	return gvk
+}
+
+// isDryRun indicates if the patch options request a dry run, which does not persist the object
+func isDryRun(opts []cr.PatchOption) bool {
+	options := &cr.PatchOptions{}
+	options.ApplyOptions(opts)
+	return len(options.DryRun) > 0
+}
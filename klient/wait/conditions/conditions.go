@@ -189,7 +189,7 @@ func (c *Condition) ResourcesDeleted(list k8s.ObjectList) apimachinerywait.Condi
 func (c *Condition) ResourceDeleted(obj k8s.Object) apimachinerywait.ConditionWithContextFunc {
 	return func(ctx context.Context) (done bool, err error) {
 		log.V(4).InfoS("Checking for resource to be garbage collected", "resource", c.namespacedName(obj))
-		if err := c.resources.Get(context.Background(), obj.GetName(), obj.GetNamespace(), obj); err != nil {
+		if err := c.resources.Get(ctx, obj.GetName(), obj.GetNamespace(), obj); err != nil {
 			if errors.IsNotFound(err) {
 				return true, nil
 			}
@@ -259,7 +259,7 @@ func (c *Condition) PodConditionMatch(pod k8s.Object, conditionType v1.PodCondit
 func (c *Condition) PodPhaseMatch(pod k8s.Object, phase v1.PodPhase) apimachinerywait.ConditionWithContextFunc {
 	return func(ctx context.Context) (done bool, err error) {
 		log.V(4).InfoS("Checking for phase match", "resource", c.namespacedName(pod), "phase", phase)
-		if err := c.resources.Get(context.Background(), pod.GetName(), pod.GetNamespace(), pod); err != nil {
+		if err := c.resources.Get(ctx, pod.GetName(), pod.GetNamespace(), pod); err != nil {
 			return false, err
 		}
 		log.V(4).InfoS("Current phase", "phase", pod.(*v1.Pod).Status.Phase)
@@ -288,6 +288,31 @@ func (c *Condition) JobCompleted(job k8s.Object) apimachinerywait.ConditionWithC
 	return c.JobConditionMatch(job, batchv1.JobComplete, v1.ConditionTrue)
 }
 
+// JobSucceeded is a helper function used to check if the Job has completed successfully by checking if the
+// batchv1.JobComplete condition has reached v1.ConditionTrue state. Unlike JobCompleted, the check stops with an
+// error as soon as the batchv1.JobFailed condition has reached v1.ConditionTrue state, as the Job will never complete.
+func (c *Condition) JobSucceeded(job k8s.Object) apimachinerywait.ConditionWithContextFunc {
+	return func(ctx context.Context) (done bool, err error) {
+		log.V(4).InfoS("Checking for job to succeed", "resource", c.namespacedName(job))
+		if err := c.resources.Get(ctx, job.GetName(), job.GetNamespace(), job); err != nil {
+			return false, err
+		}
+		status := job.(*batchv1.Job).Status
+		for _, cond := range status.Conditions {
+			if cond.Status != v1.ConditionTrue {
+				continue
+			}
+			switch cond.Type {
+			case batchv1.JobComplete:
+				return true, nil
+			case batchv1.JobFailed:
+				return false, fmt.Errorf("job %s failed: %s: %s (%d succeeded, %d failed pod(s))", c.namespacedName(job), cond.Reason, cond.Message, status.Succeeded, status.Failed)
+			}
+		}
+		return false, nil
+	}
+}
+
 // JobFailed is a helper function used to check if the Job has failed by checking if the batchv1.JobFailed has reached
 // v1.ConditionTrue state
 func (c *Condition) JobFailed(job k8s.Object) apimachinerywait.ConditionWithContextFunc {
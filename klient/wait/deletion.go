@@ -0,0 +1,68 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wait
+
+import (
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	apimachinerywait "k8s.io/apimachinery/pkg/util/wait"
+	log "k8s.io/klog/v2"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/klient/wait/conditions"
+)
+
+// ForResourceDeleted waits for the resource with the name and namespace of obj to be deleted, that is
+// until getting it returns a NotFound error. When the resource is still present once the timeout
+// configured by the options expires, the error reports the finalizers remaining on the resource, which
+// are the usual reason for a deletion to be stuck.
+func ForResourceDeleted(r *resources.Resources, obj k8s.Object, opts ...Option) error {
+	err := For(conditions.New(r).ResourceDeleted(obj), opts...)
+	if err != nil && apimachinerywait.Interrupted(err) {
+		return fmt.Errorf("resource %s/%s was not deleted, remaining finalizers %v: %w", obj.GetNamespace(), obj.GetName(), obj.GetFinalizers(), err)
+	}
+	return err
+}
+
+// ForResourceDeletedWithFinalizerRemoval waits for the resource with the name and namespace of obj
+// to be deleted like ForResourceDeleted. When the resource is still present after the grace period,
+// its finalizers are removed to force its deletion before waiting again with the provided options.
+// This is meant for forced cleanups in teardowns, where a stuck finalizer must not leak resources
+// into the next tests.
+func ForResourceDeletedWithFinalizerRemoval(r *resources.Resources, obj k8s.Object, gracePeriod time.Duration, opts ...Option) error {
+	ctx := optionsContext(opts...)
+
+	graceOpts := append(append([]Option{}, opts...), WithTimeout(gracePeriod))
+	err := For(conditions.New(r).ResourceDeleted(obj), graceOpts...)
+	if err == nil || !apimachinerywait.Interrupted(err) || ctx.Err() != nil {
+		return err
+	}
+
+	log.V(2).InfoS("Removing finalizers to force the deletion of the resource", "namespace", obj.GetNamespace(), "name", obj.GetName(), "finalizers", obj.GetFinalizers())
+	patch := k8s.Patch{PatchType: types.MergePatchType, Data: []byte(`{"metadata":{"finalizers":null}}`)}
+	if err := r.Patch(ctx, obj, patch); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to remove the finalizers of resource %s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+	}
+	return ForResourceDeleted(r, obj, opts...)
+}
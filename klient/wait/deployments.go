@@ -0,0 +1,75 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wait
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apimachinerywait "k8s.io/apimachinery/pkg/util/wait"
+	log "k8s.io/klog/v2"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+)
+
+// ForAllDeploymentsAvailable waits for all the Deployments of the namespace to be available, e.g.
+// after installing a chart made of several Deployments. The Deployments are listed on every check
+// so that the ones created while waiting are taken into account. The progress is logged on each
+// check, and the error returned once the timeout is exceeded, or ctx is done, reports the
+// Deployments that are not available along with their number of available replicas.
+func ForAllDeploymentsAvailable(ctx context.Context, r *resources.Resources, namespace string, timeout time.Duration) error {
+	var unavailable []string
+	err := For(func(ctx context.Context) (bool, error) {
+		var list appsv1.DeploymentList
+		if err := r.GetControllerRuntimeClient().List(ctx, &list, cr.InNamespace(namespace)); err != nil {
+			return false, err
+		}
+		unavailable = unavailableDeployments(list.Items)
+		log.V(2).InfoS("Waiting for the deployments to be available", "namespace", namespace, "available", len(list.Items)-len(unavailable), "total", len(list.Items))
+		return len(unavailable) == 0, nil
+	}, WithContext(ctx), WithTimeout(timeout), WithImmediate())
+	if err != nil && apimachinerywait.Interrupted(err) {
+		return fmt.Errorf("deployments of namespace %s not available: %s: %w", namespace, strings.Join(unavailable, ", "), err)
+	}
+	return err
+}
+
+// unavailableDeployments describes the deployments whose Available condition is not true
+func unavailableDeployments(deployments []appsv1.Deployment) []string {
+	var unavailable []string
+	for _, deployment := range deployments {
+		available := false
+		for _, cond := range deployment.Status.Conditions {
+			if cond.Type == appsv1.DeploymentAvailable && cond.Status == corev1.ConditionTrue {
+				available = true
+			}
+		}
+		if !available {
+			desired := int32(1)
+			if deployment.Spec.Replicas != nil {
+				desired = *deployment.Spec.Replicas
+			}
+			unavailable = append(unavailable, fmt.Sprintf("%s (%d/%d replicas available)", deployment.Name, deployment.Status.AvailableReplicas, desired))
+		}
+	}
+	return unavailable
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wait
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apimachinerywait "k8s.io/apimachinery/pkg/util/wait"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+)
+
+// recentEventsReported is the number of recent events reported when no event matched
+const recentEventsReported = 10
+
+// ForEvent waits for an event of the namespace to satisfy match and returns the first one found.
+// This lets assessments verify outcomes that controllers only surface as events. When no event
+// matched before the timeout configured by the options, the error lists the most recent events
+// of the namespace for context.
+func ForEvent(r *resources.Resources, namespace string, match func(ev corev1.Event) bool, opts ...Option) (*corev1.Event, error) {
+	var matched *corev1.Event
+	var events []corev1.Event
+	err := For(func(ctx context.Context) (bool, error) {
+		var list corev1.EventList
+		if err := r.GetControllerRuntimeClient().List(ctx, &list, cr.InNamespace(namespace)); err != nil {
+			return false, err
+		}
+		events = list.Items
+		for i := range events {
+			if match(events[i]) {
+				matched = &events[i]
+				return true, nil
+			}
+		}
+		return false, nil
+	}, opts...)
+	if err != nil && apimachinerywait.Interrupted(err) {
+		return nil, fmt.Errorf("no event of namespace %s matched, recent events:%s: %w", namespace, formatRecentEvents(events), err)
+	}
+	return matched, err
+}
+
+// formatRecentEvents formats the most recent events, one per line
+func formatRecentEvents(events []corev1.Event) string {
+	if len(events) == 0 {
+		return " none"
+	}
+	recent := append([]corev1.Event(nil), events...)
+	sort.SliceStable(recent, func(i, j int) bool {
+		return eventTime(recent[i]).After(eventTime(recent[j]))
+	})
+	if len(recent) > recentEventsReported {
+		recent = recent[:recentEventsReported]
+	}
+	var sb strings.Builder
+	for _, ev := range recent {
+		fmt.Fprintf(&sb, "\n  %s %s %s/%s: %s", ev.Type, ev.Reason, ev.InvolvedObject.Kind, ev.InvolvedObject.Name, ev.Message)
+	}
+	return sb.String()
+}
+
+// eventTime returns the time the event was last observed
+func eventTime(ev corev1.Event) time.Time {
+	switch {
+	case !ev.LastTimestamp.IsZero():
+		return ev.LastTimestamp.Time
+	case !ev.EventTime.IsZero():
+		return ev.EventTime.Time
+	default:
+		return ev.CreationTimestamp.Time
+	}
+}
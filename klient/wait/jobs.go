@@ -0,0 +1,41 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wait
+
+import (
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apimachinerywait "k8s.io/apimachinery/pkg/util/wait"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/klient/wait/conditions"
+)
+
+// ForJobCompleted waits for the Job with the given name and namespace to complete successfully.
+// It returns an error as soon as the Job fails, with the reason of the failure, or when the Job
+// has not completed before the timeout configured by the options. In both cases, the error
+// reports the number of succeeded and failed pods of the Job.
+func ForJobCompleted(r *resources.Resources, name, namespace string, opts ...Option) error {
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	err := For(conditions.New(r).JobSucceeded(job), opts...)
+	if err != nil && apimachinerywait.Interrupted(err) {
+		return fmt.Errorf("job %s/%s did not complete (%d succeeded, %d failed pod(s)): %w", namespace, name, job.Status.Succeeded, job.Status.Failed, err)
+	}
+	return err
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wait
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apimachinerywait "k8s.io/apimachinery/pkg/util/wait"
+	log "k8s.io/klog/v2"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+)
+
+// ForRolloutComplete waits for the rollout of the Deployment with the given name and namespace to
+// complete, as kubectl rollout status does, e.g. after updating its image: the Deployment controller
+// must have observed its last generation, and all its replicas must be updated and available, the
+// old ones being terminated. The progress is logged on each check. The error returned once the
+// timeout configured by the options is exceeded, or right away when the progress deadline of the
+// Deployment is exceeded, reports the progress of the rollout and the reasons its pods are not
+// ready, such as ImagePullBackOff.
+func ForRolloutComplete(r *resources.Resources, name, namespace string, opts ...Option) error {
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	reason := "no status observed"
+	err := For(func(ctx context.Context) (bool, error) {
+		if err := r.Get(ctx, name, namespace, deployment); err != nil {
+			return false, err
+		}
+		var done bool
+		var err error
+		done, reason, err = RolloutComplete(deployment)
+		if err == nil && !done {
+			log.V(2).InfoS("Waiting for the deployment rollout to complete", "namespace", namespace, "name", name, "progress", reason)
+		}
+		return done, err
+	}, opts...)
+	if err == nil {
+		return nil
+	}
+	var pods string
+	if failing := failingPods(optionsContext(opts...), r, deployment); len(failing) > 0 {
+		pods = ", pods not ready: " + strings.Join(failing, ", ")
+	}
+	if apimachinerywait.Interrupted(err) {
+		return fmt.Errorf("deployment %s/%s rollout not complete: %s%s: %w", namespace, name, reason, pods, err)
+	}
+	return fmt.Errorf("deployment %s/%s rollout failed%s: %w", namespace, name, pods, err)
+}
+
+// failingPods describes the reasons the pods of the deployment are not ready, none if they cannot
+// be listed, e.g. when the context is done
+func failingPods(ctx context.Context, r *resources.Resources, deployment *appsv1.Deployment) []string {
+	if deployment.Spec.Selector == nil {
+		return nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil
+	}
+	var list corev1.PodList
+	if err := r.GetControllerRuntimeClient().List(ctx, &list, cr.InNamespace(deployment.Namespace), cr.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil
+	}
+	var failing []string
+	for _, pod := range list.Items {
+		if reason := podNotReadyReason(pod); reason != "" {
+			failing = append(failing, fmt.Sprintf("%s (%s)", pod.Name, reason))
+		}
+	}
+	sort.Strings(failing)
+	return failing
+}
+
+// podNotReadyReason returns the reason why the pod is not ready, such as the waiting reason of one of
+// its containers or the reason it is not scheduled, or an empty string if the pod is ready
+func podNotReadyReason(pod corev1.Pod) string {
+	for _, status := range append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...) {
+		if waiting := status.State.Waiting; waiting != nil && waiting.Reason != "" && waiting.Reason != "ContainerCreating" && waiting.Reason != "PodInitializing" {
+			if waiting.Message != "" {
+				return fmt.Sprintf("%s: %s: %s", status.Name, waiting.Reason, waiting.Message)
+			}
+			return fmt.Sprintf("%s: %s", status.Name, waiting.Reason)
+		}
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodScheduled && cond.Status == corev1.ConditionFalse {
+			return fmt.Sprintf("%s: %s", cond.Reason, cond.Message)
+		}
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady && cond.Status != corev1.ConditionTrue {
+			return "not ready"
+		}
+	}
+	return ""
+}
+
+// RolloutComplete indicates if the rollout of the Deployment, StatefulSet or DaemonSet is complete,
+// as computed by kubectl rollout status, or the reason why it is not. An error is returned when the
+// rollout cannot complete, e.g. when the progress deadline of a Deployment is exceeded, or when the
+// rollout status is not available for the object.
+func RolloutComplete(obj k8s.Object) (done bool, reason string, err error) {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		if o.Generation > o.Status.ObservedGeneration {
+			return false, "waiting for the deployment spec update to be observed", nil
+		}
+		for _, cond := range o.Status.Conditions {
+			if cond.Type == appsv1.DeploymentProgressing && cond.Reason == "ProgressDeadlineExceeded" {
+				return false, "", fmt.Errorf("deployment %s exceeded its progress deadline", o.Name)
+			}
+		}
+		replicas := int32(1)
+		if o.Spec.Replicas != nil {
+			replicas = *o.Spec.Replicas
+		}
+		switch {
+		case o.Status.UpdatedReplicas < replicas:
+			return false, fmt.Sprintf("%d out of %d new replicas have been updated", o.Status.UpdatedReplicas, replicas), nil
+		case o.Status.Replicas > o.Status.UpdatedReplicas:
+			return false, fmt.Sprintf("%d old replicas are pending termination", o.Status.Replicas-o.Status.UpdatedReplicas), nil
+		case o.Status.AvailableReplicas < o.Status.UpdatedReplicas:
+			return false, fmt.Sprintf("%d of %d updated replicas are available", o.Status.AvailableReplicas, o.Status.UpdatedReplicas), nil
+		}
+		return true, "", nil
+	case *appsv1.StatefulSet:
+		if o.Spec.UpdateStrategy.Type != appsv1.RollingUpdateStatefulSetStrategyType {
+			return false, "", fmt.Errorf("rollout status is only available for the %s strategy type", appsv1.RollingUpdateStatefulSetStrategyType)
+		}
+		if o.Generation > o.Status.ObservedGeneration {
+			return false, "waiting for the statefulset spec update to be observed", nil
+		}
+		replicas := int32(1)
+		if o.Spec.Replicas != nil {
+			replicas = *o.Spec.Replicas
+		}
+		if o.Status.ReadyReplicas < replicas {
+			return false, fmt.Sprintf("%d of %d pods are ready", o.Status.ReadyReplicas, replicas), nil
+		}
+		if rollingUpdate := o.Spec.UpdateStrategy.RollingUpdate; rollingUpdate != nil && rollingUpdate.Partition != nil {
+			if partitioned := replicas - *rollingUpdate.Partition; o.Status.UpdatedReplicas < partitioned {
+				return false, fmt.Sprintf("%d of %d new pods of the partitioned rollout have been updated", o.Status.UpdatedReplicas, partitioned), nil
+			}
+			return true, "", nil
+		}
+		if o.Status.UpdateRevision != o.Status.CurrentRevision {
+			return false, fmt.Sprintf("%d of %d pods have been updated to revision %s", o.Status.UpdatedReplicas, replicas, o.Status.UpdateRevision), nil
+		}
+		return true, "", nil
+	case *appsv1.DaemonSet:
+		if o.Spec.UpdateStrategy.Type != appsv1.RollingUpdateDaemonSetStrategyType {
+			return false, "", fmt.Errorf("rollout status is only available for the %s strategy type", appsv1.RollingUpdateDaemonSetStrategyType)
+		}
+		if o.Generation > o.Status.ObservedGeneration {
+			return false, "waiting for the daemonset spec update to be observed", nil
+		}
+		switch {
+		case o.Status.UpdatedNumberScheduled < o.Status.DesiredNumberScheduled:
+			return false, fmt.Sprintf("%d out of %d new pods have been updated", o.Status.UpdatedNumberScheduled, o.Status.DesiredNumberScheduled), nil
+		case o.Status.NumberAvailable < o.Status.DesiredNumberScheduled:
+			return false, fmt.Sprintf("%d of %d updated pods are available", o.Status.NumberAvailable, o.Status.DesiredNumberScheduled), nil
+		}
+		return true, "", nil
+	default:
+		return false, "", fmt.Errorf("rollout status is not supported for %T", obj)
+	}
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wait_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"sigs.k8s.io/e2e-framework/internal/testutil"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+)
+
+// rolloutPod returns a pod of the web deployment with the status
+func rolloutPod(name string, status corev1.PodStatus) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": "web"}},
+		Status:     status,
+	}
+}
+
+func TestForRolloutComplete(t *testing.T) {
+	replicas := int32(3)
+	deployment := func(status appsv1.DeploymentStatus) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			},
+			Status: status,
+		}
+	}
+	pods := []*corev1.Pod{
+		rolloutPod("web-ready", corev1.PodStatus{Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}}),
+		rolloutPod("web-pull", corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+			Name:  "app",
+			State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "Back-off pulling image"}},
+		}}}),
+		rolloutPod("web-pending", corev1.PodStatus{Conditions: []corev1.PodCondition{{
+			Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: "Unschedulable", Message: "0/1 nodes are available",
+		}}}),
+		rolloutPod("web-starting", corev1.PodStatus{
+			InitContainerStatuses: []corev1.ContainerStatus{{Name: "init", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "PodInitializing"}}}},
+			Conditions:            []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionFalse}},
+		}),
+	}
+	failing := "pods not ready: web-pending (Unschedulable: 0/1 nodes are available), web-pull (app: ImagePullBackOff: Back-off pulling image), web-starting (not ready)"
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name       string
+		deployment *appsv1.Deployment
+		ctx        context.Context
+		wantErr    []string
+		unwanted   string
+	}{
+		{
+			name:       "rollout complete",
+			deployment: deployment(appsv1.DeploymentStatus{Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 3}),
+		},
+		{
+			name:       "rollout not complete",
+			deployment: deployment(appsv1.DeploymentStatus{Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 1}),
+			wantErr:    []string{"deployment default/web rollout not complete: 1 of 3 updated replicas are available, " + failing},
+		},
+		{
+			name: "progress deadline exceeded",
+			deployment: deployment(appsv1.DeploymentStatus{Conditions: []appsv1.DeploymentCondition{
+				{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded"},
+			}}),
+			wantErr: []string{"deployment default/web rollout failed, " + failing + ": deployment web exceeded its progress deadline"},
+		},
+		{
+			name:       "pods not listed once the context is done",
+			deployment: deployment(appsv1.DeploymentStatus{Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 1}),
+			ctx:        cancelled,
+			wantErr:    []string{"deployment default/web rollout not complete"},
+			unwanted:   "pods not ready",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			objs := []cr.Object{test.deployment}
+			for _, pod := range pods {
+				objs = append(objs, pod.DeepCopy())
+			}
+			// the pods are listed with the context of the caller, the one of the wait being done
+			r := testutil.NewFakeClient(interceptor.Funcs{
+				List: func(ctx context.Context, c cr.WithWatch, list cr.ObjectList, opts ...cr.ListOption) error {
+					if err := ctx.Err(); err != nil {
+						return err
+					}
+					return c.List(ctx, list, opts...)
+				},
+			}, objs...).Resources()
+			opts := []wait.Option{wait.WithTimeout(100 * time.Millisecond), wait.WithInterval(10 * time.Millisecond), wait.WithImmediate()}
+			if test.ctx != nil {
+				opts = append(opts, wait.WithContext(test.ctx))
+			}
+
+			err := wait.ForRolloutComplete(r, "web", "default", opts...)
+			if len(test.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range test.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("expected error %q to contain %q", err, want)
+				}
+			}
+			if test.unwanted != "" && strings.Contains(err.Error(), test.unwanted) {
+				t.Errorf("expected error %q not to contain %q", err, test.unwanted)
+			}
+		})
+	}
+}
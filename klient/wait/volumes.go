@@ -0,0 +1,69 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wait
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimachinerywait "k8s.io/apimachinery/pkg/util/wait"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+)
+
+// ForPVCBound waits for the PersistentVolumeClaim with the given name and namespace to be bound to
+// a volume. When the claim is not bound before the timeout configured by the options, the error
+// reports its phase and the most recent events involving it, e.g. a provisioning failure. The
+// claim not being found yet is not an error, so that ForPVCBound can be called right after
+// creating the workload whose volume claim template creates it.
+func ForPVCBound(r *resources.Resources, name, namespace string, opts ...Option) error {
+	phase := "not found"
+	err := For(func(ctx context.Context) (bool, error) {
+		var pvc corev1.PersistentVolumeClaim
+		if err := r.Get(ctx, name, namespace, &pvc); err != nil {
+			if apierrors.IsNotFound(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		phase = string(pvc.Status.Phase)
+		return pvc.Status.Phase == corev1.ClaimBound, nil
+	}, opts...)
+	if err != nil && apimachinerywait.Interrupted(err) {
+		return fmt.Errorf("persistentvolumeclaim %s/%s not bound (phase %s), recent events:%s: %w", namespace, name, phase, formatRecentEvents(claimEvents(optionsContext(opts...), r, name, namespace)), err)
+	}
+	return err
+}
+
+// claimEvents returns the events involving the PersistentVolumeClaim, none if they cannot be
+// listed, e.g. when the context is done
+func claimEvents(ctx context.Context, r *resources.Resources, name, namespace string) []corev1.Event {
+	var list corev1.EventList
+	if err := r.GetControllerRuntimeClient().List(ctx, &list, cr.InNamespace(namespace)); err != nil {
+		return nil
+	}
+	var events []corev1.Event
+	for _, ev := range list.Items {
+		if ev.InvolvedObject.Kind == "PersistentVolumeClaim" && ev.InvolvedObject.Name == name {
+			events = append(events, ev)
+		}
+	}
+	return events
+}
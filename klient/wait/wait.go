@@ -78,6 +78,19 @@ func WithImmediate() Option {
 	}
 }
 
+// optionsContext returns the context configured by the options with WithContext, the background
+// context if none, e.g. to list the diagnostics reported once a wait did not succeed
+func optionsContext(opts ...Option) context.Context {
+	options := &Options{}
+	for _, fn := range opts {
+		fn(options)
+	}
+	if options.Ctx == nil {
+		return context.Background()
+	}
+	return options.Ctx
+}
+
 // For provides a way to perform poll checks against the kubernetes resource to make sure the resource under
 // test has reached a suitable state before moving to the next action or fail with an error message.
 //
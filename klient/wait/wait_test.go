@@ -27,6 +27,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/watch"
 
 	"sigs.k8s.io/e2e-framework/klient/k8s"
 	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
@@ -88,6 +89,36 @@ func TestJobFailed(t *testing.T) {
 	}
 }
 
+func TestForJobCompleted(t *testing.T) {
+	job := createJob("j3", "echo", "kubernetes", t)
+	if err := wait.ForJobCompleted(getResourceManager(), job.Name, job.Namespace); err != nil {
+		t.Error("failed waiting for job to complete", err)
+	}
+
+	job = createJob("j4", "exit", "1", t)
+	if err := wait.ForJobCompleted(getResourceManager(), job.Name, job.Namespace); err == nil {
+		t.Error("expected an error waiting for a failing job to complete")
+	}
+}
+
+func TestForEvent(t *testing.T) {
+	pod := createPod("p-event", t)
+	ev, err := wait.ForEvent(getResourceManager(), pod.Namespace, func(ev v1.Event) bool {
+		return ev.InvolvedObject.Name == pod.Name && ev.Reason == "Scheduled"
+	}, wait.WithTimeout(time.Minute))
+	if err != nil {
+		t.Fatal("failed waiting for the pod to be scheduled", err)
+	}
+	if ev.InvolvedObject.Kind != "Pod" {
+		t.Errorf("unexpected kind of the object involved in the event: %s", ev.InvolvedObject.Kind)
+	}
+
+	_, err = wait.ForEvent(getResourceManager(), pod.Namespace, func(ev v1.Event) bool { return false }, wait.WithTimeout(5*time.Second))
+	if err == nil {
+		t.Error("expected an error when no event matches")
+	}
+}
+
 func TestResourceDeleted(t *testing.T) {
 	var err error
 	pod := createPod("p5", t)
@@ -107,6 +138,33 @@ func TestResourceDeleted(t *testing.T) {
 	}
 }
 
+func TestForResourceDeleted(t *testing.T) {
+	pod := createPod("p-deleted", t)
+	if err := getResourceManager().Delete(context.Background(), pod); err != nil {
+		t.Fatal(err)
+	}
+	if err := wait.ForResourceDeleted(getResourceManager(), pod, wait.WithTimeout(time.Minute)); err != nil {
+		t.Error("failed waiting for pod to be deleted", err)
+	}
+}
+
+func TestForResourceDeletedWithFinalizerRemoval(t *testing.T) {
+	pod := createPod("p-finalized", t)
+	pod.SetFinalizers([]string{"e2e-framework.k8s.io/test"})
+	if err := getResourceManager().Update(context.Background(), pod); err != nil {
+		t.Fatal(err)
+	}
+	if err := getResourceManager().Delete(context.Background(), pod); err != nil {
+		t.Fatal(err)
+	}
+	if err := wait.ForResourceDeleted(getResourceManager(), pod, wait.WithTimeout(5*time.Second), wait.WithInterval(time.Second)); err == nil {
+		t.Error("expected the deletion of the pod to be blocked by its finalizer")
+	}
+	if err := wait.ForResourceDeletedWithFinalizerRemoval(getResourceManager(), pod, 5*time.Second, wait.WithTimeout(time.Minute), wait.WithInterval(time.Second)); err != nil {
+		t.Error("failed waiting for pod to be deleted after removing its finalizers", err)
+	}
+}
+
 func TestResourceScaled(t *testing.T) {
 	var err error
 	deployment := createDeployment("d1", 2, t)
@@ -248,6 +306,14 @@ func TestDeploymentAvailable(t *testing.T) {
 	}
 }
 
+func TestForAllDeploymentsAvailable(t *testing.T) {
+	createDeployment("d8", 1, t)
+	createDeployment("d9", 2, t)
+	if err := wait.ForAllDeploymentsAvailable(context.TODO(), getResourceManager(), namespace, 5*time.Minute); err != nil {
+		t.Error("failed waiting for all the deployments to become available", err)
+	}
+}
+
 func TestForTimeout(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -268,6 +334,21 @@ func TestForTimeout(t *testing.T) {
 	}
 }
 
+func TestResourceDeletedCancelled(t *testing.T) {
+	pod := createPod("p-cancelled", t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	err := wait.For(conditions.New(getResourceManager()).ResourceDeleted(pod), wait.WithContext(ctx), wait.WithImmediate())
+	if err == nil {
+		t.Error("expected error")
+	}
+	if dur := time.Since(start); dur > time.Second {
+		t.Errorf("expected an immediate return with a cancelled context, returned after %v", dur)
+	}
+}
+
 func TestForCancel(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -290,3 +371,21 @@ func TestForCancel(t *testing.T) {
 		t.Error("expected error")
 	}
 }
+
+func TestWatchUntil(t *testing.T) {
+	pod := createPod("p-watch", t)
+	err := wait.WatchUntil(context.Background(), getResourceManager(), &v1.PodList{}, func(event watch.Event) (bool, error) {
+		p, ok := event.Object.(*v1.Pod)
+		return ok && p.Name == pod.Name && p.Status.Phase == v1.PodRunning, nil
+	}, 2*time.Minute, resources.WithFieldSelector("metadata.name="+pod.Name))
+	if err != nil {
+		t.Fatal("failed watching for the pod to be running", err)
+	}
+
+	err = wait.WatchUntil(context.Background(), getResourceManager(), &v1.PodList{}, func(event watch.Event) (bool, error) {
+		return false, nil
+	}, 5*time.Second)
+	if err == nil {
+		t.Error("expected an error when the condition is not met within the timeout")
+	}
+}
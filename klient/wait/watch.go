@@ -0,0 +1,113 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wait
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+)
+
+// watchRestartDelay is the delay before a watch closed by the server is reopened
+const watchRestartDelay = time.Second
+
+// WatchUntil watches the resources of the kind of list, filtered by the list options, and
+// evaluates cond on each event received until it returns true. It returns nil once cond is
+// satisfied, the error returned by cond, or an error once the timeout is exceeded. This is
+// more responsive than polling with For when testing event-driven controllers.
+//
+// The watch is reopened from the last resource version received when the server closes it,
+// e.g. on disconnect, and from scratch when that resource version has expired, in which case
+// the existing resources are received again as added.
+func WatchUntil(ctx context.Context, r *resources.Resources, list k8s.ObjectList, cond func(event watch.Event) (bool, error), timeout time.Duration, opts ...resources.ListOption) error {
+	client, err := cr.NewWithWatch(r.GetConfig(), cr.Options{Scheme: r.GetScheme()})
+	if err != nil {
+		return fmt.Errorf("watch: %w", err)
+	}
+	listOptions := &metav1.ListOptions{}
+	for _, fn := range opts {
+		fn(listOptions)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		w, err := client.Watch(ctx, list, &cr.ListOptions{Raw: listOptions.DeepCopy()})
+		if err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("watch: condition not met within %s: %w", timeout, ctx.Err())
+			}
+			return fmt.Errorf("watch: %w", err)
+		}
+		done, err := consumeWatch(ctx, w, cond, listOptions)
+		w.Stop()
+		if done || err != nil {
+			return err
+		}
+		// the watch was closed: reopen it after a delay unless the timeout is exceeded
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("watch: condition not met within %s: %w", timeout, ctx.Err())
+		case <-time.After(watchRestartDelay):
+		}
+	}
+}
+
+// consumeWatch evaluates cond on the events of the watch until it is satisfied, the context is
+// done or the watch is closed. The resource version of listOptions is updated with the last one
+// received so that the watch can be reopened from there.
+func consumeWatch(ctx context.Context, w watch.Interface, cond func(event watch.Event) (bool, error), listOptions *metav1.ListOptions) (bool, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return false, nil
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				return false, nil
+			}
+			switch event.Type {
+			case watch.Error:
+				err := apierrors.FromObject(event.Object)
+				if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+					listOptions.ResourceVersion = ""
+					return false, nil
+				}
+				return false, fmt.Errorf("watch: %w", err)
+			case watch.Bookmark:
+				if obj, ok := event.Object.(metav1.Object); ok {
+					listOptions.ResourceVersion = obj.GetResourceVersion()
+				}
+				continue
+			}
+			if obj, ok := event.Object.(metav1.Object); ok {
+				listOptions.ResourceVersion = obj.GetResourceVersion()
+			}
+			done, err := cond(event)
+			if done || err != nil {
+				return done, err
+			}
+		}
+	}
+}
@@ -0,0 +1,507 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package assert provides assertion helpers that can be plugged directly into
+// a feature step such as Assess. Each helper returns a features.Func that uses
+// the client of the environment configuration and reports a descriptive error
+// on the test when the assertion does not hold.
+package assert
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	apimachinerywait "k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/jsonpath"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"sigs.k8s.io/e2e-framework/klient"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+)
+
+// ResourceExists asserts that the resource with the name and namespace of obj exists
+func ResourceExists(obj k8s.Object) features.Func {
+	return func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		t.Helper()
+		if _, err := fetch(ctx, t, cfg, obj); err != nil {
+			t.Errorf("expected %s to exist: %s", identify(obj), err)
+		}
+		return ctx
+	}
+}
+
+// NotExistsOption configures the ResourceNotExists assertion
+type NotExistsOption func(*notExistsOptions)
+
+type notExistsOptions struct {
+	grace time.Duration
+}
+
+// WithGracePeriod makes ResourceNotExists and ResourceNotFound check the resource repeatedly during the grace
+// period and fail as soon as it shows up, so that a resource created late, e.g. by a
+// controller, is not missed. By default, the resource is checked once.
+func WithGracePeriod(grace time.Duration) NotExistsOption {
+	return func(o *notExistsOptions) {
+		o.grace = grace
+	}
+}
+
+// ResourceNotExists asserts that the resource with the name and namespace of obj does not exist.
+// The resource of a kind without a Go type can be checked with an *unstructured.Unstructured
+// object setting its group, version and kind.
+func ResourceNotExists(obj k8s.Object, opts ...NotExistsOption) features.Func {
+	options := &notExistsOptions{}
+	for _, fn := range opts {
+		fn(options)
+	}
+	return func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		t.Helper()
+		check := func(ctx context.Context) (bool, error) {
+			_, err := fetch(ctx, t, cfg, obj)
+			switch {
+			case err == nil:
+				return true, nil
+			case errors.IsNotFound(err):
+				return false, nil
+			default:
+				return false, err
+			}
+		}
+		var found bool
+		var err error
+		if options.grace > 0 {
+			err = wait.For(check, wait.WithContext(ctx), wait.WithTimeout(options.grace), wait.WithImmediate())
+			found = err == nil
+			if apimachinerywait.Interrupted(err) {
+				err = nil
+			}
+		} else {
+			found, err = check(ctx)
+		}
+		switch {
+		case err != nil:
+			t.Errorf("failed to check that %s does not exist: %s", identify(obj), err)
+		case found:
+			t.Errorf("expected %s not to exist", identify(obj))
+		}
+		return ctx
+	}
+}
+
+// ResourceNotFound asserts that the resource of kind gvk identified by key does not exist, e.g. to
+// verify that a controller did not create it. The context and configuration are those passed to
+// the step, see WithGracePeriod to check that the resource does not show up late.
+func ResourceNotFound(key cr.ObjectKey, gvk schema.GroupVersionKind, opts ...NotExistsOption) features.Func {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetName(key.Name)
+	obj.SetNamespace(key.Namespace)
+	return ResourceNotExists(obj, opts...)
+}
+
+// ResourceMatch asserts that the resource with the name and namespace of obj exists and that
+// matchFetcher returns true for its current state
+func ResourceMatch(obj k8s.Object, matchFetcher func(object k8s.Object) bool) features.Func {
+	return func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		t.Helper()
+		current, err := fetch(ctx, t, cfg, obj)
+		if err != nil {
+			t.Errorf("failed to get %s: %s", identify(obj), err)
+			return ctx
+		}
+		if !matchFetcher(current) {
+			t.Errorf("%s does not match the expected state", identify(obj))
+		}
+		return ctx
+	}
+}
+
+// ReplicasEqual asserts that the scalable resource with the name and namespace of obj has the
+// expected number of replicas, as reported by scaleFetcher. This can be used for deployments,
+// statefulsets or any other scalable resources.
+func ReplicasEqual(obj k8s.Object, scaleFetcher func(object k8s.Object) int32, replicas int32) features.Func {
+	return func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		t.Helper()
+		current, err := fetch(ctx, t, cfg, obj)
+		if err != nil {
+			t.Errorf("failed to get %s: %s", identify(obj), err)
+			return ctx
+		}
+		if actual := scaleFetcher(current); actual != replicas {
+			t.Errorf("expected %s to have %d replicas, got %d", identify(obj), replicas, actual)
+		}
+		return ctx
+	}
+}
+
+// JSONPath asserts that the resource of kind gvk identified by key exists and that evaluating the
+// JSONPath expression, such as "{.status.phase}", against it yields the expected value. The braces
+// of the expression can be omitted. This avoids writing a typed fetcher for every field to assert on.
+func JSONPath(key cr.ObjectKey, gvk schema.GroupVersionKind, jsonPath, expected string) features.Func {
+	return func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		t.Helper()
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(gvk)
+		obj.SetName(key.Name)
+		obj.SetNamespace(key.Namespace)
+		current, err := fetch(ctx, t, cfg, obj)
+		if err != nil {
+			t.Errorf("failed to get %s %s: %s", gvk.Kind, key, err)
+			return ctx
+		}
+		actual, err := evalJSONPath(current.(*unstructured.Unstructured).Object, jsonPath)
+		if err != nil {
+			t.Errorf("failed to evaluate %s on %s %s: %s", jsonPath, gvk.Kind, key, err)
+			return ctx
+		}
+		if actual != expected {
+			t.Errorf("expected %s of %s %s to be %q, got %q", jsonPath, gvk.Kind, key, expected, actual)
+		}
+		return ctx
+	}
+}
+
+// Reconciled asserts that the controller of the resource of kind gvk identified by key processed its
+// latest change, i.e. that its status.observedGeneration caught up with its metadata.generation, within
+// the timeout. This is a precise way to wait for a controller to act on a change before asserting on its
+// results. The assertion fails right away for a resource lacking a metadata.generation, and once the
+// timeout is exceeded for a resource whose status does not report an observedGeneration.
+func Reconciled(key cr.ObjectKey, gvk schema.GroupVersionKind, timeout time.Duration) features.Func {
+	return func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		t.Helper()
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(gvk)
+		obj.SetName(key.Name)
+		obj.SetNamespace(key.Namespace)
+		var reason string
+		err := wait.For(func(ctx context.Context) (bool, error) {
+			current, err := fetch(ctx, t, cfg, obj)
+			if err != nil {
+				reason = err.Error()
+				return false, nil
+			}
+			var reconciled bool
+			reconciled, reason, err = reconcileState(current.(*unstructured.Unstructured).Object)
+			return reconciled, err
+		}, wait.WithContext(ctx), wait.WithTimeout(timeout), wait.WithImmediate())
+		switch {
+		case err == nil:
+		case reason != "":
+			t.Errorf("%s %s is not reconciled after %s: %s", gvk.Kind, key, timeout, reason)
+		default:
+			t.Errorf("failed to check that %s %s is reconciled: %s", gvk.Kind, key, err)
+		}
+		return ctx
+	}
+}
+
+// MetricValue asserts that the Prometheus metric exposed by the pod on /metrics at the given
+// port has the expected value, the metrics being scraped through a port-forward. The value is
+// summed across the series of the metric whose labels include the given ones, e.g. to assert
+// on a counter regardless of its other labels. Counters, gauges and untyped metrics are supported.
+func MetricValue(namespace, pod string, port int, metric string, labels map[string]string, expected float64) features.Func {
+	return func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		t.Helper()
+		client, err := cfg.NewClient()
+		if err != nil {
+			t.Fatalf("failed to create client: %s", err)
+		}
+		families, err := klient.ScrapeMetrics(ctx, client.RESTConfig(), namespace, pod, port)
+		if err != nil {
+			t.Errorf("failed to scrape the metrics of pod %s/%s: %s", namespace, pod, err)
+			return ctx
+		}
+		actual, err := metricValue(families, metric, labels)
+		if err != nil {
+			t.Errorf("metric %s of pod %s/%s: %s", metric, namespace, pod, err)
+			return ctx
+		}
+		if actual != expected {
+			t.Errorf("expected metric %s%v of pod %s/%s to be %v, got %v", metric, labels, namespace, pod, expected, actual)
+		}
+		return ctx
+	}
+}
+
+// HTTPStatus asserts that an HTTP GET request of path on the Service or Ingress obj, a *corev1.Service
+// or a *networkingv1.Ingress identified by its name and namespace, responds with the expected status
+// code. The endpoint is resolved depending on how the cluster exposes the Service or Ingress, through
+// its load balancer, a node port or a port-forward (see klient.HTTPGet for the meaning of port).
+func HTTPStatus(obj k8s.Object, port int32, path string, expected int) features.Func {
+	return func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		t.Helper()
+		client, err := cfg.NewClient()
+		if err != nil {
+			t.Fatalf("failed to create client: %s", err)
+		}
+		resp, err := klient.HTTPGet(ctx, client.RESTConfig(), obj, port, path)
+		if err != nil {
+			t.Errorf("failed to get %s on %s: %s", path, identify(obj), err)
+			return ctx
+		}
+		if resp.StatusCode != expected {
+			t.Errorf("expected status %d for %s on %s, got %s", expected, path, identify(obj), resp.Status)
+		}
+		return ctx
+	}
+}
+
+// VolumeMounted asserts that the PersistentVolumeClaim named claim, in the namespace of the pod, is
+// bound and that the pod identified by key is running with a volume of the claim mounted by at least
+// one of its containers, e.g. once wait.ForPVCBound returned for a storage feature. The reasons the
+// assertion does not hold, such as the phase of the claim or of the pod, are all reported.
+func VolumeMounted(key cr.ObjectKey, claim string) features.Func {
+	return func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		t.Helper()
+		client, err := cfg.NewClient()
+		if err != nil {
+			t.Fatalf("failed to create client: %s", err)
+		}
+		var pvc corev1.PersistentVolumeClaim
+		if err := client.Resources().Get(ctx, claim, key.Namespace, &pvc); err != nil {
+			t.Errorf("failed to get persistentvolumeclaim %s/%s: %s", key.Namespace, claim, err)
+			return ctx
+		}
+		var pod corev1.Pod
+		if err := client.Resources().Get(ctx, key.Name, key.Namespace, &pod); err != nil {
+			t.Errorf("failed to get pod %s: %s", key, err)
+			return ctx
+		}
+		if problems := volumeMountProblems(&pvc, &pod); len(problems) > 0 {
+			t.Errorf("expected pod %s to be running with persistentvolumeclaim %s mounted: %s", key, claim, strings.Join(problems, ", "))
+		}
+		return ctx
+	}
+}
+
+// volumeMountProblems describes the reasons the pod is not running with a volume of the claim mounted
+func volumeMountProblems(pvc *corev1.PersistentVolumeClaim, pod *corev1.Pod) []string {
+	var problems []string
+	if pvc.Status.Phase != corev1.ClaimBound {
+		problems = append(problems, fmt.Sprintf("the claim is %s", phaseOrUnknown(string(pvc.Status.Phase))))
+	}
+	if pod.Status.Phase != corev1.PodRunning {
+		problems = append(problems, fmt.Sprintf("the pod is %s", phaseOrUnknown(string(pod.Status.Phase))))
+	}
+	var volume string
+	for _, v := range pod.Spec.Volumes {
+		if v.PersistentVolumeClaim != nil && v.PersistentVolumeClaim.ClaimName == pvc.Name {
+			volume = v.Name
+		}
+	}
+	if volume == "" {
+		return append(problems, "the pod has no volume of the claim")
+	}
+	for _, container := range pod.Spec.Containers {
+		for _, mount := range container.VolumeMounts {
+			if mount.Name == volume {
+				return problems
+			}
+		}
+	}
+	return append(problems, fmt.Sprintf("no container of the pod mounts volume %s", volume))
+}
+
+func phaseOrUnknown(phase string) string {
+	if phase == "" {
+		return "in an unknown phase"
+	}
+	return phase
+}
+
+// PodCrashOption configures the NoPodsCrashing assertion
+type PodCrashOption func(*podCrashOptions)
+
+type podCrashOptions struct {
+	maxRestarts int32
+}
+
+// WithMaxRestarts sets the number of restarts a container is allowed before NoPodsCrashing
+// reports it. By default, any restart is reported.
+func WithMaxRestarts(restarts int32) PodCrashOption {
+	return func(o *podCrashOptions) {
+		o.maxRestarts = restarts
+	}
+}
+
+// NoPodsCrashing asserts that none of the containers of the pods in namespace is in
+// CrashLoopBackOff or restarted more times than allowed (see WithMaxRestarts), reporting
+// the offending pods along with the last termination reason of their containers. When
+// namespace is empty, the namespace of the environment configuration is used. This is
+// meant as a sanity check run as the last assessment of a feature.
+func NoPodsCrashing(namespace string, opts ...PodCrashOption) features.Func {
+	options := &podCrashOptions{}
+	for _, fn := range opts {
+		fn(options)
+	}
+	return func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		t.Helper()
+		client, err := cfg.NewClient()
+		if err != nil {
+			t.Fatalf("failed to create client: %s", err)
+		}
+		ns := namespace
+		if ns == "" {
+			ns = cfg.Namespace()
+		}
+		var pods corev1.PodList
+		if err := client.Resources(ns).List(ctx, &pods); err != nil {
+			t.Errorf("failed to list the pods of namespace %s: %s", ns, err)
+			return ctx
+		}
+		if crashing := crashingContainers(pods.Items, options.maxRestarts); len(crashing) > 0 {
+			t.Errorf("expected no crashing pods in namespace %s, got:\n%s", ns, strings.Join(crashing, "\n"))
+		}
+		return ctx
+	}
+}
+
+// crashingContainers describes the containers of pods that are in CrashLoopBackOff or
+// restarted more than maxRestarts times
+func crashingContainers(pods []corev1.Pod, maxRestarts int32) []string {
+	var crashing []string
+	for _, pod := range pods {
+		statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		for _, status := range statuses {
+			backOff := status.State.Waiting != nil && status.State.Waiting.Reason == "CrashLoopBackOff"
+			if !backOff && status.RestartCount <= maxRestarts {
+				continue
+			}
+			desc := fmt.Sprintf("pod %s/%s container %s: %d restarts", pod.Namespace, pod.Name, status.Name, status.RestartCount)
+			if backOff {
+				desc += ", in CrashLoopBackOff"
+			}
+			if last := status.LastTerminationState.Terminated; last != nil {
+				desc += fmt.Sprintf(", last terminated with reason %s (exit code %d)", last.Reason, last.ExitCode)
+			}
+			crashing = append(crashing, desc)
+		}
+	}
+	return crashing
+}
+
+// metricValue sums the values of the series of the metric whose labels include the given ones
+func metricValue(families map[string]*dto.MetricFamily, metric string, labels map[string]string) (float64, error) {
+	family, ok := families[metric]
+	if !ok {
+		return 0, fmt.Errorf("not exposed")
+	}
+	var value float64
+	var matched bool
+	for _, m := range family.GetMetric() {
+		if !hasLabels(m, labels) {
+			continue
+		}
+		switch {
+		case m.GetCounter() != nil:
+			value += m.GetCounter().GetValue()
+		case m.GetGauge() != nil:
+			value += m.GetGauge().GetValue()
+		case m.GetUntyped() != nil:
+			value += m.GetUntyped().GetValue()
+		default:
+			return 0, fmt.Errorf("unsupported metric type %s", family.GetType())
+		}
+		matched = true
+	}
+	if !matched {
+		return 0, fmt.Errorf("no series with labels %v", labels)
+	}
+	return value, nil
+}
+
+func hasLabels(m *dto.Metric, labels map[string]string) bool {
+	found := 0
+	for _, pair := range m.GetLabel() {
+		if value, ok := labels[pair.GetName()]; ok {
+			if value != pair.GetValue() {
+				return false
+			}
+			found++
+		}
+	}
+	return found == len(labels)
+}
+
+// evalJSONPath evaluates the JSONPath expression against the object, the braces of the
+// expression being optional
+func evalJSONPath(obj map[string]any, expr string) (string, error) {
+	if !strings.HasPrefix(expr, "{") {
+		expr = "{" + expr + "}"
+	}
+	parser := jsonpath.New("assert")
+	if err := parser.Parse(expr); err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := parser.Execute(&buf, obj); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// reconcileState indicates if the status.observedGeneration of the object caught up with its
+// metadata.generation, and why not otherwise. An error is returned when the object has no generation.
+func reconcileState(obj map[string]any) (reconciled bool, reason string, err error) {
+	generation, found, err := unstructured.NestedInt64(obj, "metadata", "generation")
+	if err != nil || !found {
+		return false, "", fmt.Errorf("the resource has no metadata.generation, its reconciliation cannot be tracked")
+	}
+	observed, found, err := unstructured.NestedInt64(obj, "status", "observedGeneration")
+	switch {
+	case err != nil:
+		return false, fmt.Sprintf("invalid status.observedGeneration: %s", err), nil
+	case !found:
+		return false, "the status of the resource has no observedGeneration", nil
+	case observed < generation:
+		return false, fmt.Sprintf("observedGeneration %d is behind generation %d", observed, generation), nil
+	}
+	return true, "", nil
+}
+
+// fetch gets the current state of obj into a copy so that the object provided by
+// the caller is never mutated by the assertions
+func fetch(ctx context.Context, t *testing.T, cfg *envconf.Config, obj k8s.Object) (k8s.Object, error) {
+	t.Helper()
+	client, err := cfg.NewClient()
+	if err != nil {
+		t.Fatalf("failed to create client: %s", err)
+	}
+	current, ok := obj.DeepCopyObject().(k8s.Object)
+	if !ok {
+		t.Fatalf("unexpected type %T for %s", obj, identify(obj))
+	}
+	return current, client.Resources().Get(ctx, obj.GetName(), obj.GetNamespace(), current)
+}
+
+func identify(obj k8s.Object) string {
+	if obj.GetNamespace() == "" {
+		return fmt.Sprintf("%T %s", obj, obj.GetName())
+	}
+	return fmt.Sprintf("%T %s/%s", obj, obj.GetNamespace(), obj.GetName())
+}
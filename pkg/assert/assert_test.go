@@ -0,0 +1,285 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package assert
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/expfmt"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"sigs.k8s.io/e2e-framework/internal/testutil"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+)
+
+func TestResourceAssertions(t *testing.T) {
+	replicas := int32(3)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+	}
+	cfg := envconf.New().WithClient(testutil.NewFakeClient(interceptor.Funcs{}, deployment))
+	existing := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}}
+	missing := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "missing", Namespace: "default"}}
+	gvk := appsv1.SchemeGroupVersion.WithKind("Deployment")
+	scale := func(obj k8s.Object) int32 {
+		return *obj.(*appsv1.Deployment).Spec.Replicas
+	}
+
+	tests := []struct {
+		name      string
+		assertion features.Func
+		pass      bool
+	}{
+		{name: "exists", assertion: ResourceExists(existing), pass: true},
+		{name: "exists missing", assertion: ResourceExists(missing)},
+		{name: "not exists missing", assertion: ResourceNotExists(missing), pass: true},
+		{name: "not exists", assertion: ResourceNotExists(existing)},
+		{name: "not exists missing during the grace period", assertion: ResourceNotExists(missing, WithGracePeriod(50*time.Millisecond)), pass: true},
+		{name: "not exists during the grace period", assertion: ResourceNotExists(existing, WithGracePeriod(time.Second))},
+		{name: "not found missing", assertion: ResourceNotFound(cr.ObjectKey{Name: "missing", Namespace: "default"}, gvk), pass: true},
+		{name: "not found", assertion: ResourceNotFound(cr.ObjectKey{Name: "app", Namespace: "default"}, gvk)},
+		{name: "not found during the grace period", assertion: ResourceNotFound(cr.ObjectKey{Name: "app", Namespace: "default"}, gvk, WithGracePeriod(time.Second))},
+		{name: "replicas equal", assertion: ReplicasEqual(existing, scale, 3), pass: true},
+		{name: "replicas differ", assertion: ReplicasEqual(existing, scale, 2)},
+		{name: "replicas missing", assertion: ReplicasEqual(missing, scale, 3)},
+	}
+	for _, test := range tests {
+		test := test
+		// run the assertion in isolation to keep its failure from bubbling up to this test
+		outcome := testutil.RunIsolated("TestResourceAssertions", func(t *testing.T) {
+			_ = test.assertion(context.Background(), t, cfg)
+		})
+		if pass := !outcome.Failed; pass != test.pass {
+			t.Errorf("%s: expected the assertion to pass: %t, got %t", test.name, test.pass, pass)
+		}
+	}
+}
+
+func TestEvalJSONPath(t *testing.T) {
+	obj := map[string]any{
+		"status": map[string]any{
+			"phase":    "Running",
+			"replicas": int64(3),
+		},
+	}
+	tests := []struct {
+		expr     string
+		expected string
+		err      bool
+	}{
+		{expr: "{.status.phase}", expected: "Running"},
+		{expr: ".status.phase", expected: "Running"},
+		{expr: ".status.replicas", expected: "3"},
+		{expr: ".status.missing", err: true},
+		{expr: "{.status[", err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			actual, err := evalJSONPath(obj, test.expr)
+			if test.err {
+				if err == nil {
+					t.Errorf("expected an error, got %q", actual)
+				}
+				return
+			}
+			if err != nil || actual != test.expected {
+				t.Errorf("expected %q, got %q (error: %v)", test.expected, actual, err)
+			}
+		})
+	}
+}
+
+func TestReconcileState(t *testing.T) {
+	tests := []struct {
+		name       string
+		obj        map[string]any
+		reconciled bool
+		reason     string
+		err        bool
+	}{
+		{
+			name:       "caught up",
+			obj:        map[string]any{"metadata": map[string]any{"generation": int64(2)}, "status": map[string]any{"observedGeneration": int64(2)}},
+			reconciled: true,
+		},
+		{
+			name:   "behind",
+			obj:    map[string]any{"metadata": map[string]any{"generation": int64(3)}, "status": map[string]any{"observedGeneration": int64(2)}},
+			reason: "observedGeneration 2 is behind generation 3",
+		},
+		{
+			name:   "no observed generation",
+			obj:    map[string]any{"metadata": map[string]any{"generation": int64(1)}},
+			reason: "the status of the resource has no observedGeneration",
+		},
+		{
+			name: "no generation",
+			obj:  map[string]any{"metadata": map[string]any{"name": "config"}},
+			err:  true,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			reconciled, reason, err := reconcileState(test.obj)
+			if (err != nil) != test.err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if reconciled != test.reconciled || reason != test.reason {
+				t.Errorf("expected reconciled %t (%q), got %t (%q)", test.reconciled, test.reason, reconciled, reason)
+			}
+		})
+	}
+}
+
+func TestMetricValue(t *testing.T) {
+	exposition := `# TYPE reconciles_total counter
+reconciles_total{controller="foo",result="success"} 3
+reconciles_total{controller="foo",result="error"} 1
+reconciles_total{controller="bar",result="success"} 5
+# TYPE queue_depth gauge
+queue_depth 2
+`
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(strings.NewReader(exposition))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		metric   string
+		labels   map[string]string
+		expected float64
+		err      bool
+	}{
+		{metric: "reconciles_total", labels: map[string]string{"controller": "foo", "result": "success"}, expected: 3},
+		{metric: "reconciles_total", labels: map[string]string{"controller": "foo"}, expected: 4},
+		{metric: "reconciles_total", expected: 9},
+		{metric: "queue_depth", expected: 2},
+		{metric: "reconciles_total", labels: map[string]string{"controller": "baz"}, err: true},
+		{metric: "missing_total", err: true},
+	}
+	for _, test := range tests {
+		actual, err := metricValue(families, test.metric, test.labels)
+		if test.err {
+			if err == nil {
+				t.Errorf("expected an error for %s%v", test.metric, test.labels)
+			}
+			continue
+		}
+		if err != nil || actual != test.expected {
+			t.Errorf("expected %s%v to be %v, got %v (error: %v)", test.metric, test.labels, test.expected, actual, err)
+		}
+	}
+}
+
+func TestCrashingContainers(t *testing.T) {
+	pod := func(name string, restarts int32, waiting string, lastReason string) corev1.Pod {
+		status := corev1.ContainerStatus{Name: "app", RestartCount: restarts}
+		if waiting != "" {
+			status.State.Waiting = &corev1.ContainerStateWaiting{Reason: waiting}
+		}
+		if lastReason != "" {
+			status.LastTerminationState.Terminated = &corev1.ContainerStateTerminated{Reason: lastReason, ExitCode: 137}
+		}
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test-ns"},
+			Status:     corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{status}},
+		}
+	}
+	pods := []corev1.Pod{
+		pod("healthy", 0, "", ""),
+		pod("restarted", 2, "", "OOMKilled"),
+		pod("crashing", 1, "CrashLoopBackOff", "Error"),
+	}
+
+	crashing := crashingContainers(pods, 0)
+	if len(crashing) != 2 {
+		t.Fatalf("expected 2 crashing containers, got: %v", crashing)
+	}
+	if !strings.Contains(crashing[0], "test-ns/restarted") || !strings.Contains(crashing[0], "OOMKilled") {
+		t.Errorf("unexpected description of the restarted pod: %s", crashing[0])
+	}
+	if !strings.Contains(crashing[1], "CrashLoopBackOff") || !strings.Contains(crashing[1], "reason Error") {
+		t.Errorf("unexpected description of the crashing pod: %s", crashing[1])
+	}
+
+	crashing = crashingContainers(pods, 2)
+	if len(crashing) != 1 || !strings.Contains(crashing[0], "test-ns/crashing") {
+		t.Errorf("expected only the pod in CrashLoopBackOff to be reported, got: %v", crashing)
+	}
+}
+
+func TestVolumeMountProblems(t *testing.T) {
+	pvc := func(phase corev1.PersistentVolumeClaimPhase) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "data"}, Status: corev1.PersistentVolumeClaimStatus{Phase: phase}}
+	}
+	pod := func(phase corev1.PodPhase, claim, mounted string) *corev1.Pod {
+		return &corev1.Pod{
+			Spec: corev1.PodSpec{
+				Volumes: []corev1.Volume{{Name: "storage", VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claim},
+				}}},
+				Containers: []corev1.Container{{Name: "app", VolumeMounts: []corev1.VolumeMount{{Name: mounted, MountPath: "/data"}}}},
+			},
+			Status: corev1.PodStatus{Phase: phase},
+		}
+	}
+	tests := []struct {
+		name     string
+		pvc      *corev1.PersistentVolumeClaim
+		pod      *corev1.Pod
+		expected []string
+	}{
+		{name: "mounted", pvc: pvc(corev1.ClaimBound), pod: pod(corev1.PodRunning, "data", "storage")},
+		{
+			name:     "pending",
+			pvc:      pvc(corev1.ClaimPending),
+			pod:      pod(corev1.PodPending, "data", "storage"),
+			expected: []string{"the claim is Pending", "the pod is Pending"},
+		},
+		{
+			name:     "other claim",
+			pvc:      pvc(corev1.ClaimBound),
+			pod:      pod(corev1.PodRunning, "other", "storage"),
+			expected: []string{"the pod has no volume of the claim"},
+		},
+		{
+			name:     "not mounted",
+			pvc:      pvc(corev1.ClaimBound),
+			pod:      pod(corev1.PodRunning, "data", "cache"),
+			expected: []string{"no container of the pod mounts volume storage"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			problems := volumeMountProblems(test.pvc, test.pod)
+			if strings.Join(problems, ", ") != strings.Join(test.expected, ", ") {
+				t.Errorf("expected %v, got %v", test.expected, problems)
+			}
+		})
+	}
+}
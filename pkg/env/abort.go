@@ -0,0 +1,65 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// abortKey is the context key of the abort state of the feature being executed
+type abortKey struct{}
+
+// featureAbort records whether the feature was aborted with AbortFeature, and why
+type featureAbort struct {
+	mu      sync.Mutex
+	aborted bool
+	reason  string
+}
+
+// abortReason returns the reason the feature of ctx was aborted with, if it was
+func abortReason(ctx context.Context) (string, bool) {
+	abort, ok := ctx.Value(abortKey{}).(*featureAbort)
+	if !ok {
+		return "", false
+	}
+	abort.mu.Lock()
+	defer abort.mu.Unlock()
+	return abort.reason, abort.aborted
+}
+
+// AbortFeature aborts the feature whose step ctx belongs to, e.g. when an assessment detects that
+// the rest of the feature cannot meaningfully run. The step calling it carries on until it returns,
+// then the remaining assessments of the feature are skipped with the reason, and the teardown steps
+// of the feature run as usual. Aborting a feature does not fail it, unlike t.FailNow which only stops
+// the current assessment, and does not affect the other features, unlike the fail-fast mode. Only
+// the first reason is kept when the feature is aborted several times. An error is returned if ctx
+// does not belong to a feature step.
+func AbortFeature(ctx context.Context, reason string) error {
+	abort, ok := ctx.Value(abortKey{}).(*featureAbort)
+	if !ok {
+		return errors.New("abort feature: the context does not belong to a feature")
+	}
+	abort.mu.Lock()
+	defer abort.mu.Unlock()
+	if !abort.aborted {
+		abort.aborted = true
+		abort.reason = reason
+	}
+	return nil
+}
@@ -68,6 +68,10 @@ type action struct {
 
 	// testFuncs store the TestEnvFunc for before/after feature.
 	testFuncs []types.TestEnvFunc
+
+	// cleanup stores the optional EnvFunc paired with a setup action. It is
+	// only run at the end of the suite if the setup action succeeded.
+	cleanup types.EnvFunc
 }
 
 // runWithT will run the action and inject *testing.T into the callback function.
@@ -0,0 +1,70 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"context"
+	"sync"
+
+	klog "k8s.io/klog/v2"
+)
+
+// attributesContextKey is the key of the attributes of the current assessment stored in its context
+type attributesContextKey struct{}
+
+// assessmentAttributes collects the attributes recorded by an assessment with RecordAttribute
+type assessmentAttributes struct {
+	mu     sync.Mutex
+	values map[string]any
+}
+
+// RecordAttribute attaches a key/value attribute to the assessment running with the context ctx,
+// e.g. a latency or a count measured by the assessment. The attributes are reported in the summary
+// logged once the test suite completes and, when assessment events are enabled, in the event ending
+// the assessment, so that they can be collected alongside its outcome. Recording a key again
+// replaces its value.
+//
+// The attributes are scoped to the current assessment: the context passed to the next steps does
+// not carry them, and the attributes recorded outside of an assessment, e.g. by a setup or teardown
+// step of the feature, are ignored, a warning being logged.
+func RecordAttribute(ctx context.Context, key string, value any) {
+	attrs, _ := ctx.Value(attributesContextKey{}).(*assessmentAttributes)
+	if attrs == nil {
+		klog.Warningf("Ignoring attribute %q recorded outside of an assessment", key)
+		return
+	}
+	attrs.mu.Lock()
+	defer attrs.mu.Unlock()
+	if attrs.values == nil {
+		attrs.values = make(map[string]any)
+	}
+	attrs.values[key] = value
+}
+
+// snapshot returns a copy of the recorded attributes, nil if none
+func (a *assessmentAttributes) snapshot() map[string]any {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.values) == 0 {
+		return nil
+	}
+	values := make(map[string]any, len(a.values))
+	for k, v := range a.values {
+		values[k] = v
+	}
+	return values
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"runtime/debug"
+
+	"sigs.k8s.io/e2e-framework/pkg/types"
+)
+
+// WithBuildInfo sets the version and commit of the code under test, which are reported in
+// the summary logged at the end of the run so that the results can be correlated with code
+// versions, e.g. to attribute flakiness to specific commits. By default, the version of the
+// main module and the VCS revision embedded in the test binary are reported, if available.
+func (e *testEnv) WithBuildInfo(version, commit string) types.Environment {
+	e.buildVersion = version
+	e.buildCommit = commit
+	return e
+}
+
+// buildInfo returns the version and commit set with WithBuildInfo, falling back on the build
+// information embedded in the binary for the ones not set
+func (e *testEnv) buildInfo() (version, commit string) {
+	version, commit = e.buildVersion, e.buildCommit
+	if version != "" && commit != "" {
+		return version, commit
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		detectedVersion, detectedCommit := detectBuildInfo(bi)
+		if version == "" {
+			version = detectedVersion
+		}
+		if commit == "" {
+			commit = detectedCommit
+		}
+	}
+	return version, commit
+}
+
+// detectBuildInfo returns the version of the main module and the VCS revision of the build
+func detectBuildInfo(bi *debug.BuildInfo) (version, commit string) {
+	if bi.Main.Version != "(devel)" {
+		version = bi.Main.Version
+	}
+	for _, setting := range bi.Settings {
+		if setting.Key == "vcs.revision" {
+			commit = setting.Value
+		}
+	}
+	return version, commit
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+// CleanupFunc is a cleanup operation registered with AppendCleanup
+type CleanupFunc func(context.Context, *envconf.Config) error
+
+// cleanupsKey is the context key of the cleanups of the innermost scope, feature or test suite
+type cleanupsKey struct{}
+
+// cleanupList holds the cleanups registered with AppendCleanup in a scope
+type cleanupList struct {
+	mu    sync.Mutex
+	funcs []CleanupFunc
+}
+
+// drain returns the registered cleanups in reverse order of registration and empties the list
+func (l *cleanupList) drain() []CleanupFunc {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	funcs := make([]CleanupFunc, 0, len(l.funcs))
+	for i := len(l.funcs) - 1; i >= 0; i-- {
+		funcs = append(funcs, l.funcs[i])
+	}
+	l.funcs = nil
+	return funcs
+}
+
+// AppendCleanup registers a cleanup operation from a step or an environment operation, e.g. to
+// release a resource whose creation is only known at runtime. The cleanup is scoped by ctx:
+//
+//   - when ctx is the context of a feature step, the cleanup runs once the teardown steps of the
+//     feature have run, even when the feature failed
+//   - when ctx is the context of a Setup operation, or of an action outside of a feature such as
+//     BeforeEachTest, the cleanup runs with the Finish operations of the test suite, after them
+//
+// Cleanups run in the reverse order of their registration. Their errors fail the feature for
+// feature-scoped cleanups, and are logged for suite-scoped ones. An error is returned if ctx
+// belongs to neither scope, e.g. when the test suite is not launched with Run.
+func AppendCleanup(ctx context.Context, fn CleanupFunc) error {
+	cleanups, ok := ctx.Value(cleanupsKey{}).(*cleanupList)
+	if !ok {
+		return errors.New("append cleanup: the context belongs to neither a feature nor a test suite")
+	}
+	cleanups.mu.Lock()
+	defer cleanups.mu.Unlock()
+	cleanups.funcs = append(cleanups.funcs, fn)
+	return nil
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"sigs.k8s.io/e2e-framework/pkg/featuregate"
+)
+
+const (
+	// DefaultDotEnvFile is the file loaded by WithDotEnv when no path is provided
+	DefaultDotEnvFile = ".env"
+	// DotEnvFeatureGatesKey is the key of a dotenv file setting feature gates, using the
+	// same key=value pairs as the --feature-gates flag
+	DotEnvFeatureGatesKey = "E2E_FEATURE_GATES"
+)
+
+// WithDotEnv loads the KEY=VALUE pairs of a dotenv file into the environment variables of the
+// process, e.g. to keep the toggles of local test runs in a file. It is meant to be called from
+// TestMain before the environment is created from flags, so that the variables required with
+// RequireEnvVars and the feature gates set under DotEnvFeatureGatesKey can come from the file.
+// The variables already set in the process are not overridden, and the --feature-gates flag
+// overrides the feature gates of the file.
+//
+// Blank lines and lines starting with # are ignored, an optional "export " prefix is allowed and
+// values can be quoted. When path is empty, DefaultDotEnvFile is loaded if it exists: a missing
+// file is only an error when its path is provided.
+func WithDotEnv(path string) error {
+	explicit := path != ""
+	if !explicit {
+		path = DefaultDotEnvFile
+	}
+	vars, err := readDotEnv(path)
+	if err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	for _, kv := range vars {
+		if _, set := os.LookupEnv(kv[0]); set {
+			continue
+		}
+		if err := os.Setenv(kv[0], kv[1]); err != nil {
+			return fmt.Errorf("dotenv %s: %w", path, err)
+		}
+	}
+	if gates, ok := os.LookupEnv(DotEnvFeatureGatesKey); ok && gates != "" {
+		if err := featuregate.DefaultMutableFeatureGate.Set(gates); err != nil {
+			return fmt.Errorf("dotenv %s: invalid %s: %w", path, DotEnvFeatureGatesKey, err)
+		}
+	}
+	return nil
+}
+
+// readDotEnv parses the dotenv file and returns its key/value pairs in order
+func readDotEnv(path string) ([][2]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("dotenv: %w", err)
+	}
+	defer file.Close()
+
+	var vars [][2]string
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(strings.TrimPrefix(text, "export "), "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			return nil, fmt.Errorf("dotenv %s: line %d: expected KEY=VALUE", path, line)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		vars = append(vars, [2]string{key, value})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("dotenv %s: %w", path, err)
+	}
+	return vars, nil
+}
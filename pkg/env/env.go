@@ -20,15 +20,26 @@ package env
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"os"
 	"regexp"
 	"runtime/debug"
 	"sort"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/blang/semver/v4"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	klog "k8s.io/klog/v2"
 
+	"sigs.k8s.io/e2e-framework/klient"
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
 	"sigs.k8s.io/e2e-framework/pkg/featuregate"
 	"sigs.k8s.io/e2e-framework/pkg/features"
@@ -40,12 +51,43 @@ type (
 	Func        = types.EnvFunc
 	FeatureFunc = types.FeatureEnvFunc
 	TestFunc    = types.TestEnvFunc
+
+	PanicHandler = types.PanicHandler
+	SkipHandler  = types.SkipHandler
+	ActionInfo   = types.ActionInfo
+	RunStats     = types.RunStats
+
+	MessageKind      = types.MessageKind
+	MessageFormatter = types.MessageFormatter
+	LogRedactor      = types.LogRedactor
+)
+
+const (
+	// MessageSkip is the kind of the messages reporting why a feature, suite or assessment is skipped
+	MessageSkip = types.MessageSkip
+	// MessageFailure is the kind of the messages reporting a fatal failure of a test
+	MessageFailure = types.MessageFailure
 )
 
+// serialAssessmentsMu is held while a serial assessment runs, see features.FeatureBuilder.AssessSerial
+var serialAssessmentsMu sync.Mutex
+
 type testEnv struct {
-	ctx     context.Context
-	cfg     *envconf.Config
-	actions []action
+	ctx          context.Context
+	cfg          *envconf.Config
+	actions      []action
+	events       *eventStream
+	panicHandler types.PanicHandler
+	skipHandler  types.SkipHandler
+	requiredEnv  []string
+	suiteOnce    *suiteOnceSteps
+	fixtures     *fixtureRegistry
+	formatter    types.MessageFormatter
+	redactor     types.LogRedactor
+	buildVersion string
+	buildCommit  string
+	// assessmentNamespacePrefix, when set, enables the creation of a namespace per assessment
+	assessmentNamespacePrefix string
 }
 
 // New creates a test environment with no config attached.
@@ -83,10 +125,10 @@ func NewWithKubeConfig(kubeconfigfile string) types.Environment {
 }
 
 // NewInClusterConfig creates an environment using an Environment Configuration value
-// and assumes an in-cluster kubeconfig.
+// forced to use the in-cluster configuration (see envconf.Config.WithInClusterConfig).
 func NewInClusterConfig() types.Environment {
 	env := newTestEnv()
-	cfg := envconf.NewWithKubeConfig("")
+	cfg := envconf.New().WithInClusterConfig()
 	env.cfg = cfg
 	return env
 }
@@ -99,37 +141,108 @@ func NewWithContext(ctx context.Context, cfg *envconf.Config) (types.Environment
 	if cfg == nil {
 		return nil, fmt.Errorf("environment config is nil")
 	}
-	return &testEnv{ctx: ctx, cfg: cfg}, nil
+	return &testEnv{ctx: ctx, cfg: cfg, events: &eventStream{}, suiteOnce: &suiteOnceSteps{}, fixtures: &fixtureRegistry{}}, nil
+}
+
+// Extend creates an environment layered on top of base, for instance to let a
+// package add its own BeforeEachFeature or AfterEachTest operations to a shared
+// cluster setup without registering it again. The new environment inherits the
+// context, the config and a copy of the actions of base: for each kind of action,
+// the ones of base run first, followed by the ones registered on the new environment,
+// in the order they were registered. In particular, the Setup operations of base run
+// before the ones of the new environment, which receive the context they produced.
+// The actions registered on base after the call are not added to the new environment,
+// and vice versa.
+//
+// Argument base must be an environment created by this package.
+func Extend(base types.Environment) types.Environment {
+	b, ok := base.(*testEnv)
+	if !ok {
+		panic(fmt.Sprintf("cannot extend environment type %T", base))
+	}
+	return b.clone()
 }
 
 func newTestEnv() *testEnv {
 	return &testEnv{
-		ctx: context.Background(),
-		cfg: envconf.New(),
+		ctx:       context.Background(),
+		cfg:       envconf.New(),
+		events:    &eventStream{},
+		suiteOnce: &suiteOnceSteps{},
+		fixtures:  &fixtureRegistry{},
 	}
 }
 
 func newTestEnvWithParallel() *testEnv {
 	return &testEnv{
-		ctx: context.Background(),
-		cfg: envconf.New().WithParallelTestEnabled(),
+		ctx:       context.Background(),
+		cfg:       envconf.New().WithParallelTestEnabled(),
+		events:    &eventStream{},
+		suiteOnce: &suiteOnceSteps{},
+		fixtures:  &fixtureRegistry{},
 	}
 }
 
+// clone returns a copy of the environment. The copy shares the config and the event
+// stream of the environment but owns its list of actions, so that the actions registered
+// on either environment after the copy are not visible to the other one.
+func (e *testEnv) clone() *testEnv {
+	env := &testEnv{
+		ctx:          e.ctx,
+		cfg:          e.cfg,
+		events:       e.events,
+		panicHandler: e.panicHandler,
+		skipHandler:  e.skipHandler,
+		suiteOnce:    e.suiteOnce,
+		fixtures:     e.fixtures,
+		formatter:    e.formatter,
+		redactor:     e.redactor,
+		buildVersion: e.buildVersion,
+		buildCommit:  e.buildCommit,
+
+		assessmentNamespacePrefix: e.assessmentNamespacePrefix,
+	}
+	env.requiredEnv = append(env.requiredEnv, e.requiredEnv...)
+	env.actions = append(env.actions, e.actions...)
+	return env
+}
+
 // WithContext returns a new environment with the context set to ctx.
-// Argument ctx cannot be nil
+// Argument ctx cannot be nil. The new environment starts with a copy of
+// the actions of e: the actions registered on e afterwards are not added
+// to the new environment, and vice versa.
 func (e *testEnv) WithContext(ctx context.Context) types.Environment {
 	if ctx == nil {
 		panic("nil context") // this should never happen
 	}
-	env := &testEnv{
-		ctx: ctx,
-		cfg: e.cfg,
+	env := e.clone()
+	env.ctx = ctx
+	return env
+}
+
+// WithMergedActions returns a new environment, with the context and config of e, combining
+// the actions of e and other. For each kind of action, the actions of e run first, followed
+// by the actions of other, each in the order they were registered. The actions registered
+// on e or other afterwards are not added to the new environment.
+//
+// Argument other must be an environment created by this package.
+func (e *testEnv) WithMergedActions(other types.Environment) types.Environment {
+	o, ok := other.(*testEnv)
+	if !ok {
+		panic(fmt.Sprintf("cannot merge the actions of environment type %T", other))
 	}
-	env.actions = append(env.actions, e.actions...)
+	env := e.clone()
+	env.actions = append(env.actions, o.actions...)
 	return env
 }
 
+// Context returns the root context of the environment. After Run has
+// executed the Setup operations, it returns the context produced by them,
+// which every Test and TestInParallel call of the suite starts from.
+func (e *testEnv) Context() context.Context {
+	return e.ctx
+}
+
 // Setup registers environment operations that are executed once
 // prior to the environment being ready and prior to any test.
 func (e *testEnv) Setup(funcs ...Func) types.Environment {
@@ -140,6 +253,163 @@ func (e *testEnv) Setup(funcs ...Func) types.Environment {
 	return e
 }
 
+// SetupWithCleanup registers a setup operation paired with the cleanup operation undoing it.
+// Unlike the operations registered with Finish, the cleanup is only executed if its setup
+// succeeded. The cleanups are executed at the end of the test suite, after the Finish
+// operations, in the reverse order of their setups.
+func (e *testEnv) SetupWithCleanup(setup, cleanup Func) types.Environment {
+	if setup == nil {
+		return e
+	}
+	e.actions = append(e.actions, action{role: roleSetup, funcs: []types.EnvFunc{setup}, cleanup: cleanup})
+	return e
+}
+
+// SetupOnProvider registers environment operations executed like the Setup ones, in the order
+// of the registrations, only when the provider of the cluster under test, see
+// envconf.Config.ClusterProvider, matches the given one, such as "kind" or "aws". On another
+// provider, the operations are skipped and the skip is logged. The provider is usually
+// detected by a previous Setup operation, see envfuncs.DetectClusterLabels, which lets the
+// suites running on several providers share their setup code. When the provider was not
+// detected, the operations are skipped as well and a warning is logged.
+func (e *testEnv) SetupOnProvider(provider string, funcs ...Func) types.Environment {
+	if len(funcs) == 0 {
+		return e
+	}
+	return e.Setup(func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		actual := cfg.ClusterProvider()
+		if actual == "" {
+			klog.Warningf("Skipping the Setup operations of provider %q: the provider of the cluster is unknown, detect it with a previous Setup operation such as envfuncs.DetectClusterLabels", provider)
+			return ctx, nil
+		}
+		if actual != provider {
+			klog.InfoS("Skipping the Setup operations of another provider", "provider", provider, "clusterProvider", actual)
+			return ctx, nil
+		}
+		for _, fn := range funcs {
+			if fn == nil {
+				continue
+			}
+			var err error
+			if ctx, err = fn(ctx, cfg); err != nil {
+				return ctx, err
+			}
+		}
+		return ctx, nil
+	})
+}
+
+// SetupWithTimeout registers environment operations executed like the Setup ones, each of them
+// within its own timeout, so that a hung operation fails the setup with its position instead of
+// blocking until the test binary times out. See withFuncTimeout.
+func (e *testEnv) SetupWithTimeout(timeout time.Duration, funcs ...Func) types.Environment {
+	return e.Setup(withFuncTimeout("Setup", timeout, funcs)...)
+}
+
+// FinishWithTimeout registers environment operations executed like the Finish ones, each of them
+// within its own timeout, so that a hung operation fails the finish with its position instead of
+// blocking until the test binary times out. See withFuncTimeout.
+func (e *testEnv) FinishWithTimeout(timeout time.Duration, funcs ...Func) types.Environment {
+	return e.Finish(withFuncTimeout("Finish", timeout, funcs)...)
+}
+
+// withFuncTimeout wraps each func to set the timeout as the deadline of its context and to fail
+// with its position among funcs, 1-based, when the timeout is exceeded. As funcs cannot be
+// interrupted, a func that does not honor its context is not aborted, it only fails once it
+// returns. The values added to the context by a func are passed down to the next operations but
+// its deadline is not. Without a positive timeout, funcs are left unbounded.
+func withFuncTimeout(role string, timeout time.Duration, funcs []Func) []Func {
+	if timeout <= 0 {
+		return funcs
+	}
+	wrapped := make([]Func, 0, len(funcs))
+	for i, fn := range funcs {
+		if fn == nil {
+			continue
+		}
+		i, fn := i, fn
+		wrapped = append(wrapped, func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+			funcCtx, cancel := context.WithTimeoutCause(ctx, timeout, fmt.Errorf("%s func %d of %d exceeded its timeout of %s", role, i+1, len(funcs), timeout))
+			defer cancel()
+			out, err := fn(funcCtx, cfg)
+			if out == nil {
+				out = funcCtx
+			}
+			// a cancellation of the parent context is not caused by the timeout of the func
+			if funcCtx.Err() != nil && ctx.Err() == nil {
+				if err != nil {
+					return ctx, fmt.Errorf("%w: %w", context.Cause(funcCtx), err)
+				}
+				return ctx, context.Cause(funcCtx)
+			}
+			return valuesContext{Context: ctx, values: out}, err
+		})
+	}
+	return wrapped
+}
+
+// WithSkipHandler registers a handler invoked synchronously, with the name of the feature and the
+// reason of the skip, whenever a feature or one of its assessments is skipped, e.g. to report the
+// coverage gaps of a run to a dashboard. The reason starts with the source of the skip, such as
+// "regex", "label", "precondition" or "shard", followed by the skip message. As features can run
+// in parallel, the handler may be invoked concurrently.
+func (e *testEnv) WithSkipHandler(handler types.SkipHandler) types.Environment {
+	e.skipHandler = handler
+	return e
+}
+
+// notifySkip reports the skip of a feature, or of one of its assessments, to the skip handler, if any
+func (e *testEnv) notifySkip(featName, source, message string) {
+	if e.skipHandler != nil {
+		e.skipHandler(featName, fmt.Sprintf("%s: %s", source, e.redact(message)))
+	}
+}
+
+// WithPanicHandler registers a handler invoked synchronously, with the name of the feature and step,
+// when a step of a feature panics. This is meant to report panics to external systems, the panic is
+// then converted to a test failure as usual.
+func (e *testEnv) WithPanicHandler(handler types.PanicHandler) types.Environment {
+	e.panicHandler = handler
+	return e
+}
+
+// RequireEnvVars declares environment variables that must be set for the test suite to run.
+// They are checked at the start of Run, which fails with the list of the missing variables
+// before executing any Setup operation. The values of the variables are then available to
+// the operations and steps of the suite via envconf.Config.EnvVar.
+func (e *testEnv) RequireEnvVars(names ...string) types.Environment {
+	e.requiredEnv = append(e.requiredEnv, names...)
+	return e
+}
+
+// WithPerAssessmentNamespace isolates each assessment in its own namespace. Before an
+// assessment runs, a namespace with a random name starting with prefix is created and
+// set on a copy of the environment configuration, which is the configuration passed to
+// the assessment. The namespace is deleted once the assessment completes. Setup and
+// teardown steps of the feature keep using the namespace of the environment configuration.
+func (e *testEnv) WithPerAssessmentNamespace(prefix string) types.Environment {
+	e.assessmentNamespacePrefix = prefix
+	return e
+}
+
+// loadRequiredEnvVars records the values of the required environment variables in the
+// environment config and returns an error listing the variables that are not set
+func (e *testEnv) loadRequiredEnvVars() error {
+	var missing []string
+	for _, name := range e.requiredEnv {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		e.cfg.WithEnvVar(name, value)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required environment variables: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
 // BeforeEachTest registers environment funcs that are executed
 // before each Env.Test(...)
 func (e *testEnv) BeforeEachTest(funcs ...types.TestEnvFunc) types.Environment {
@@ -195,8 +465,11 @@ func (e *testEnv) processTestActions(ctx context.Context, t *testing.T, actions
 	out := ctx
 	for _, action := range actions {
 		out, err = action.runWithT(ctx, e.cfg, t)
+		if errors.Is(err, ErrSkip) {
+			e.skipf(t, "Skipping test: %s: %s", action.role, err)
+		}
 		if err != nil {
-			t.Fatalf("%s failure: %s", action.role, err)
+			e.fatalf(t, "%s failure: %s", action.role, err)
 		}
 	}
 	return out
@@ -205,19 +478,36 @@ func (e *testEnv) processTestActions(ctx context.Context, t *testing.T, actions
 // processTestFeature is used to trigger the execution of the actual feature. This function wraps the entire
 // workflow of orchestrating the feature execution be running the action configured by BeforeEachFeature /
 // AfterEachFeature.
-func (e *testEnv) processTestFeature(ctx context.Context, t *testing.T, featureName string, feature types.Feature) context.Context {
-	skipped, message := e.requireFeatureProcessing(feature)
+func (e *testEnv) processTestFeature(ctx context.Context, t *testing.T, featureName string, feature types.Feature) (out context.Context) {
+	skipped, source, message, err := e.requireFeatureSelection(featureName, feature)
+	if err != nil {
+		e.fatalf(t, "Feature %q: %s", featureName, err)
+	}
 	if skipped {
-		t.Skipf(message)
+		e.events.count(func(stats *types.RunStats) { stats.FeaturesSkipped++ })
+		e.notifySkip(featureName, source, message)
+		// when summarizing skips, the message is only surfaced at the end of the run
+		// to avoid flooding the output with identical skip lines
+		if e.cfg.SkipSummary() {
+			e.events.record(event{kind: eventFeatureSkipped, feature: featureName, message: message, metadata: featureMetadata(feature)})
+			t.SkipNow()
+		}
+		e.skipf(t, "%s", message)
 	}
+	// execute afterEachFeature actions in a deferred call so that they are run even when the
+	// feature or one of the beforeEachFeature actions aborts the test, out is set first so
+	// that they are passed the context of the feature if a beforeEachFeature action aborts
+	out = ctx
+	defer func() {
+		out = e.processFeatureActions(out, t, feature, e.getAfterFeatureActions())
+	}()
+
 	// execute beforeEachFeature actions
-	ctx = e.processFeatureActions(ctx, t, feature, e.getBeforeFeatureActions())
+	out = e.processFeatureActions(out, t, feature, e.getBeforeFeatureActions())
 
 	// execute feature test
-	ctx = e.execFeature(ctx, t, featureName, feature)
-
-	// execute afterEachFeature actions
-	return e.processFeatureActions(ctx, t, feature, e.getAfterFeatureActions())
+	out = e.execFeature(out, t, featureName, feature)
+	return out
 }
 
 // processFeatureActions is used to run a series of feature action that were configured as
@@ -227,8 +517,11 @@ func (e *testEnv) processFeatureActions(ctx context.Context, t *testing.T, featu
 	out := ctx
 	for _, action := range actions {
 		out, err = action.runWithFeature(out, e.cfg, t, deepCopyFeature(feature))
+		if errors.Is(err, ErrSkip) {
+			e.skipf(t, "Skipping test: %s: %s", action.role, err)
+		}
 		if err != nil {
-			t.Fatalf("%s failure: %s", action.role, err)
+			e.fatalf(t, "%s failure: %s", action.role, err)
 		}
 	}
 	return out
@@ -254,6 +547,22 @@ func (e *testEnv) processTests(ctx context.Context, t *testing.T, enableParallel
 	beforeTestActions := e.getBeforeTestActions()
 	afterTestActions := e.getAfterTestActions()
 
+	// a failure of the test that is not only explained by quarantined features, such as a
+	// failing test action, must still fail the test suite
+	defer func() {
+		if t.Failed() && !e.events.onlyQuarantinedFailures(t.Name()) {
+			e.events.record(event{kind: eventTestFailed, test: t.Name()})
+		}
+	}()
+
+	conflicts, unknownConflicts := featureConflicts(testFeatures)
+	if len(unknownConflicts) > 0 {
+		klog.Warningf("Features declare conflicts with features not tested along with them, check that their names are spelled right: %s", strings.Join(unknownConflicts, ", "))
+	}
+	if len(conflicts) > 0 {
+		e.fatalf(t, "Conflicting features cannot be tested together: %s", strings.Join(conflicts, ", "))
+	}
+
 	runInParallel := e.cfg.ParallelTestEnabled() && enableParallelRun
 
 	if runInParallel {
@@ -262,21 +571,39 @@ func (e *testEnv) processTests(ctx context.Context, t *testing.T, enableParallel
 
 	ctx = e.processTestActions(ctx, t, beforeTestActions)
 
+	// fixtures requested by the features, torn down once the last feature requesting them completed
+	fixtureUsers := e.reserveFixtures(testFeatures)
+	defer e.releaseFixtures(ctx, t, fixtureUsers, -1)
+
 	var wg sync.WaitGroup
-	for i, feature := range testFeatures {
+	// held for writing while an exclusive feature runs, and for reading while another feature runs
+	var exclusiveMu sync.RWMutex
+	// features setting environment variables, run serially once the parallel ones completed
+	var serial []int
+	for _, i := range e.featureOrder(testFeatures) {
+		feature := testFeatures[i]
 		featureCopy := feature
-		featName := feature.Name()
-		if featName == "" {
-			featName = fmt.Sprintf("Feature-%d", i+1)
-		}
-		if runInParallel {
+		featName := featureName(feature, i)
+		e.events.markSeen(featName)
+		if runInParallel && len(featureEnvVars(feature)) > 0 {
+			serial = append(serial, i)
+		} else if runInParallel {
 			wg.Add(1)
-			go func(ctx context.Context, w *sync.WaitGroup, featName string, f types.Feature) {
+			go func(ctx context.Context, w *sync.WaitGroup, i int, featName string, f types.Feature) {
 				defer w.Done()
+				defer e.releaseFixtures(ctx, t, fixtureUsers, i)
+				if featureExclusive(f) {
+					exclusiveMu.Lock()
+					defer exclusiveMu.Unlock()
+				} else {
+					exclusiveMu.RLock()
+					defer exclusiveMu.RUnlock()
+				}
 				_ = e.processTestFeature(ctx, t, featName, f)
-			}(ctx, &wg, featName, featureCopy)
+			}(ctx, &wg, i, featName, featureCopy)
 		} else {
 			ctx = e.processTestFeature(ctx, t, featName, featureCopy)
+			e.releaseFixtures(ctx, t, fixtureUsers, i)
 			// In case if the feature under test has failed, skip reset of the features
 			// that are part of the same test
 			if e.cfg.FailFast() && t.Failed() {
@@ -287,6 +614,10 @@ func (e *testEnv) processTests(ctx context.Context, t *testing.T, enableParallel
 	if runInParallel {
 		wg.Wait()
 	}
+	for _, i := range serial {
+		_ = e.processTestFeature(ctx, t, featureName(testFeatures[i], i), testFeatures[i])
+		e.releaseFixtures(ctx, t, fixtureUsers, i)
+	}
 	return e.processTestActions(ctx, t, afterTestActions)
 }
 
@@ -322,12 +653,107 @@ func (e *testEnv) TestInParallel(t *testing.T, testFeatures ...types.Feature) co
 // Feature tests will have access to and able to update the context
 // passed to it.
 //
+// Every call starts from the root context of the environment which,
+// when the suite is launched with Run, is the context produced by the
+// Setup operations. Updates made to the context by a call are returned
+// to the caller but are not visible to other Test calls, so values
+// stored by Setup are shared by the whole suite.
+//
 // BeforeTest and AfterTest operations are executed before and after
 // the feature is tested respectively.
 func (e *testEnv) Test(t *testing.T, testFeatures ...types.Feature) context.Context {
 	return e.processTests(e.ctx, t, false, testFeatures...)
 }
 
+// TestWithContext executes feature tests the same way Test does, starting from
+// ctx instead of the root context of the environment, e.g. to run the features
+// with a deadline or request-scoped values. The BeforeTest and AfterTest
+// operations of the call are executed with ctx as well. The values stored by
+// the Setup operations are only visible when ctx derives from Context. It
+// panics if ctx is nil.
+func (e *testEnv) TestWithContext(ctx context.Context, t *testing.T, testFeatures ...types.Feature) context.Context {
+	return e.processTests(ctx, t, false, testFeatures...)
+}
+
+// TestSuite executes the features of a suite from within a TestXXX function, the same
+// way Test does, surrounded by the setup and teardown operations of the suite.
+//
+// The suite operations are only executed if at least one feature of the suite is
+// selected to run under the current filters, otherwise the test is skipped. This
+// avoids paying the cost of the suite setup for deselected suites. The operations
+// are executed in the following order:
+//
+//	Environment Setup (once, from Run)
+//	Suite setups
+//	BeforeEachTest, then each feature surrounded by BeforeEachFeature/AfterEachFeature
+//	AfterEachTest
+//	Suite teardowns, also executed when a feature fails
+//	Environment Finish (once, from Run)
+func (e *testEnv) TestSuite(t *testing.T, suite types.Suite) context.Context {
+	ctx := e.ctx
+	testFeatures := suite.Features()
+	selected := false
+	for i, feature := range testFeatures {
+		skip, _, _, err := e.requireFeatureSelection(featureName(feature, i), feature)
+		if err != nil {
+			e.fatalf(t, "Suite %q: %s", suite.Name(), err)
+		}
+		if !skip {
+			selected = true
+			break
+		}
+	}
+	if !selected {
+		e.skipf(t, "Skipping suite %q: none of its features is selected to run", suite.Name())
+	}
+
+	defer func() {
+		if e.cfg.KeepResources() {
+			t.Logf("Skipping the teardowns of suite %q: %s is set, its resources are kept", suite.Name(), e.cfg.KeepEnv())
+			return
+		}
+		for _, teardown := range suite.Teardowns() {
+			var err error
+			if ctx, err = e.runSuiteFunc(ctx, teardown); err != nil {
+				e.errorf(t, "Suite %q teardown failure: %s", suite.Name(), err)
+			}
+		}
+	}()
+	for _, setup := range suite.Setups() {
+		var err error
+		if ctx, err = e.runSuiteFunc(ctx, setup); errors.Is(err, ErrSkip) {
+			e.skipf(t, "Skipping suite %q: %s", suite.Name(), err)
+		} else if err != nil {
+			e.fatalf(t, "Suite %q setup failure: %s", suite.Name(), err)
+		}
+	}
+
+	ctx = e.processTests(ctx, t, false, testFeatures...)
+	return ctx
+}
+
+// runSuiteFunc executes a setup or teardown operation of a suite, unless in dry-run mode
+func (e *testEnv) runSuiteFunc(ctx context.Context, fn types.EnvFunc) (context.Context, error) {
+	if e.cfg.DryRunMode() || fn == nil {
+		return ctx, nil
+	}
+	return fn(ctx, e.cfg)
+}
+
+// Actions returns a snapshot of the actions registered on the environment, in the
+// order they were registered. Changes to the snapshot do not affect the environment.
+func (e *testEnv) Actions() []ActionInfo {
+	infos := make([]ActionInfo, 0, len(e.actions))
+	for _, a := range e.actions {
+		infos = append(infos, ActionInfo{
+			Role:       a.role.String(),
+			Funcs:      len(a.funcs) + len(a.featureFuncs) + len(a.testFuncs),
+			HasCleanup: a.cleanup != nil,
+		})
+	}
+	return infos
+}
+
 // Finish registers funcs that are executed at the end of the
 // test suite.
 func (e *testEnv) Finish(funcs ...Func) types.Environment {
@@ -344,14 +770,82 @@ func (e *testEnv) Finish(funcs ...Func) types.Environment {
 // package.  This method will all Env.Setup operations prior to
 // starting the tests and run all Env.Finish operations after
 // before completing the suite.
-func (e *testEnv) Run(m *testing.M) (exitCode int) {
+//
+// The environment configuration and the environment variables
+// declared with RequireEnvVars are validated first and, if they are
+// invalid, the suite exits with a non-zero code without running
+// any Setup, test or Finish operation. When a Setup operation fails,
+// the error is logged and the remaining Setup operations are skipped,
+// but the tests are still run, see RunE to stop the suite instead.
+// A Setup operation returning ErrSkip stops the suite: the tests are
+// not run, the Finish operations are executed and the suite exits with
+// a zero code.
+//
+// When the only failures of the suite were raised by features labeled
+// as quarantined (see features.QuarantineLabelKey), the suite exits with
+// a zero code: the quarantined features still report their failures as
+// any other feature does, and the summary counts them separately. Note
+// that the failures of the tests that do not run any feature through the
+// environment cannot be tracked, a suite mixing them with quarantined
+// features should not rely on its exit code.
+func (e *testEnv) Run(m *testing.M) int {
+	exitCode, _ := e.RunWithStats(m)
+	return exitCode
+}
+
+// RunE launches the test suite like Run but returns the error that prevented the tests
+// from running, such as a failed Setup operation, instead of logging it. This lets the
+// callers embedding the environment into a larger harness decide how to handle it.
+// Unlike Run, the tests are not run when a Setup operation fails, the suite exiting with
+// a non-zero code. The Finish operations are executed even when a Setup operation fails.
+func (e *testEnv) RunE(m *testing.M) (int, error) {
+	exitCode, _, err := e.run(m.Run, true)
+	return exitCode, err
+}
+
+// RunWithStats launches the test suite like Run and also returns the statistics of the
+// features and assessments of the run. This enables test suites to check themselves from
+// their TestMain function, e.g. to guard against features being filtered out by mistake.
+func (e *testEnv) RunWithStats(m *testing.M) (int, RunStats) {
+	exitCode, stats, err := e.run(m.Run, false)
+	if err != nil {
+		klog.Error(e.redact(err.Error()))
+	}
+	return exitCode, stats
+}
+
+// run launches the test suite, whose tests are run by runTests, and returns its exit code,
+// the statistics of the run and the error that prevented the tests from running, if any.
+// A failed setup prevents the tests from running when failFast is set, otherwise it is
+// logged and only the remaining setups are skipped.
+func (e *testEnv) run(runTests func() int, failFast bool) (exitCode int, stats RunStats, err error) {
 	e.panicOnMissingContext()
-	ctx := e.ctx
+	// cleanups registered with AppendCleanup by the setups and the actions outside of features
+	envCleanups := &cleanupList{}
+	ctx := context.WithValue(e.ctx, cleanupsKey{}, envCleanups)
 
-	setups := e.getSetupActions()
-	// fail fast on setup, upon err exit
-	var err error
+	// fail fast on a misconfigured environment, before any setup is executed
+	if err := e.cfg.Validate(); err != nil {
+		return 1, stats, fmt.Errorf("invalid environment configuration: %w", err)
+	}
+	if err := e.loadRequiredEnvVars(); err != nil {
+		return 1, stats, err
+	}
 
+	setups := e.getSetupActions()
+	if e.cfg.SkipSetup() {
+		// the features then start from the root context of the environment
+		klog.Warning("Skipping the Setup operations of the test suite as requested, features may fail if their prerequisites are not met")
+		setups = nil
+	}
+	// cleanups of the setups that succeeded, in the order of the setups
+	var cleanups []action
+	// set when a setup failed, the suite then fails whatever the features that failed
+	setupFailed := false
+
+	// written last to include the objects created by the finish operations
+	defer e.writeResourceManifest()
+	defer e.writeGVKCoverageReport()
 	defer func() {
 		// Recover and see if the panic handler is disabled. If it is disabled, panic and stop the workflow.
 		// Otherwise, log and continue with running the Finish steps of the Test suite
@@ -360,35 +854,157 @@ func (e *testEnv) Run(m *testing.M) (exitCode int) {
 			if e.cfg.DisableGracefulTeardown() {
 				panic(rErr)
 			}
-			klog.Errorf("Recovering from panic and running finish actions: %s, stack: %s", rErr, string(debug.Stack()))
+			klog.Error(e.redact(fmt.Sprintf("Recovering from panic and running finish actions: %s, stack: %s", rErr, string(debug.Stack()))))
 			// Set this exit code value to non 0 to indicate that the test suite has failed
 			// Not doing this will mark the test suite as passed even though there was a panic
 			exitCode = 1
+			err = fmt.Errorf("recovered from panic: %v", rErr)
 		}
+		stats = e.events.runStats()
 
+		if e.cfg.SkipFinish() {
+			klog.Warning("Skipping the Finish operations and the cleanups of the test suite as requested, the resources they release are left behind")
+			e.ctx = ctx
+			return
+		}
+		if e.cfg.KeepResources() {
+			e.logKeptResources()
+			e.ctx = ctx
+			return
+		}
 		finishes := e.getFinishActions()
 		// attempt to gracefully clean up.
 		// Upon error, log and continue.
 		for _, fin := range finishes {
 			// context passed down to each finish step
-			if ctx, err = fin.run(ctx, e.cfg); err != nil {
-				klog.V(2).ErrorS(err, "Cleanup failed", "action", fin.role)
+			var finErr error
+			if ctx, finErr = fin.run(ctx, e.cfg); finErr != nil {
+				klog.V(2).ErrorS(e.redactError(finErr), "Cleanup failed", "action", fin.role)
 			}
 		}
+		for _, cleanup := range envCleanups.drain() {
+			if cleanupErr := cleanup(ctx, e.cfg); cleanupErr != nil {
+				klog.V(2).ErrorS(e.redactError(cleanupErr), "Cleanup failed", "action", "AppendCleanup")
+			}
+		}
+		ctx = e.runCleanups(ctx, cleanups)
 		e.ctx = ctx
 	}()
 
 	for _, setup := range setups {
 		// context passed down to each setup
-		if ctx, err = setup.run(ctx, e.cfg); err != nil {
-			klog.Errorf("%s failure: %s", setup.role, err)
+		var setupErr error
+		if ctx, setupErr = setup.run(ctx, e.cfg); errors.Is(setupErr, ErrSkip) {
+			// the suite is not applicable: the tests are not run but the finish actions still are
+			klog.Warning(e.redact(fmt.Sprintf("Skipping the test suite: %s: %s", setup.role, setupErr)))
+			return 0, stats, nil
+		} else if setupErr != nil && failFast {
+			// fail fast on setup: the tests are not run but the finish actions still are
+			return 1, stats, fmt.Errorf("%s failure: %w", setup.role, setupErr)
+		} else if setupErr != nil {
+			klog.Error(e.redact(fmt.Sprintf("%s failure: %s", setup.role, setupErr)))
+			setupFailed = true
 			break
 		}
+		if setup.cleanup != nil {
+			cleanups = append(cleanups, action{role: roleFinish, funcs: []types.EnvFunc{setup.cleanup}})
+		}
 	}
+	// the context produced by the setups becomes the root context of every
+	// test executed as part of the suite
 	e.ctx = ctx
 
 	// Execute the test suite
-	return m.Run()
+	exitCode = runTests()
+	e.events.printSummary(e.redact)
+	if version, commit := e.buildInfo(); version != "" || commit != "" {
+		klog.InfoS("Test suite build info", "version", version, "commit", commit)
+	}
+	if unknown := e.events.unseen(e.cfg.FeatureList()); len(unknown) > 0 {
+		klog.Warningf("Features listed in the features file were not found in the test suite: %s", strings.Join(unknown, ", "))
+	}
+	if manifest := e.cfg.FailuresManifest(); manifest != "" {
+		if err := envconf.WriteFailuresManifest(manifest, e.events.failedFeatures(e.redact)); err != nil {
+			klog.ErrorS(e.redactError(err), "Failed to write the failures manifest", "path", manifest)
+		}
+	}
+	e.writeTimingMetrics()
+	if quarantined, other := e.events.failures(); exitCode != 0 && !setupFailed && quarantined > 0 && other == 0 {
+		klog.Warning("Test suite failures were caused by quarantined features only, ignoring them")
+		exitCode = 0
+	}
+	return exitCode, stats, nil
+}
+
+// writeResourceManifest writes the objects created during the run to the resource manifest, if any
+func (e *testEnv) writeResourceManifest() {
+	manifest := e.cfg.ResourceManifest()
+	if manifest == "" {
+		return
+	}
+	if err := envconf.WriteResourceManifest(manifest, e.cfg.CreatedResources()); err != nil {
+		klog.ErrorS(e.redactError(err), "Failed to write the resource manifest", "path", manifest)
+	}
+}
+
+// writeGVKCoverageReport writes the operations performed per kind during the run to the coverage report, if any
+func (e *testEnv) writeGVKCoverageReport() {
+	report := e.cfg.GVKCoverageReport()
+	if report == "" {
+		return
+	}
+	if err := envconf.WriteGVKCoverageReport(report, e.cfg.GVKCoverage()); err != nil {
+		klog.ErrorS(e.redactError(err), "Failed to write the GVK coverage report", "path", report)
+	}
+}
+
+// runCleanups executes the cleanups of the setups that succeeded in reverse order.
+// Upon error, log and continue.
+func (e *testEnv) runCleanups(ctx context.Context, cleanups []action) context.Context {
+	for i := len(cleanups) - 1; i >= 0; i-- {
+		var err error
+		if ctx, err = cleanups[i].run(ctx, e.cfg); err != nil {
+			klog.V(2).ErrorS(e.redactError(err), "Cleanup failed", "action", "SetupWithCleanup")
+		}
+	}
+	return ctx
+}
+
+// featureOrder returns the indexes of the features in the order they must be processed.
+// The features follow the order of the features file, if any, the unlisted ones, which
+// are skipped, being processed last in their original order.
+func (e *testEnv) featureOrder(testFeatures []types.Feature) []int {
+	order := make([]int, len(testFeatures))
+	for i := range order {
+		order[i] = i
+	}
+	list := e.cfg.FeatureList()
+	if list == nil {
+		return order
+	}
+	positions := make(map[string]int, len(list))
+	for i := len(list) - 1; i >= 0; i-- {
+		positions[list[i]] = i
+	}
+	position := func(i int) int {
+		if pos, ok := positions[featureName(testFeatures[i], i)]; ok {
+			return pos
+		}
+		return len(list)
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return position(order[a]) < position(order[b])
+	})
+	return order
+}
+
+// featureName returns the name of the feature at index i of a list of features,
+// generating one if the feature is unnamed
+func featureName(f types.Feature, i int) string {
+	if f.Name() == "" {
+		return fmt.Sprintf("Feature-%d", i+1)
+	}
+	return f.Name()
 }
 
 func (e *testEnv) getActionsByRole(r actionRole) []action {
@@ -436,89 +1052,602 @@ func (e *testEnv) getFinishActions() []action {
 	return finishAction
 }
 
-func (e *testEnv) executeSteps(ctx context.Context, t *testing.T, steps []types.Step) context.Context {
+func (e *testEnv) executeSteps(ctx context.Context, t *testing.T, cfg *envconf.Config, steps []types.Step) context.Context {
 	if e.cfg.DryRunMode() {
 		return ctx
 	}
 	for _, setup := range steps {
-		ctx = setup.Func()(ctx, t, e.cfg)
+		ctx = setup.Func()(ctx, t, cfg)
+	}
+	return ctx
+}
+
+// executeSuiteOnceStep executes the step unless a step with the same key was executed
+// successfully by a previous feature of the test suite
+func (e *testEnv) executeSuiteOnceStep(ctx context.Context, t *testing.T, step types.SuiteOnceStep) context.Context {
+	once := e.suiteOnce.step(step.OnceKey())
+	once.mu.Lock()
+	defer once.mu.Unlock()
+	if once.done {
+		klog.V(4).InfoS("Skipping step already executed by the test suite", "step", step.Name(), "key", step.OnceKey())
+		return ctx
 	}
+	ctx = e.executeSteps(ctx, t, e.cfg, []types.Step{step})
+	once.done = !t.Failed()
 	return ctx
 }
 
 func (e *testEnv) execFeature(ctx context.Context, t *testing.T, featName string, f types.Feature) context.Context {
+	e.events.count(func(stats *types.RunStats) { stats.FeaturesRun++ })
+	// values memoized with Once are scoped to this execution of the feature
+	ctx = context.WithValue(ctx, onceStoreKey{}, &onceStore{})
+	// cleanups registered with AppendCleanup by the steps of the feature
+	cleanups := &cleanupList{}
+	ctx = context.WithValue(ctx, cleanupsKey{}, cleanups)
+	// the remaining assessments are skipped once the feature is aborted with AbortFeature
+	ctx = context.WithValue(ctx, abortKey{}, &featureAbort{})
+	if e.redactor != nil {
+		ctx = context.WithValue(ctx, logRedactorKey{}, e.redactor)
+	}
 	// feature-level subtest
-	t.Run(featName, func(newT *testing.T) {
+	run := func(newT *testing.T) {
+		if e.cfg.TimingMetrics() != "" {
+			// deferred first to include the teardown steps and cleanups in the duration of the feature
+			defer e.recordTiming(newT, featName, "", time.Now())
+		}
+		// name of the feature-level step being executed, reported to the panic handler
+		var stepName string
+		defer e.recoverStepPanic(newT, featName, &stepName)
+		// the teardown steps and cleanups are skipped when the resources are kept
+		keep := e.cfg.KeepResources()
+		if keep {
+			newT.Logf("Skipping the teardown steps and cleanups of feature %q: %s is set, its resources are kept", featName, e.cfg.KeepEnv())
+		}
+		// deferred before the cleanups so that the variables are restored once they have run
+		restoreEnvVars, err := setFeatureEnvVars(f)
+		if err != nil {
+			e.fatalf(newT, "Feature %q environment variables: %s", featName, err)
+		}
+		defer restoreEnvVars()
+		// client tracking the objects created by the feature, when its cleanup is verified
+		var tracker *klient.TrackingClient
+		// deferred before the cleanups so that the objects they delete are not reported
+		defer func() {
+			if tracker != nil {
+				e.verifyCleanup(context.WithoutCancel(ctx), newT, featName, tracker)
+			}
+		}()
+		// deferred after the recovery so that the cleanups run, and a panicking cleanup is recovered, once a step panicked
+		defer func() {
+			if keep {
+				return
+			}
+			for _, cleanup := range cleanups.drain() {
+				if err := cleanup(context.WithoutCancel(ctx), e.cfg); err != nil {
+					e.errorf(newT, "Feature %q cleanup failure: %s", featName, err)
+				}
+			}
+		}()
+
 		if fDescription, ok := f.(types.DescribableFeature); ok && fDescription.Description() != "" {
 			t.Logf("Processing Feature: %s", fDescription.Description())
 		}
 
-		// setups run at feature-level
-		setups := features.GetStepsByLevel(f.Steps(), types.LevelSetup)
-		ctx = e.executeSteps(ctx, newT, setups)
+		e.checkPreconditions(ctx, newT, featName, f)
+		ctx = e.acquireFixtures(ctx, newT, featName, f)
 
-		// assessments run as feature/assessment sub level
-		assessments := features.GetStepsByLevel(f.Steps(), types.LevelAssess)
-
-		failed := false
-		for i, assess := range assessments {
-			assessName := assess.Name()
-			if dAssess, ok := assess.(types.DescribableStep); ok && dAssess.Description() != "" {
-				t.Logf("Processing Assessment: %s", dAssess.Description())
+		// configuration passed to the steps of the feature
+		cfg := e.cfg
+		if cf, ok := f.(types.CleanupVerifiedFeature); ok && cf.CleanupVerification() && !e.cfg.DryRunMode() && !keep {
+			client, err := e.cfg.NewClient()
+			if err != nil {
+				e.fatalf(newT, "Feature %q cleanup verification: %s", featName, err)
 			}
-			if assessName == "" {
-				assessName = fmt.Sprintf("Assessment-%d", i+1)
+			tracker = klient.NewTrackingClient(client)
+			cfg = e.cfg.Clone().WithClient(tracker)
+		}
+
+		defer profileFeature(featName, f)()
+
+		// deadline is done once the timeout of the feature, if any, is exceeded
+		deadline := context.Background()
+		if tf, ok := f.(types.TimeBoundFeature); ok && tf.Timeout() > 0 {
+			var cancel context.CancelFunc
+			deadline, cancel = context.WithTimeoutCause(ctx, tf.Timeout(), fmt.Errorf("feature %q exceeded its timeout of %s", featName, tf.Timeout()))
+			defer cancel()
+			ctx = deadline
+		}
+
+		// steps run level by level, in the order of the level weights
+		for _, level := range types.Levels() {
+			if level == types.LevelTeardown && keep {
+				continue
 			}
-			// shouldFailNow catches whether t.FailNow() is called in the assessment.
-			// If it is, we won't proceed with the next assessment.
-			var shouldFailNow bool
-			newT.Run(assessName, func(internalT *testing.T) {
-				skipped, message := e.requireAssessmentProcessing(assess, i+1)
-				if skipped {
-					internalT.Skipf(message)
+			steps := runLastStepsLast(features.GetStepsByLevel(f.Steps(), level))
+			if level != types.LevelAssess {
+				// steps other than assessments run at feature-level
+				for _, step := range steps {
+					stepName = step.Name()
+					if once, ok := step.(types.SuiteOnceStep); ok && once.OnceKey() != "" {
+						ctx = e.executeSuiteOnceStep(ctx, newT, once)
+						continue
+					}
+					ctx = e.executeSteps(ctx, newT, cfg, []types.Step{step})
 				}
-				// Set shouldFailNow to true before actually running the assessment, because if the assessment
-				// calls t.FailNow(), the function will be abruptly stopped in the middle of `e.executeSteps()`.
-				shouldFailNow = true
-				ctx = e.executeSteps(ctx, internalT, []types.Step{assess})
-				// If we reach this point, it means the assessment did not call t.FailNow().
-				shouldFailNow = false
-			})
-			// Check if the Test assessment under question performed either 2 things:
-			// - a t.FailNow() invocation
-			// - a `t.Fail()` or `t.Failed()` invocation
-			// In one of those cases, we need to track that and stop the next set of assessment in the feature
-			// under test from getting executed.
-			if shouldFailNow || (e.cfg.FailFast() && newT.Failed()) {
-				failed = true
+				continue
+			}
+
+			var failed bool
+			pf, parallel := f.(types.ParallelAssessmentsFeature)
+			ctx, failed = e.execAssessments(ctx, deadline, newT, cfg, featName, steps, parallel && pf.ParallelAssessments())
+			if deadline.Done() != nil {
+				// the steps following the assessments still run once the feature timed out
+				ctx = context.WithoutCancel(ctx)
+			}
+
+			// Let us fail the test fast and not run the remaining steps in case if the framework specific fail-fast
+			// mode is invoked to make sure we leave the traces of the failed test behind to enable better debugging
+			// for the test developers
+			if e.cfg.FailFast() && failed {
+				newT.FailNow()
+			}
+		}
+	}
+	if passed := t.Run(featName, run); !passed {
+		e.events.count(func(stats *types.RunStats) { stats.FeaturesFailed++ })
+		quarantined := features.IsQuarantined(f)
+		e.events.record(event{kind: eventFeatureFailed, test: t.Name(), feature: featName, quarantined: quarantined, metadata: featureMetadata(f)})
+		if quarantined {
+			klog.Warningf("Quarantined feature %q failed, its failure does not fail the test suite", featName)
+		}
+	}
+	if len(featureFixtures(f)) > 0 {
+		// the fixtures are scoped to the feature requesting them
+		ctx = context.WithValue(ctx, fixturesKey{}, map[string]any(nil))
+	}
+
+	return ctx
+}
+
+// verifyCleanup fails the feature if one of the objects created through the tracking client of
+// the feature still exists and is not being deleted
+func (e *testEnv) verifyCleanup(ctx context.Context, t *testing.T, featName string, tracker *klient.TrackingClient) {
+	client, err := e.cfg.NewClient()
+	if err != nil {
+		e.errorf(t, "Feature %q cleanup verification: %s", featName, err)
+		return
+	}
+	var leftovers []string
+	verified := make(map[klient.CreatedObject]bool)
+	for _, created := range tracker.Created() {
+		if verified[created] {
+			continue
+		}
+		verified[created] = true
+		name := created.Name
+		if created.Namespace != "" {
+			name = created.Namespace + "/" + name
+		}
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(created.GVK)
+		err := client.Resources().Get(ctx, created.Name, created.Namespace, obj)
+		switch {
+		case apierrors.IsNotFound(err):
+		case err != nil:
+			e.errorf(t, "Feature %q cleanup verification: failed to get %s %s: %s", featName, created.GVK.Kind, name, err)
+		case obj.GetDeletionTimestamp() == nil:
+			leftovers = append(leftovers, fmt.Sprintf("%s %s", created.GVK.Kind, name))
+		}
+	}
+	if len(leftovers) > 0 {
+		e.errorf(t, "Feature %q did not delete the objects it created: %s", featName, strings.Join(leftovers, ", "))
+	}
+}
+
+// checkPreconditions checks the preconditions of the feature, if any, in order. It skips
+// the test when a precondition is not met and fails it when a check returns an error.
+// The preconditions are not checked in dry-run mode, as they may need the cluster.
+func (e *testEnv) checkPreconditions(ctx context.Context, t *testing.T, featName string, f types.Feature) {
+	pf, ok := f.(types.PreconditionedFeature)
+	if !ok || e.cfg.DryRunMode() {
+		return
+	}
+	for _, precondition := range pf.Preconditions() {
+		met, err := precondition.Check(ctx, e.cfg)
+		if err != nil {
+			e.fatalf(t, "Precondition %q of feature %q cannot be checked: %s", precondition.Name, f.Name(), err)
+		}
+		if !met {
+			message := fmt.Sprintf("Skipping feature %q: precondition %q is not met", f.Name(), precondition.Name)
+			e.notifySkip(featName, skipSourcePrecondition, message)
+			e.skipf(t, "%s", message)
+		}
+	}
+}
+
+// featureConflicts returns the pairs of features conflicting with each other among the features,
+// formatted as "a <-> b" in the order of the features, and the conflicts declared with features
+// not among them, formatted as "a -> b", which may be caused by a misspelled feature name
+func featureConflicts(testFeatures []types.Feature) (conflicts, unknown []string) {
+	index := make(map[string]int, len(testFeatures))
+	for i, f := range testFeatures {
+		index[f.Name()] = i
+	}
+	reported := make(map[[2]int]bool)
+	for i, f := range testFeatures {
+		cf, ok := f.(types.ConflictingFeature)
+		if !ok {
+			continue
+		}
+		for _, name := range cf.ConflictsWith() {
+			j, found := index[name]
+			if !found {
+				unknown = append(unknown, fmt.Sprintf("%q -> %q", f.Name(), name))
+				continue
+			}
+			if j == i {
+				continue
+			}
+			pair := [2]int{min(i, j), max(i, j)}
+			if reported[pair] {
+				continue
+			}
+			reported[pair] = true
+			conflicts = append(conflicts, fmt.Sprintf("%q <-> %q", testFeatures[pair[0]].Name(), testFeatures[pair[1]].Name()))
+		}
+	}
+	return conflicts, unknown
+}
+
+// featureExclusive indicates if the feature must run in isolation, see features.FeatureBuilder.WithExclusive
+func featureExclusive(f types.Feature) bool {
+	xf, ok := f.(types.ExclusiveFeature)
+	return ok && xf.Exclusive()
+}
+
+// featureMetadata returns the metadata of the feature, if any
+func featureMetadata(f types.Feature) map[string]any {
+	if mf, ok := f.(types.MetadataFeature); ok {
+		return mf.Metadata()
+	}
+	return nil
+}
+
+// runLastStepsLast moves the steps required to run last at the end of the list, preserving
+// the declaration order of the steps otherwise
+func runLastStepsLast(steps []types.Step) []types.Step {
+	var first, last []types.Step
+	for _, step := range steps {
+		if ordered, ok := step.(types.OrderedStep); ok && ordered.RunLast() {
+			last = append(last, step)
+			continue
+		}
+		first = append(first, step)
+	}
+	return append(first, last...)
+}
+
+// execAssessments runs the assessments of a feature as subtests of the feature. It returns true
+// if an assessment failed, or the deadline of the feature was exceeded, and the next assessments
+// were not run as a consequence. When parallel is true, the assessments run concurrently, except
+// the ones required to run last (see execParallelAssessments).
+func (e *testEnv) execAssessments(ctx, deadline context.Context, featT *testing.T, cfg *envconf.Config, featName string, assessments []types.Step, parallel bool) (context.Context, bool) {
+	failed := false
+	start := 0
+	if parallel {
+		// the assessments required to run last are at the end of the list and still run sequentially
+		for start < len(assessments) {
+			if ordered, ok := assessments[start].(types.OrderedStep); ok && ordered.RunLast() {
 				break
 			}
+			start++
 		}
+		failed = e.execParallelAssessments(ctx, deadline, featT, cfg, featName, assessments[:start])
+	}
+	for i := start; i < len(assessments) && !failed; i++ {
+		assess := assessments[i]
+		assessName := assessmentName(featT, assess, i)
+		if deadline.Err() != nil {
+			e.errorf(featT, "%s before assessment %q, the remaining assessments are not run", context.Cause(deadline), assessName)
+			failed = true
+			break
+		}
+		var shouldFailNow bool
+		ctx, shouldFailNow = e.runAssessment(ctx, featT, cfg, featName, assessName, assess, i+1)
+		// Check if the Test assessment under question performed either 2 things:
+		// - a t.FailNow() invocation
+		// - a `t.Fail()` or `t.Failed()` invocation
+		// In one of those cases, we need to track that and stop the next set of assessment in the feature
+		// under test from getting executed.
+		if shouldFailNow || (e.cfg.FailFast() && featT.Failed()) {
+			failed = true
+			break
+		}
+		if deadline.Err() != nil {
+			e.errorf(featT, "%s during assessment %q, the remaining assessments are not run", context.Cause(deadline), assessName)
+			failed = true
+			break
+		}
+	}
+	return ctx, failed
+}
 
-		// Let us fail the test fast and not run the teardown in case if the framework specific fail-fast mode is
-		// invoked to make sure we leave the traces of the failed test behind to enable better debugging for the
-		// test developers
-		if e.cfg.FailFast() && failed {
-			newT.FailNow()
+// execParallelAssessments runs the assessments of a feature concurrently, as subtests of the feature
+// started from their own goroutine, and waits for all of them to complete. Each assessment starts
+// from the context of the feature and the contexts they return are discarded, so that the values
+// added by an assessment are not visible to the other ones nor to the following steps. It returns
+// true if an assessment called t.FailNow, or failed in fail-fast mode, or if the deadline of the
+// feature was exceeded.
+func (e *testEnv) execParallelAssessments(ctx, deadline context.Context, featT *testing.T, cfg *envconf.Config, featName string, assessments []types.Step) bool {
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed bool
+	)
+	for i, assess := range assessments {
+		assessName := assessmentName(featT, assess, i)
+		wg.Add(1)
+		go func(index int, assess types.Step, assessName string) {
+			defer wg.Done()
+			if _, shouldFailNow := e.runAssessment(ctx, featT, cfg, featName, assessName, assess, index); shouldFailNow {
+				mu.Lock()
+				failed = true
+				mu.Unlock()
+			}
+		}(i+1, assess, assessName)
+	}
+	wg.Wait()
+	if deadline.Err() != nil {
+		e.errorf(featT, "%s during the parallel assessments, the remaining assessments are not run", context.Cause(deadline))
+		return true
+	}
+	return failed || (e.cfg.FailFast() && featT.Failed())
+}
+
+// assessmentName returns the name of the assessment at index i of a feature, generating one if the
+// assessment is unnamed, and logs its description, if any
+func assessmentName(featT *testing.T, assess types.Step, i int) string {
+	if dAssess, ok := assess.(types.DescribableStep); ok && dAssess.Description() != "" {
+		featT.Logf("Processing Assessment: %s", dAssess.Description())
+	}
+	if assess.Name() == "" {
+		return fmt.Sprintf("Assessment-%d", i+1)
+	}
+	return assess.Name()
+}
+
+// runAssessment runs an assessment as a subtest of the feature, index being its position, from 1, among
+// the assessments of the feature. It returns the context produced by the assessment and true if the
+// assessment called t.FailNow().
+func (e *testEnv) runAssessment(ctx context.Context, featT *testing.T, cfg *envconf.Config, featName, assessName string, assess types.Step, index int) (context.Context, bool) {
+	// shouldFailNow catches whether t.FailNow() is called in the assessment.
+	// If it is, we won't proceed with the next assessment.
+	var shouldFailNow bool
+	run := func(internalT *testing.T) {
+		// deferred first to count the outcome of the assessment once a panic has been recovered
+		defer e.events.countAssessment(internalT)
+		if e.cfg.TimingMetrics() != "" {
+			defer e.recordTiming(internalT, featName, assessName, time.Now())
 		}
+		attrs := &assessmentAttributes{}
+		defer func() {
+			if values := attrs.snapshot(); values != nil {
+				e.events.record(event{kind: eventAssessmentAttributes, test: featT.Name(), feature: featName, assessment: assessName, metadata: values})
+			}
+		}()
+		if e.cfg.AssessmentEventsEnabled() {
+			start := time.Now()
+			logAssessmentEvent(internalT, assessmentEvent{Action: "start", Feature: featName, Assessment: assessName})
+			defer func() {
+				logAssessmentEvent(internalT, assessmentEvent{Action: assessmentResult(internalT), Feature: featName, Assessment: assessName, Elapsed: time.Since(start).Seconds(), Attributes: attrs.snapshot()})
+			}()
+		}
+		defer e.recoverStepPanic(internalT, featName, &assessName)
 
-		// teardowns run at feature-level
-		teardowns := features.GetStepsByLevel(f.Steps(), types.LevelTeardown)
-		ctx = e.executeSteps(ctx, newT, teardowns)
+		skipped, source, message := e.requireAssessmentProcessing(assess, index)
+		if skipped {
+			e.notifySkip(featName, source, message)
+			e.skipf(internalT, "%s", message)
+		}
+		if reason, aborted := abortReason(ctx); aborted {
+			message := fmt.Sprintf("Skipping assessment %q: feature %q was aborted: %s", assessName, featName, reason)
+			e.notifySkip(featName, skipSourceAbort, message)
+			e.skipf(internalT, "%s", message)
+		}
+		// a serial assessment does not run concurrently with any other serial assessment
+		if serial, ok := assess.(types.SerialStep); ok && serial.Serial() {
+			serialAssessmentsMu.Lock()
+			defer serialAssessmentsMu.Unlock()
+		}
+		stepCfg := cfg
+		if e.assessmentNamespacePrefix != "" && !e.cfg.DryRunMode() {
+			stepCfg = e.withAssessmentNamespace(ctx, internalT, cfg)
+		}
+		// Set shouldFailNow to true before actually running the assessment, because if the assessment
+		// calls t.FailNow(), the function will be abruptly stopped in the middle of `e.executeSteps()`.
+		shouldFailNow = true
+		assessCtx := context.WithValue(ctx, attributesContextKey{}, attrs)
+		if timeout := e.assessmentTimeout(assess); timeout > 0 {
+			assessCtx = e.executeStepWithTimeout(assessCtx, internalT, stepCfg, assess, assessName, timeout)
+		} else {
+			assessCtx = e.executeSteps(assessCtx, internalT, stepCfg, []types.Step{assess})
+		}
+		// the attributes are scoped to the assessment
+		ctx = context.WithValue(assessCtx, attributesContextKey{}, (*assessmentAttributes)(nil))
+		// If we reach this point, it means the assessment did not call t.FailNow().
+		shouldFailNow = false
+	}
+	featT.Run(assessName, run)
+	return ctx, shouldFailNow
+}
+
+// assessmentTimeout returns the timeout of the assessment, if any, or the default one of the config
+func (e *testEnv) assessmentTimeout(assess types.Step) time.Duration {
+	if tb, ok := assess.(types.TimeBoundStep); ok && tb.Timeout() > 0 {
+		return tb.Timeout()
+	}
+	return e.cfg.DefaultAssessmentTimeout()
+}
+
+// executeStepWithTimeout executes the assessment with the timeout set as the deadline of its
+// context, and fails it when the timeout is exceeded. The values added to the context by the
+// assessment are passed down to the next steps but its deadline is not.
+func (e *testEnv) executeStepWithTimeout(ctx context.Context, t *testing.T, cfg *envconf.Config, assess types.Step, assessName string, timeout time.Duration) context.Context {
+	stepCtx, cancel := context.WithTimeoutCause(ctx, timeout, fmt.Errorf("assessment %q exceeded its timeout of %s", assessName, timeout))
+	defer cancel()
+	out := e.executeSteps(stepCtx, t, cfg, []types.Step{assess})
+	// a cancellation of the parent context, e.g. by the timeout of the feature, is reported by the feature
+	if stepCtx.Err() != nil && ctx.Err() == nil {
+		e.errorf(t, "%s", context.Cause(stepCtx))
+	}
+	return valuesContext{Context: ctx, values: out}
+}
+
+// valuesContext is a context carrying the values of another context, the deadline and
+// cancellation of the embedded context being kept
+type valuesContext struct {
+	context.Context
+	values context.Context
+}
+
+func (c valuesContext) Value(key any) any {
+	return c.values.Value(key)
+}
+
+// withAssessmentNamespace creates the namespace of an assessment and returns a copy of the
+// configuration cfg of the feature using it. The namespace is deleted when the assessment completes.
+// Each assessment gets its own copy of the configuration, so that assessments running
+// concurrently do not share their namespace.
+func (e *testEnv) withAssessmentNamespace(ctx context.Context, t *testing.T, cfg *envconf.Config) *envconf.Config {
+	client, err := e.cfg.NewClient()
+	if err != nil {
+		e.fatalf(t, "Failed to create the namespace of the assessment: %s", err)
+	}
+	name, err := e.cfg.GenerateName(e.assessmentNamespacePrefix)
+	if err != nil {
+		e.fatalf(t, "Failed to create the namespace of the assessment: %s", err)
+	}
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if id := RunID(ctx); id != "" {
+		namespace.Labels = map[string]string{RunIDLabelKey: id}
+	}
+	if err := client.Resources().Create(ctx, namespace); err != nil {
+		e.fatalf(t, "Failed to create the namespace of the assessment: %s", err)
+	}
+	t.Cleanup(func() {
+		if err := client.Resources().Delete(context.WithoutCancel(ctx), namespace); err != nil {
+			e.errorf(t, "Failed to delete the namespace %s of the assessment: %s", namespace.Name, err)
+		}
 	})
+	return cfg.Clone().WithNamespace(namespace.Name)
+}
 
-	return ctx
+// recoverStepPanic is meant to be deferred by the feature and assessment subtests. A panic raised by
+// a step is reported as a failure of the subtest instead of crashing the test binary. A panicking
+// assessment lets the following assessments and the teardown steps run, while a panic raised by a
+// feature-level step skips the remaining steps of the feature: only the cleanups registered with
+// AppendCleanup and the afterEachFeature actions run. Unless graceful teardown is disabled, in which
+// case the panic is propagated. In both cases, the panic handler of the environment, if any, is
+// invoked first with the name of the step pointed to by stepName.
+func (e *testEnv) recoverStepPanic(t *testing.T, featName string, stepName *string) {
+	if r := recover(); r != nil {
+		if e.panicHandler != nil {
+			e.panicHandler(featName, *stepName, r, debug.Stack())
+		}
+		if e.cfg.DisableGracefulTeardown() {
+			panic(r)
+		}
+		e.errorf(t, "Recovered from panic: %v, stack: %s", r, string(debug.Stack()))
+	}
+}
+
+// requireFeatureSelection checks if the feature is selected to run by the filters, the rerun manifest, the features file,
+// the shard and the Kubernetes version constraint of the environment configuration.
+// The source of the skip, one of the skipSource constants, is returned along with its message.
+func (e *testEnv) requireFeatureSelection(featureName string, feature types.Feature) (skip bool, source, message string, err error) {
+	if skip, source, message = e.requireFeatureProcessing(feature); skip {
+		return skip, source, message, nil
+	}
+	if !e.cfg.RerunFeature(featureName) {
+		return true, skipSourceRerun, fmt.Sprintf(`Skipping feature "%s": not listed in the failures manifest to rerun`, featureName), nil
+	}
+	if !e.cfg.FeatureListed(featureName) {
+		return true, skipSourceFeaturesFile, fmt.Sprintf(`Skipping feature "%s": not listed in the features file`, featureName), nil
+	}
+	if skip, message = e.requireShardProcessing(featureName); skip {
+		return skip, skipSourceShard, message, nil
+	}
+	skip, message, err = e.requireVersionProcessing(feature)
+	return skip, skipSourceVersion, message, err
 }
 
 // requireFeatureProcessing is a wrapper around the requireProcessing function to process the feature level validation
-func (e *testEnv) requireFeatureProcessing(f types.Feature) (skip bool, message string) {
+func (e *testEnv) requireFeatureProcessing(f types.Feature) (skip bool, source, message string) {
 	requiredRegexp := e.cfg.FeatureRegex()
 	skipRegexp := e.cfg.SkipFeatureRegex()
-	return e.requireProcessing("feature", f.Name(), requiredRegexp, skipRegexp, f.Labels())
+	labels := f.Labels()
+	if clusterLabels := e.cfg.ClusterLabels(); len(clusterLabels) > 0 {
+		// the labels of the cluster are matched as if the feature carried them
+		labels = make(types.Labels, len(labels)+len(clusterLabels))
+		for key, vals := range f.Labels() {
+			labels[key] = append(labels[key], vals...)
+		}
+		for key, val := range clusterLabels {
+			labels[key] = append(labels[key], val)
+		}
+	}
+	return e.requireProcessing("feature", f.Name(), requiredRegexp, skipRegexp, labels)
+}
+
+// requireShardProcessing checks if the feature belongs to the shard selected in the environment config.
+// Features are assigned to a shard using a hash of their name so that the partitioning is deterministic
+// across the machines running the different shards.
+func (e *testEnv) requireShardProcessing(featureName string) (skip bool, message string) {
+	index, count := e.cfg.Shard()
+	if count <= 0 {
+		return false, ""
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(featureName))
+	if shard := int(h.Sum32() % uint32(count)); shard != index {
+		return true, fmt.Sprintf(`Skipping feature "%s": belongs to shard %d, not the current shard %d of %d`, featureName, shard, index, count)
+	}
+	return false, ""
+}
+
+// requireVersionProcessing checks the Kubernetes version constraint of the feature, if any, against the
+// Kubernetes version detected into the environment config. The constraint is not enforced when no version
+// has been detected.
+func (e *testEnv) requireVersionProcessing(f types.Feature) (skip bool, message string, err error) {
+	vf, ok := f.(types.VersionConstrainedFeature)
+	if !ok || vf.KubernetesVersionConstraint() == "" {
+		return false, "", nil
+	}
+	constraint := vf.KubernetesVersionConstraint()
+	versionRange, err := semver.ParseRange(constraint)
+	if err != nil {
+		return false, "", fmt.Errorf("invalid Kubernetes version constraint %q: %w", constraint, err)
+	}
+	detected := e.cfg.KubernetesVersion()
+	if detected == "" {
+		klog.V(2).InfoS("No Kubernetes version detected, ignoring feature version constraint", "feature", f.Name(), "constraint", constraint)
+		return false, "", nil
+	}
+	version, err := semver.ParseTolerant(detected)
+	if err != nil {
+		return false, "", fmt.Errorf("invalid detected Kubernetes version %q: %w", detected, err)
+	}
+	// vendor specific suffixes such as "-gke.1" must not affect the range comparison
+	version.Pre = nil
+	version.Build = nil
+	if !versionRange(version) {
+		return true, fmt.Sprintf(`Skipping feature "%s": detected Kubernetes version %s does not satisfy the required range "%s"`, f.Name(), detected, constraint), nil
+	}
+	return false, "", nil
 }
 
 // requireAssessmentProcessing is a wrapper around the requireProcessing function to process the Assessment level validation
-func (e *testEnv) requireAssessmentProcessing(a types.Step, assessmentIndex int) (skip bool, message string) {
+func (e *testEnv) requireAssessmentProcessing(a types.Step, assessmentIndex int) (skip bool, source, message string) {
 	requiredRegexp := e.cfg.AssessmentRegex()
 	skipRegexp := e.cfg.SkipAssessmentRegex()
 	assessmentName := a.Name()
@@ -534,16 +1663,16 @@ func (e *testEnv) requireAssessmentProcessing(a types.Step, assessmentIndex int)
 // to decide if the entity in question will need processing.
 // This function also perform a label check against include/skip labels to make sure only those features to make sure
 // we can filter out all the non-required features during the test execution
-func (e *testEnv) requireProcessing(kind, testName string, requiredRegexp, skipRegexp *regexp.Regexp, labels types.Labels) (skip bool, message string) {
+func (e *testEnv) requireProcessing(kind, testName string, requiredRegexp, skipRegexp *regexp.Regexp, labels types.Labels) (skip bool, source, message string) {
 	if requiredRegexp != nil && !requiredRegexp.MatchString(testName) {
 		skip = true
 		message = fmt.Sprintf(`Skipping %s "%s": name not matched`, kind, testName)
-		return skip, message
+		return skip, skipSourceRegex, message
 	}
 	if skipRegexp != nil && skipRegexp.MatchString(testName) {
 		skip = true
 		message = fmt.Sprintf(`Skipping %s: "%s": name matched`, kind, testName)
-		return skip, message
+		return skip, skipSourceRegex, message
 	}
 
 	if labels != nil {
@@ -566,7 +1695,7 @@ func (e *testEnv) requireProcessing(kind, testName string, requiredRegexp, skipR
 				kvs = append(kvs, fmt.Sprintf("%s=%s", k, v)) // prettify output
 			}
 			message = fmt.Sprintf(`Skipping feature "%s": unmatched labels "%s"`, testName, kvs)
-			return skip, message
+			return skip, skipSourceLabel, message
 		}
 
 		// skip running a feature if labels matches with --skip-labels
@@ -575,12 +1704,12 @@ func (e *testEnv) requireProcessing(kind, testName string, requiredRegexp, skipR
 				if labels.Contains(key, v) {
 					skip = true
 					message = fmt.Sprintf(`Skipping feature "%s": matched label provided in --skip-lables "%s=%s"`, testName, key, labels[key])
-					return skip, message
+					return skip, skipSourceLabel, message
 				}
 			}
 		}
 	}
-	return skip, message
+	return skip, source, message
 }
 
 // deepCopyFeature just copies the values from the Feature but creates a deep
@@ -592,8 +1721,36 @@ func deepCopyFeature(f types.Feature) types.Feature {
 			fcopy = fcopy.WithLabel(k, v)
 		}
 	}
+	if vf, ok := f.(types.VersionConstrainedFeature); ok {
+		fcopy = fcopy.WithKubernetesVersionConstraint(vf.KubernetesVersionConstraint())
+	}
+	if tf, ok := f.(types.TimeBoundFeature); ok {
+		fcopy = fcopy.WithFeatureTimeout(tf.Timeout())
+	}
+	if pf, ok := f.(types.ParallelAssessmentsFeature); ok && pf.ParallelAssessments() {
+		fcopy = fcopy.WithParallelAssessments()
+	}
+	if cf, ok := f.(types.CleanupVerifiedFeature); ok && cf.CleanupVerification() {
+		fcopy = fcopy.WithCleanupVerification()
+	}
+	if vars := featureEnvVars(f); len(vars) > 0 {
+		fcopy = fcopy.WithEnvVars(vars)
+	}
+	if featureExclusive(f) {
+		fcopy = fcopy.WithExclusive()
+	}
+	if names := featureFixtures(f); len(names) > 0 {
+		fcopy = fcopy.WithFixture(names...)
+	}
+	for k, v := range featureMetadata(f) {
+		fcopy = fcopy.WithMetadata(k, v)
+	}
 	f.Steps()
 	for _, step := range f.Steps() {
+		if ordered, ok := step.(types.OrderedStep); ok && ordered.RunLast() && step.Level() == types.LevelAssess {
+			fcopy = fcopy.AssessLast(step.Name(), nil)
+			continue
+		}
 		fcopy = fcopy.WithStep(step.Name(), step.Level(), nil)
 	}
 	return fcopy.Feature()
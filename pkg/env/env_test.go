@@ -17,13 +17,29 @@ limitations under the License.
 package env
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klog "k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
 	"sigs.k8s.io/e2e-framework/pkg/types"
 
+	"sigs.k8s.io/e2e-framework/internal/testutil"
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
 	"sigs.k8s.io/e2e-framework/pkg/features"
 )
@@ -839,3 +855,1702 @@ func getFeaturesForTest() []features.Feature {
 		}).Feature()
 	return []features.Feature{f1, f2}
 }
+
+func TestEnv_SkipSummary(t *testing.T) {
+	env := NewWithConfig(envconf.New().WithSkipSummary().WithFeatureRegex("selected")).(*testEnv)
+	var executed bool
+	t.Run("skipped feature", func(t *testing.T) {
+		f := features.New("not-matching").Assess("assess", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			executed = true
+			return ctx
+		})
+		_ = env.Test(t, f.Feature())
+	})
+	if executed {
+		t.Fatal("expected feature to be skipped")
+	}
+	skipped := env.events.byKind(eventFeatureSkipped)
+	if len(skipped) != 1 {
+		t.Fatalf("expected 1 skip event to be recorded, got %d", len(skipped))
+	}
+	if skipped[0].feature != "not-matching" {
+		t.Errorf("unexpected feature recorded for skip event: %s", skipped[0].feature)
+	}
+}
+
+// TestEnv_Context checks that the context produced by the Setup
+// operations of the suite (see main_test.go) is the root context of
+// the environment.
+func TestEnv_Context(t *testing.T) {
+	val, ok := envForTesting.Context().Value(&ctxTestKeyString{}).([]string)
+	if !ok {
+		t.Fatal("context value was not []string")
+	}
+	expected := []string{"setup-1", "setup-2"}
+	if len(val) != len(expected) {
+		t.Fatalf("Expected:\n%v but got result:\n%v", expected, val)
+	}
+	for i := range val {
+		if val[i] != expected[i] {
+			t.Errorf("Expected:\n%v but got result:\n%v", expected, val)
+			break
+		}
+	}
+}
+
+func TestEnv_KubernetesVersionConstraint(t *testing.T) {
+	env := NewWithConfig(envconf.New().WithKubernetesVersion("v1.29.2-gke.1"))
+	tests := []struct {
+		name       string
+		constraint string
+		executed   bool
+	}{
+		{name: "no constraint", executed: true},
+		{name: "in range", constraint: ">=1.28.0 <1.30.0", executed: true},
+		{name: "out of range", constraint: ">=1.30.0", executed: false},
+	}
+	for _, test := range tests {
+		var executed bool
+		t.Run(test.name, func(t *testing.T) {
+			f := features.New(test.name).
+				WithKubernetesVersionConstraint(test.constraint).
+				Assess("assess", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+					executed = true
+					return ctx
+				})
+			_ = env.Test(t, f.Feature())
+		})
+		if executed != test.executed {
+			t.Errorf("%s: expected feature execution to be %v, got %v", test.name, test.executed, executed)
+		}
+	}
+}
+
+func TestEnv_AfterEachFeatureOnPanic(t *testing.T) {
+	var observedFeature string
+	var observedFailure bool
+	env := NewWithConfig(envconf.New()).
+		AfterEachFeature(func(ctx context.Context, _ *envconf.Config, t *testing.T, f types.Feature) (context.Context, error) {
+			observedFeature = f.Name()
+			observedFailure = t.Failed()
+			return ctx, nil
+		})
+	f := features.New("panicking").Assess("assess", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+		panic("assessment panic")
+	})
+
+	// run the failing feature in isolation to keep the failure from bubbling up to this test
+	outcome := testutil.RunIsolated("TestPanickingFeature", func(t *testing.T) { _ = env.Test(t, f.Feature()) })
+	if !outcome.Failed {
+		t.Error("expected the panicking feature to fail the test")
+	}
+	if observedFeature != "panicking" {
+		t.Errorf("expected AfterEachFeature to observe feature %q, got %q", "panicking", observedFeature)
+	}
+	if !observedFailure {
+		t.Error("expected AfterEachFeature to observe the failure of the feature")
+	}
+}
+
+func TestEnv_AfterEachFeatureOnBeforeEachFeatureFailure(t *testing.T) {
+	type ctxKey struct{}
+	var observed context.Context
+	env := NewWithConfig(envconf.New()).
+		BeforeEachFeature(func(ctx context.Context, _ *envconf.Config, _ *testing.T, _ types.Feature) (context.Context, error) {
+			return ctx, errors.New("before feature failure")
+		}).
+		AfterEachFeature(func(ctx context.Context, _ *envconf.Config, _ *testing.T, _ types.Feature) (context.Context, error) {
+			observed = ctx
+			return ctx, nil
+		})
+	executed := false
+	f := features.New("feature").Assess("assess", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+		executed = true
+		return ctx
+	})
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+	outcome := testutil.RunIsolated("TestFailingBeforeEachFeature", func(t *testing.T) { _ = env.TestWithContext(ctx, t, f.Feature()) })
+	if !outcome.Failed {
+		t.Error("expected the failing BeforeEachFeature action to fail the test")
+	}
+	if executed {
+		t.Error("expected the feature not to run once BeforeEachFeature failed")
+	}
+	if observed == nil {
+		t.Fatal("expected AfterEachFeature to be passed a context")
+	}
+	if value := observed.Value(ctxKey{}); value != "value" {
+		t.Errorf("expected AfterEachFeature to be passed the context of the feature, got value %v", value)
+	}
+}
+
+func TestEnv_Sharding(t *testing.T) {
+	const shardCount = 3
+	featureNames := []string{"feature-a", "feature-b", "feature-c", "feature-d", "feature-e", "feature-f"}
+	runs := make(map[string]int)
+	for index := 0; index < shardCount; index++ {
+		env := NewWithConfig(envconf.New().WithShard(index, shardCount))
+		for _, name := range featureNames {
+			name := name
+			t.Run(fmt.Sprintf("shard-%d/%s", index, name), func(t *testing.T) {
+				f := features.New(name).Assess("assess", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+					runs[name]++
+					return ctx
+				})
+				_ = env.Test(t, f.Feature())
+			})
+		}
+	}
+	for _, name := range featureNames {
+		if runs[name] != 1 {
+			t.Errorf("expected feature %q to run on exactly one shard, ran on %d", name, runs[name])
+		}
+	}
+}
+
+func TestEnv_PanicHandler(t *testing.T) {
+	var feature, step string
+	var recovered any
+	env := NewWithConfig(envconf.New()).WithPanicHandler(func(f, s string, r any, stack []byte) {
+		feature, step, recovered = f, s, r
+		if len(stack) == 0 {
+			t.Error("expected the stack of the panic to be provided")
+		}
+	})
+	f := features.New("panicking").WithSetup("panicking-setup", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+		panic("setup panic")
+	})
+
+	// run the failing feature in isolation to keep the failure from bubbling up to this test
+	_ = testutil.RunIsolated("TestPanickingFeature", func(t *testing.T) { _ = env.Test(t, f.Feature()) })
+	if feature != "panicking" || step != "panicking-setup" || recovered != "setup panic" {
+		t.Errorf("unexpected panic handler arguments: feature=%q step=%q recovered=%v", feature, step, recovered)
+	}
+}
+
+func TestEnv_Quarantine(t *testing.T) {
+	env := NewWithConfig(envconf.New()).(*testEnv)
+	fail := func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+		t.Error("known failure")
+		return ctx
+	}
+	var assessed []string
+	pass := func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+		assessed = append(assessed, t.Name())
+		return ctx
+	}
+	quarantined := features.New("quarantined").Quarantine().Assess("fail", fail).Assess("pass", pass)
+
+	// the quarantined feature runs as a regular subtest and reports its failure
+	outcome := testutil.RunIsolated("TestQuarantined", func(t *testing.T) { _ = env.Test(t, quarantined.Feature()) })
+	if !outcome.Failed {
+		t.Error("expected the quarantined feature to report its failure")
+	}
+	if !reflect.DeepEqual(assessed, []string{"TestQuarantined/quarantined/pass"}) {
+		t.Errorf("expected the other assessments to run, got %v", assessed)
+	}
+	if q, other := env.events.failures(); q != 1 || other != 0 {
+		t.Errorf("expected only a quarantined failure, got %d quarantined and %d other failure(s)", q, other)
+	}
+	exitCode, _, _ := env.run(func() int { return 1 }, false)
+	if exitCode != 0 {
+		t.Errorf("expected quarantined failures not to fail the test suite, got exit code %d", exitCode)
+	}
+
+	failing := features.New("failing").Assess("fail", fail)
+	_ = testutil.RunIsolated("TestFailing", func(t *testing.T) { _ = env.Test(t, failing.Feature()) })
+	if q, other := env.events.failures(); q != 1 || other != 2 {
+		t.Errorf("expected the failure of the feature and of its test to be counted, got %d quarantined and %d other failure(s)", q, other)
+	}
+	exitCode, _, _ = env.run(func() int { return 1 }, false)
+	if exitCode != 1 {
+		t.Errorf("expected the failure of a feature that is not quarantined to fail the test suite, got exit code %d", exitCode)
+	}
+}
+
+type eventRecorder struct {
+	lines []string
+}
+
+func (r *eventRecorder) Logf(format string, args ...any) {
+	r.lines = append(r.lines, fmt.Sprintf(format, args...))
+}
+
+func TestLogAssessmentEvent(t *testing.T) {
+	rec := &eventRecorder{}
+	logAssessmentEvent(rec, assessmentEvent{Action: "start", Feature: "feature", Assessment: "assess"})
+	logAssessmentEvent(rec, assessmentEvent{Action: "pass", Feature: "feature", Assessment: "assess", Elapsed: 1.5})
+	expected := []string{
+		`e2e-framework/assessment-event: {"Action":"start","Feature":"feature","Assessment":"assess"}`,
+		`e2e-framework/assessment-event: {"Action":"pass","Feature":"feature","Assessment":"assess","Elapsed":1.5}`,
+	}
+	if len(rec.lines) != len(expected) {
+		t.Fatalf("expected %d events, got %d", len(expected), len(rec.lines))
+	}
+	for i := range expected {
+		if rec.lines[i] != expected[i] {
+			t.Errorf("unexpected event:\n%s\nexpected:\n%s", rec.lines[i], expected[i])
+		}
+	}
+}
+
+func TestEnv_StepLevelOrder(t *testing.T) {
+	var order []string
+	record := func(name string) features.Func {
+		return func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			order = append(order, name)
+			return ctx
+		}
+	}
+	f := features.New("levels").
+		WithStep("teardown", types.LevelTeardown, record("teardown")).
+		WithStep("post-assess", types.LevelPostAssess, record("post-assess")).
+		Assess("assess", record("assess")).
+		WithStep("setup", types.LevelSetup, record("setup")).
+		WithStep("pre-setup", types.LevelPreSetup, record("pre-setup"))
+	_ = NewWithConfig(envconf.New()).Test(t, f.Feature())
+
+	expected := []string{"pre-setup", "setup", "assess", "post-assess", "teardown"}
+	if len(order) != len(expected) {
+		t.Fatalf("Expected:\n%v but got result:\n%v", expected, order)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("Expected:\n%v but got result:\n%v", expected, order)
+		}
+	}
+}
+
+func TestEnv_WithContext_Isolation(t *testing.T) {
+	noop := func(ctx context.Context, _ *envconf.Config) (context.Context, error) { return ctx, nil }
+	base := New().Setup(noop)
+	derived := base.WithContext(context.TODO())
+
+	base.Setup(noop)
+	derived.Finish(noop)
+
+	if n := len(base.(*testEnv).actions); n != 2 {
+		t.Errorf("expected base environment to have 2 actions, got %d", n)
+	}
+	if n := len(derived.(*testEnv).actions); n != 2 {
+		t.Errorf("expected derived environment to have 2 actions, got %d", n)
+	}
+	if n := len(derived.(*testEnv).getSetupActions()); n != 1 {
+		t.Errorf("expected derived environment to have 1 setup action, got %d", n)
+	}
+}
+
+func TestEnv_WithMergedActions(t *testing.T) {
+	var order []string
+	setup := func(name string) Func {
+		return func(ctx context.Context, _ *envconf.Config) (context.Context, error) {
+			order = append(order, name)
+			return ctx, nil
+		}
+	}
+	base := New().Setup(setup("base-1"), setup("base-2"))
+	other := New().Setup(setup("other-1"))
+	merged := base.WithMergedActions(other).(*testEnv)
+
+	// actions registered after the merge are not visible to the merged environment
+	base.Setup(setup("base-3"))
+	other.Setup(setup("other-2"))
+
+	for _, action := range merged.getSetupActions() {
+		if _, err := action.run(context.TODO(), merged.cfg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := []string{"base-1", "base-2", "other-1"}
+	if len(order) != len(expected) {
+		t.Fatalf("Expected:\n%v but got result:\n%v", expected, order)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("Expected:\n%v but got result:\n%v", expected, order)
+		}
+	}
+}
+
+func TestEnv_Extend(t *testing.T) {
+	var order []string
+	record := func(name string) Func {
+		return func(ctx context.Context, _ *envconf.Config) (context.Context, error) {
+			order = append(order, name)
+			return ctx, nil
+		}
+	}
+	base := New().Setup(record("base-setup")).BeforeEachFeature(func(ctx context.Context, _ *envconf.Config, _ *testing.T, _ types.Feature) (context.Context, error) {
+		order = append(order, "base-before")
+		return ctx, nil
+	})
+	child := Extend(base).Setup(record("child-setup")).BeforeEachFeature(func(ctx context.Context, _ *envconf.Config, _ *testing.T, _ types.Feature) (context.Context, error) {
+		order = append(order, "child-before")
+		return ctx, nil
+	}).(*testEnv)
+
+	// actions registered on base after the call are not visible to the child
+	base.Setup(record("base-late"))
+
+	if child.cfg != base.(*testEnv).cfg {
+		t.Error("expected the child environment to share the config of its base")
+	}
+	for _, action := range child.getSetupActions() {
+		if _, err := action.run(context.TODO(), child.cfg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, action := range child.getBeforeFeatureActions() {
+		if _, err := action.runWithFeature(context.TODO(), child.cfg, t, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := []string{"base-setup", "child-setup", "base-before", "child-before"}
+	if len(order) != len(expected) {
+		t.Fatalf("Expected:\n%v but got result:\n%v", expected, order)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("Expected:\n%v but got result:\n%v", expected, order)
+		}
+	}
+}
+
+func TestEnv_SetupWithCleanup(t *testing.T) {
+	var order []string
+	record := func(name string) Func {
+		return func(ctx context.Context, _ *envconf.Config) (context.Context, error) {
+			order = append(order, name)
+			return ctx, nil
+		}
+	}
+	env := New().
+		SetupWithCleanup(record("setup-1"), record("cleanup-1")).
+		SetupWithCleanup(record("setup-2"), record("cleanup-2")).(*testEnv)
+
+	var cleanups []action
+	for _, setup := range env.getSetupActions() {
+		if setup.cleanup == nil {
+			t.Fatal("expected setup to be paired with a cleanup")
+		}
+		cleanups = append(cleanups, action{role: roleFinish, funcs: []types.EnvFunc{setup.cleanup}})
+	}
+	_ = env.runCleanups(context.TODO(), cleanups)
+
+	expected := []string{"cleanup-2", "cleanup-1"}
+	if len(order) != len(expected) {
+		t.Fatalf("Expected:\n%v but got result:\n%v", expected, order)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("Expected:\n%v but got result:\n%v", expected, order)
+		}
+	}
+}
+
+func TestEnv_SetupOnProvider(t *testing.T) {
+	var order []string
+	record := func(name string) Func {
+		return func(ctx context.Context, _ *envconf.Config) (context.Context, error) {
+			order = append(order, name)
+			return ctx, nil
+		}
+	}
+	cfg := envconf.New()
+	env := NewWithConfig(cfg).
+		Setup(func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+			cfg.WithClusterLabel(envconf.ClusterProviderLabelKey, "kind")
+			return ctx, nil
+		}).
+		SetupOnProvider("aws", record("aws-1"), record("aws-2")).
+		SetupOnProvider("kind", record("kind-1"), record("kind-2")).(*testEnv)
+
+	ctx := context.TODO()
+	for _, setup := range env.getSetupActions() {
+		var err error
+		if ctx, err = setup.run(ctx, cfg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := []string{"kind-1", "kind-2"}
+	if !reflect.DeepEqual(order, expected) {
+		t.Errorf("Expected:\n%v but got result:\n%v", expected, order)
+	}
+
+	// the operations are skipped with a warning when the provider was not detected
+	var logs bytes.Buffer
+	klog.LogToStderr(false)
+	klog.SetOutput(&logs)
+	defer func() {
+		klog.SetOutput(os.Stderr)
+		klog.LogToStderr(true)
+	}()
+	order = nil
+	env = NewWithConfig(envconf.New()).SetupOnProvider("kind", record("kind-1")).(*testEnv)
+	for _, setup := range env.getSetupActions() {
+		if _, err := setup.run(context.TODO(), env.cfg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	klog.Flush()
+	if len(order) != 0 {
+		t.Errorf("expected the operations to be skipped, got %v", order)
+	}
+	if !strings.Contains(logs.String(), `Skipping the Setup operations of provider "kind": the provider of the cluster is unknown`) {
+		t.Errorf("expected a warning about the unknown provider, got logs:\n%s", logs.String())
+	}
+}
+
+func TestEnv_SetupWithTimeout(t *testing.T) {
+	type valueKey struct{}
+	var deadlines []bool
+	record := func(ctx context.Context, _ *envconf.Config) (context.Context, error) {
+		_, ok := ctx.Deadline()
+		deadlines = append(deadlines, ok)
+		return context.WithValue(ctx, valueKey{}, "set"), nil
+	}
+	hang := func(ctx context.Context, _ *envconf.Config) (context.Context, error) {
+		<-ctx.Done()
+		return ctx, ctx.Err()
+	}
+	cfg := envconf.New()
+	env := NewWithConfig(cfg).
+		SetupWithTimeout(time.Minute, record, nil).
+		Setup(record).
+		SetupWithTimeout(10*time.Millisecond, record, hang, record).(*testEnv)
+
+	ctx := context.TODO()
+	var err error
+	for _, setup := range env.getSetupActions() {
+		if ctx, err = setup.run(ctx, cfg); err != nil {
+			break
+		}
+	}
+	if err == nil || !strings.Contains(err.Error(), "Setup func 2 of 3 exceeded its timeout of 10ms") {
+		t.Errorf("expected the hanging func to fail with its position, got %v", err)
+	}
+	// the values of the funcs are passed down, their deadlines are not
+	if expected := []bool{true, false, true}; !reflect.DeepEqual(deadlines, expected) {
+		t.Errorf("Expected:\n%v but got result:\n%v", expected, deadlines)
+	}
+	if _, ok := ctx.Deadline(); ok || ctx.Value(valueKey{}) != "set" {
+		t.Error("expected the context of the setup to carry the values of the funcs and no deadline")
+	}
+}
+
+func TestEnv_AssessLast(t *testing.T) {
+	var order []string
+	record := func(name string) features.Func {
+		return func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			order = append(order, name)
+			return ctx
+		}
+	}
+	f := features.New("assess-last").
+		AssessLast("last-1", record("last-1")).
+		Assess("assess-1", record("assess-1")).
+		AssessLast("last-2", record("last-2")).
+		Assess("assess-2", record("assess-2"))
+	_ = NewWithConfig(envconf.New()).Test(t, f.Feature())
+
+	expected := []string{"assess-1", "assess-2", "last-1", "last-2"}
+	if len(order) != len(expected) {
+		t.Fatalf("Expected:\n%v but got result:\n%v", expected, order)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("Expected:\n%v but got result:\n%v", expected, order)
+		}
+	}
+}
+
+func TestEnv_RerunFailed(t *testing.T) {
+	env := NewWithConfig(envconf.New().WithRerunFeatures("failed")).(*testEnv)
+	executed := make(map[string]bool)
+	for _, name := range []string{"failed", "passed"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			f := features.New(name).Assess("assess", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+				executed[name] = true
+				return ctx
+			})
+			_ = env.Test(t, f.Feature())
+		})
+	}
+	if !executed["failed"] || executed["passed"] {
+		t.Errorf("expected only the feature listed for the rerun to be executed, got %v", executed)
+	}
+}
+
+func TestEnv_FeatureList(t *testing.T) {
+	env := NewWithConfig(envconf.New().WithFeatureList("third", "first", "unknown")).(*testEnv)
+	var executed []string
+	var testFeatures []types.Feature
+	for _, name := range []string{"first", "second", "third"} {
+		name := name
+		testFeatures = append(testFeatures, features.New(name).Assess("assess", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			executed = append(executed, name)
+			return ctx
+		}).Feature())
+	}
+	_ = env.Test(t, testFeatures...)
+
+	expected := []string{"third", "first"}
+	if !reflect.DeepEqual(executed, expected) {
+		t.Errorf("Expected:\n%v but got result:\n%v", expected, executed)
+	}
+	if unknown := env.events.unseen(env.cfg.FeatureList()); !reflect.DeepEqual(unknown, []string{"unknown"}) {
+		t.Errorf("expected the unknown feature of the list to be reported, got %v", unknown)
+	}
+}
+
+func TestEnv_FeatureEnvVars(t *testing.T) {
+	t.Setenv("E2E_FEATURE_ENV_SET", "original")
+	env := NewWithConfig(envconf.New())
+	observed := make(map[string]string)
+	f := features.New("env-vars").
+		WithEnvVars(map[string]string{"E2E_FEATURE_ENV_SET": "feature", "E2E_FEATURE_ENV_UNSET": "feature"}).
+		Assess("observe", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			observed["set"] = os.Getenv("E2E_FEATURE_ENV_SET")
+			observed["unset"] = os.Getenv("E2E_FEATURE_ENV_UNSET")
+			return ctx
+		}).
+		Teardown(func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			panic("teardown panic")
+		})
+
+	// run the panicking feature in isolation to keep the failure from bubbling up to this test
+	_ = testutil.RunIsolated("TestFeatureEnvVars", func(t *testing.T) { _ = env.Test(t, f.Feature()) })
+	if observed["set"] != "feature" || observed["unset"] != "feature" {
+		t.Errorf("expected the variables of the feature to be set while it runs, got %v", observed)
+	}
+	if value := os.Getenv("E2E_FEATURE_ENV_SET"); value != "original" {
+		t.Errorf("expected the previous value of the variable to be restored, got %q", value)
+	}
+	if _, found := os.LookupEnv("E2E_FEATURE_ENV_UNSET"); found {
+		t.Error("expected the variable unset before the feature to be unset")
+	}
+}
+
+func TestEnv_ExclusiveFeature(t *testing.T) {
+	env := NewParallel()
+	var mu sync.Mutex
+	var running, maxShared int
+	var exclusiveAlone bool
+	assess := func(exclusive bool) features.Func {
+		return func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			mu.Lock()
+			running++
+			if exclusive {
+				exclusiveAlone = running == 1
+			} else if running > maxShared {
+				maxShared = running
+			}
+			mu.Unlock()
+			time.Sleep(50 * time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return ctx
+		}
+	}
+	var testFeatures []types.Feature
+	for i := 0; i < 4; i++ {
+		testFeatures = append(testFeatures, features.New(fmt.Sprintf("shared-%d", i)).Assess("assess", assess(false)).Feature())
+	}
+	testFeatures = append(testFeatures[:2], append([]types.Feature{
+		features.New("exclusive").WithExclusive().Assess("assess", assess(true)).Feature(),
+	}, testFeatures[2:]...)...)
+	_ = env.TestInParallel(t, testFeatures...)
+
+	if !exclusiveAlone {
+		t.Error("expected the exclusive feature to run alone")
+	}
+	if maxShared < 2 {
+		t.Errorf("expected the other features to run concurrently, at most %d ran at once", maxShared)
+	}
+}
+
+func TestEnv_RecordAttribute(t *testing.T) {
+	env := newTestEnv()
+	f := features.New("attributes").
+		Assess("measure", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			RecordAttribute(ctx, "latency", 25*time.Millisecond)
+			RecordAttribute(ctx, "count", 1)
+			RecordAttribute(ctx, "count", 2)
+			return ctx
+		}).
+		Assess("no attributes", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			return ctx
+		}).
+		Teardown(func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			RecordAttribute(ctx, "ignored", true)
+			return ctx
+		})
+	_ = env.Test(t, f.Feature())
+
+	events := env.events.byKind(eventAssessmentAttributes)
+	if len(events) != 1 {
+		t.Fatalf("expected the attributes of a single assessment, got %v", events)
+	}
+	expected := map[string]any{"latency": 25 * time.Millisecond, "count": 2}
+	if events[0].feature != "attributes" || events[0].assessment != "measure" || !reflect.DeepEqual(events[0].metadata, expected) {
+		t.Errorf("unexpected attributes event: %+v", events[0])
+	}
+}
+
+func TestEnv_KeepEnv(t *testing.T) {
+	var order []string
+	record := func(name string) features.Func {
+		return func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			order = append(order, name)
+			return ctx
+		}
+	}
+	f := features.New("keep").
+		Setup(func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			AppendCleanup(ctx, func(context.Context, *envconf.Config) error {
+				order = append(order, "cleanup")
+				return nil
+			})
+			return ctx
+		}).
+		Assess("assess", record("assess")).
+		Teardown(record("teardown")).
+		Feature()
+	env := NewWithConfig(envconf.New().WithKeepEnv("E2E_KEEP"))
+
+	for _, test := range []struct {
+		value    string
+		expected []string
+	}{
+		{value: "1", expected: []string{"assess"}},
+		{value: "true", expected: []string{"assess"}},
+		{value: "0", expected: []string{"assess", "teardown", "cleanup"}},
+		{value: "", expected: []string{"assess", "teardown", "cleanup"}},
+	} {
+		t.Setenv("E2E_KEEP", test.value)
+		order = nil
+		_ = env.Test(t, f)
+		if !reflect.DeepEqual(order, test.expected) {
+			t.Errorf("E2E_KEEP=%q: expected:\n%v but got result:\n%v", test.value, test.expected, order)
+		}
+	}
+}
+
+func TestEnv_Fixtures(t *testing.T) {
+	var order []string
+	env := newTestEnv()
+	env.RegisterFixture("server",
+		func(ctx context.Context, _ *envconf.Config) (any, error) {
+			order = append(order, "server-setup")
+			return "http://server", nil
+		},
+		func(ctx context.Context, _ *envconf.Config, value any) error {
+			order = append(order, fmt.Sprintf("server-teardown %v", value))
+			return nil
+		})
+	assess := func(name string) features.Func {
+		return func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			value, _ := Fixture(ctx, "server")
+			order = append(order, fmt.Sprintf("%s %v", name, value))
+			return ctx
+		}
+	}
+	_ = env.Test(t,
+		features.New("first").WithFixture("server").Assess("assess", assess("first")).Feature(),
+		features.New("no-fixture").Assess("assess", assess("no-fixture")).Feature(),
+		features.New("last").WithFixture("server").Assess("assess", assess("last")).
+			Teardown(func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+				order = append(order, "last-teardown")
+				return ctx
+			}).Feature(),
+	)
+	expected := []string{
+		"server-setup",
+		"first http://server",
+		"no-fixture <nil>",
+		"last http://server",
+		"last-teardown",
+		"server-teardown http://server",
+	}
+	if !reflect.DeepEqual(order, expected) {
+		t.Errorf("Expected:\n%v but got result:\n%v", expected, order)
+	}
+
+	// a feature requesting an unknown fixture fails, run in isolation to keep the failure from bubbling up to this test
+	outcome := testutil.RunIsolated("TestUnknownFixture", func(t *testing.T) {
+		_ = env.Test(t, features.New("unknown").WithFixture("database").Assess("assess", assess("unknown")).Feature())
+	})
+	if !outcome.Failed {
+		t.Error("expected the feature requesting an unknown fixture to fail")
+	}
+}
+
+func TestEnv_SkipHandler(t *testing.T) {
+	cfg := envconf.New().
+		WithSkipFeatureRegex("by-regex").
+		WithSkipLabels(map[string][]string{"tier": {"slow"}}).
+		WithSkipAssessmentRegex("skipped-assessment")
+	var mu sync.Mutex
+	reasons := make(map[string][]string)
+	env := NewWithConfig(cfg).WithSkipHandler(func(feature, reason string) {
+		mu.Lock()
+		defer mu.Unlock()
+		reasons[feature] = append(reasons[feature], reason)
+	})
+	noop := func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context { return ctx }
+	_ = env.Test(t,
+		features.New("by-regex").Assess("assess", noop).Feature(),
+		features.New("by-label").WithLabel("tier", "slow").Assess("assess", noop).Feature(),
+		features.New("by-precondition").
+			WithPrecondition("never met", func(context.Context, *envconf.Config) (bool, error) { return false, nil }).
+			Assess("assess", noop).Feature(),
+		features.New("by-assessment").Assess("skipped-assessment", noop).Assess("assess", noop).Feature(),
+	)
+
+	for feature, source := range map[string]string{
+		"by-regex":        "regex: ",
+		"by-label":        "label: ",
+		"by-precondition": "precondition: ",
+		"by-assessment":   "regex: ",
+	} {
+		if len(reasons[feature]) != 1 || !strings.HasPrefix(reasons[feature][0], source) {
+			t.Errorf("expected a single skip of feature %q with a reason starting with %q, got %v", feature, source, reasons[feature])
+		}
+	}
+}
+
+func TestEnv_TestSuite(t *testing.T) {
+	var order []string
+	suiteFunc := func(name string) Func {
+		return func(ctx context.Context, _ *envconf.Config) (context.Context, error) {
+			order = append(order, name)
+			return ctx, nil
+		}
+	}
+	newSuite := func(name string) types.Suite {
+		f := features.New(name+"-feature").Assess("assess", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			order = append(order, name+"-feature")
+			return ctx
+		})
+		return features.NewSuite(name).
+			Setup(suiteFunc(name + "-setup")).
+			Teardown(suiteFunc(name + "-teardown")).
+			WithFeatures(f.Feature()).
+			Suite()
+	}
+	env := NewWithConfig(envconf.New().WithFeatureRegex("networking"))
+
+	t.Run("networking", func(t *testing.T) {
+		_ = env.TestSuite(t, newSuite("networking"))
+	})
+	t.Run("storage", func(t *testing.T) {
+		_ = env.TestSuite(t, newSuite("storage"))
+	})
+
+	expected := []string{"networking-setup", "networking-feature", "networking-teardown"}
+	if len(order) != len(expected) {
+		t.Fatalf("Expected:\n%v but got result:\n%v", expected, order)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("Expected:\n%v but got result:\n%v", expected, order)
+		}
+	}
+}
+
+func TestEnv_RequireEnvVars(t *testing.T) {
+	t.Setenv("E2E_FRAMEWORK_TEST_TOKEN", "token")
+	env := NewWithConfig(envconf.New()).RequireEnvVars("E2E_FRAMEWORK_TEST_TOKEN").(*testEnv)
+	if err := env.loadRequiredEnvVars(); err != nil {
+		t.Fatal(err)
+	}
+	if value := env.cfg.EnvVar("E2E_FRAMEWORK_TEST_TOKEN"); value != "token" {
+		t.Errorf("unexpected value recorded for the environment variable: %q", value)
+	}
+
+	env.RequireEnvVars("E2E_FRAMEWORK_TEST_MISSING_1", "E2E_FRAMEWORK_TEST_MISSING_2")
+	err := env.loadRequiredEnvVars()
+	if err == nil || err.Error() != "missing required environment variables: E2E_FRAMEWORK_TEST_MISSING_1, E2E_FRAMEWORK_TEST_MISSING_2" {
+		t.Errorf("unexpected error for missing environment variables: %v", err)
+	}
+}
+
+func TestEnv_Actions(t *testing.T) {
+	noop := func(ctx context.Context, _ *envconf.Config) (context.Context, error) { return ctx, nil }
+	noopFeature := func(ctx context.Context, _ *envconf.Config, _ *testing.T, _ types.Feature) (context.Context, error) {
+		return ctx, nil
+	}
+	env := NewWithConfig(envconf.New()).
+		Setup(noop, noop).
+		SetupWithCleanup(noop, noop).
+		BeforeEachFeature(noopFeature).
+		Finish(noop)
+
+	expected := []ActionInfo{
+		{Role: "Setup", Funcs: 2},
+		{Role: "Setup", Funcs: 1, HasCleanup: true},
+		{Role: "BeforeEachFeature", Funcs: 1},
+		{Role: "Finish", Funcs: 1},
+	}
+	actions := env.Actions()
+	if fmt.Sprint(actions) != fmt.Sprint(expected) {
+		t.Errorf("Expected:\n%v but got result:\n%v", expected, actions)
+	}
+
+	actions[0].Funcs = 0
+	if env.Actions()[0].Funcs != 2 {
+		t.Error("expected changes to the snapshot not to affect the environment")
+	}
+}
+
+func TestEnv_FeatureTimeout(t *testing.T) {
+	var order []string
+	var teardownErr error
+	record := func(name string) features.Func {
+		return func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			order = append(order, name)
+			return ctx
+		}
+	}
+	f := features.New("timed-out").
+		WithFeatureTimeout(50*time.Millisecond).
+		Assess("slow", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			order = append(order, "slow")
+			<-ctx.Done()
+			return ctx
+		}).
+		Assess("skipped", record("skipped")).
+		Teardown(func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			order = append(order, "teardown")
+			teardownErr = ctx.Err()
+			return ctx
+		})
+
+	// run the failing feature in isolation to keep the failure from bubbling up to this test
+	outcome := testutil.RunIsolated("TestTimedOutFeature", func(t *testing.T) { _ = NewWithConfig(envconf.New()).Test(t, f.Feature()) })
+	if !outcome.Failed {
+		t.Error("expected the feature exceeding its timeout to fail the test")
+	}
+	expected := []string{"slow", "teardown"}
+	if fmt.Sprint(order) != fmt.Sprint(expected) {
+		t.Errorf("Expected:\n%v but got result:\n%v", expected, order)
+	}
+	if teardownErr != nil {
+		t.Errorf("expected the teardown context not to be bound to the feature timeout, got %v", teardownErr)
+	}
+}
+
+func TestEnv_FeatureMetadata(t *testing.T) {
+	env := NewWithConfig(envconf.New()).(*testEnv)
+	f := features.New("owned").WithMetadata("owner", "team-a").WithMetadata("ticket", 42).
+		Assess("assess", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			t.Error("failure to triage")
+			return ctx
+		})
+
+	// run the failing feature in isolation to keep the failure from bubbling up to this test
+	_ = testutil.RunIsolated("TestOwnedFeature", func(t *testing.T) { _ = env.Test(t, f.Feature()) })
+	failed := env.events.byKind(eventFeatureFailed)
+	if len(failed) != 1 {
+		t.Fatalf("expected 1 failed feature, got %d", len(failed))
+	}
+	if metadata := formatMetadata(failed[0].metadata); metadata != "owner=team-a ticket=42" {
+		t.Errorf("unexpected metadata of the failed feature: %s", metadata)
+	}
+	// the metadata is written to the failures manifest along with the feature name
+	if failures := env.events.failedFeatures(env.redact); failures["owned"] != "owner=team-a ticket=42" {
+		t.Errorf("unexpected failures for the manifest: %v", failures)
+	}
+}
+
+func TestEnv_RunStats(t *testing.T) {
+	env := NewWithConfig(envconf.New().WithSkipFeatureRegex("skipped")).(*testEnv)
+	pass := func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context { return ctx }
+	fail := func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+		t.Error("failure")
+		return ctx
+	}
+	failing := features.New("failing").Assess("pass", pass).Assess("fail", fail)
+	passing := features.New("passing").Assess("pass", pass)
+	skipped := features.New("skipped").Assess("pass", pass)
+
+	// run the failing feature in isolation to keep the failure from bubbling up to this test
+	_ = testutil.RunIsolated("TestStats", func(t *testing.T) { _ = env.Test(t, failing.Feature(), passing.Feature()) })
+	t.Run("skipped", func(t *testing.T) { _ = env.Test(t, skipped.Feature()) })
+
+	expected := RunStats{FeaturesRun: 2, FeaturesSkipped: 1, FeaturesFailed: 1, AssessmentsRun: 3, AssessmentsFailed: 1}
+	if stats := env.events.runStats(); stats != expected {
+		t.Errorf("Expected:\n%+v but got result:\n%+v", expected, stats)
+	}
+}
+
+func TestEnv_WithRunID(t *testing.T) {
+	env := NewWithConfig(envconf.New()).WithRunID("run-42")
+	if id := RunID(env.Context()); id != "run-42" {
+		t.Errorf("unexpected run ID: %q", id)
+	}
+
+	generated := RunID(NewWithConfig(envconf.New()).WithRunID("").Context())
+	if len(generated) != 32 || !strings.HasPrefix(generated, "run-") {
+		t.Errorf("expected a generated run ID of 32 characters, got %q", generated)
+	}
+	cfg := envconf.New().WithNameGenerator(func(prefix string) string { return prefix + "-fixed" })
+	if id := RunID(NewWithConfig(cfg).WithRunID("").Context()); id != "run-fixed" {
+		t.Errorf("expected the run ID of the name generator, got %q", id)
+	}
+	if id := RunID(context.Background()); id != "" {
+		t.Errorf("expected no run ID in a context without one, got %q", id)
+	}
+}
+
+func TestOnce(t *testing.T) {
+	var calls atomic.Int32
+	compute := func() (any, error) {
+		calls.Add(1)
+		return "graph", nil
+	}
+	assess := func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+		value, err := Once(ctx, "graph", compute)
+		if err != nil || value != "graph" {
+			t.Errorf("unexpected result: %v, %v", value, err)
+		}
+		return ctx
+	}
+	env := NewWithConfig(envconf.New())
+	_ = env.Test(t, features.New("first").Assess("assess-1", assess).Assess("assess-2", assess).Feature())
+	if n := calls.Load(); n != 1 {
+		t.Errorf("expected the value to be computed once for the feature, got %d calls", n)
+	}
+	_ = env.Test(t, features.New("second").Assess("assess", assess).Feature())
+	if n := calls.Load(); n != 2 {
+		t.Errorf("expected the value to be computed again for another feature, got %d calls", n)
+	}
+
+	if _, err := Once(context.Background(), "graph", compute); err == nil {
+		t.Error("expected an error outside of a feature")
+	}
+}
+
+func TestEnv_SetupOnce(t *testing.T) {
+	var installs int
+	install := func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+		installs++
+		return ctx
+	}
+	noop := func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context { return ctx }
+	env := NewWithConfig(envconf.New())
+	_ = env.Test(t,
+		features.New("first").SetupOnce("install-crd", install).Assess("assess", noop).Feature(),
+		features.New("second").SetupOnce("install-crd", install).Assess("assess", noop).Feature(),
+	)
+	_ = env.WithContext(context.Background()).Test(t, features.New("third").SetupOnce("install-crd", install).Assess("assess", noop).Feature())
+	if installs != 1 {
+		t.Errorf("expected the setup to be executed once for the suite, got %d executions", installs)
+	}
+}
+
+func TestEnv_FeatureProfile(t *testing.T) {
+	tests := []struct {
+		name string
+		wrap func(types.Feature) types.Feature
+	}{
+		{
+			name: "profiled feature",
+			wrap: func(f types.Feature) types.Feature { return f },
+		},
+		{
+			name: "profiled feature with leak detection",
+			wrap: func(f types.Feature) types.Feature { return features.WithLeakDetection(f) },
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			f := features.New("profiled feature").WithProfile(dir, dir).Assess("assess", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+				return ctx
+			})
+			cfg := envconf.New().WithClient(testutil.NewFakeClient(interceptor.Funcs{}))
+			_ = NewWithConfig(cfg).Test(t, test.wrap(f.Feature()))
+
+			for _, name := range []string{"profiled_feature.cpu.pprof", "profiled_feature.mem.pprof"} {
+				if info, err := os.Stat(filepath.Join(dir, name)); err != nil || info.Size() == 0 {
+					t.Errorf("expected profile %s to be written: %v", name, err)
+				}
+			}
+		})
+	}
+}
+
+func TestEnv_AssessSerial(t *testing.T) {
+	var running, maxRunning atomic.Int32
+	serial := func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			max := maxRunning.Load()
+			if n <= max || maxRunning.CompareAndSwap(max, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return ctx
+	}
+	var feats []types.Feature
+	for i := 0; i < 4; i++ {
+		feats = append(feats, features.New(fmt.Sprintf("feature-%d", i)).AssessSerial("serial", serial).Feature())
+	}
+	_ = NewParallel().TestInParallel(t, feats...)
+
+	if max := maxRunning.Load(); max != 1 {
+		t.Errorf("expected serial assessments to run one at a time, got %d running concurrently", max)
+	}
+}
+
+func TestEnv_MessageFormatter(t *testing.T) {
+	var formatted []string
+	env := NewWithConfig(envconf.New().WithSkipFeatureRegex("skipped")).WithMessageFormatter(func(kind MessageKind, message string) string {
+		formatted = append(formatted, string(kind))
+		return "[" + string(kind) + "] " + message
+	})
+	skipped := features.New("skipped").Assess("pass", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context { return ctx })
+	_ = env.Test(t, skipped.Feature())
+
+	if fmt.Sprint(formatted) != "[skip]" {
+		t.Errorf("Expected:\n%v but got result:\n%v", "[skip]", formatted)
+	}
+}
+
+func TestEnv_ColorMessages(t *testing.T) {
+	plain := NewWithConfig(envconf.New()).(*testEnv)
+	if msg := plain.formatMessage(MessageFailure, "failed"); msg != "failed" {
+		t.Errorf("expected a plain message by default, got %q", msg)
+	}
+
+	colored := NewWithConfig(envconf.New().WithColor()).(*testEnv)
+	t.Setenv("NO_COLOR", "")
+	if msg := colored.formatMessage(MessageFailure, "failed"); msg != colorRed+"failed"+colorReset {
+		t.Errorf("expected a red failure message, got %q", msg)
+	}
+	if msg := colored.formatMessage(MessageSkip, "skipped"); msg != colorYellow+"skipped"+colorReset {
+		t.Errorf("expected a yellow skip message, got %q", msg)
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if msg := colored.formatMessage(MessageFailure, "failed"); msg != "failed" {
+		t.Errorf("expected NO_COLOR to disable the colors, got %q", msg)
+	}
+}
+
+func TestEnv_FeaturePrecondition(t *testing.T) {
+	env := NewWithConfig(envconf.New())
+	var checked, assessed []string
+	check := func(name string, met bool, err error) features.PreconditionFunc {
+		return func(ctx context.Context, _ *envconf.Config) (bool, error) {
+			checked = append(checked, name)
+			return met, err
+		}
+	}
+	assess := func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+		assessed = append(assessed, t.Name())
+		return ctx
+	}
+	met := features.New("met").WithPrecondition("first", check("met-first", true, nil)).WithPrecondition("second", check("met-second", true, nil)).Assess("assess", assess)
+	unmet := features.New("unmet").WithPrecondition("first", check("unmet-first", false, nil)).WithPrecondition("second", check("unmet-second", true, nil)).Assess("assess", assess)
+	// the preconditions are kept by the leak detection wrapper
+	leakDetected := features.WithLeakDetection(features.New("leak detected").WithPrecondition("first", check("leak-detected-first", false, nil)).Assess("assess", assess).Feature())
+
+	// run the erroring feature in isolation to keep the failure from bubbling up to this test
+	erroring := features.New("erroring").WithPrecondition("first", check("erroring-first", true, fmt.Errorf("unreachable"))).Assess("assess", assess)
+	outcome := testutil.RunIsolated("TestErroring", func(t *testing.T) { _ = env.Test(t, erroring.Feature()) })
+	_ = env.Test(t, met.Feature(), unmet.Feature(), leakDetected)
+
+	if !outcome.Failed {
+		t.Error("expected the feature to fail when a precondition cannot be checked")
+	}
+	expectedChecks := []string{"erroring-first", "met-first", "met-second", "unmet-first", "leak-detected-first"}
+	if fmt.Sprint(checked) != fmt.Sprint(expectedChecks) {
+		t.Errorf("Expected:\n%v but got result:\n%v", expectedChecks, checked)
+	}
+	expectedAssessments := []string{"TestEnv_FeaturePrecondition/met/assess"}
+	if fmt.Sprint(assessed) != fmt.Sprint(expectedAssessments) {
+		t.Errorf("Expected:\n%v but got result:\n%v", expectedAssessments, assessed)
+	}
+
+	// the preconditions, which may need the cluster, are not checked in dry-run mode
+	checked = nil
+	_ = NewWithConfig(envconf.New().WithDryRunMode()).Test(t, unmet.Feature())
+	if len(checked) != 0 {
+		t.Errorf("expected no precondition to be checked in dry-run mode, got %v", checked)
+	}
+}
+
+func TestEnv_AssessmentTimeout(t *testing.T) {
+	env := NewWithConfig(envconf.New().WithDefaultAssessmentTimeout(50 * time.Millisecond))
+	type valueKey struct{}
+	var deadlineKept bool
+	var value any
+	hang := func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+		<-ctx.Done()
+		return context.WithValue(ctx, valueKey{}, "hang")
+	}
+	feat := features.New("timeouts").
+		Assess("default timeout", hang).
+		AssessWithTimeout("own timeout", time.Minute, func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			deadline, ok := ctx.Deadline()
+			deadlineKept = ok && time.Until(deadline) > time.Second
+			value = ctx.Value(valueKey{})
+			return ctx
+		})
+
+	// run the feature in isolation to keep the timeout failure from bubbling up to this test
+	outcome := testutil.RunIsolated("TestTimeouts", func(t *testing.T) { _ = env.Test(t, feat.Feature()) })
+
+	if !outcome.Failed {
+		t.Error("expected the assessment exceeding the default timeout to fail")
+	}
+	if !deadlineKept {
+		t.Error("expected the timeout of the assessment to override the default one")
+	}
+	if value != "hang" {
+		t.Errorf("expected the values of the timed out assessment to be passed down, got %v", value)
+	}
+}
+
+func TestEnv_LogRedactor(t *testing.T) {
+	var formatted []string
+	env := NewWithConfig(envconf.New().WithSkipFeatureRegex("secret")).
+		WithLogRedactor(func(message string) string { return strings.ReplaceAll(message, "secret", "******") }).
+		WithMessageFormatter(func(kind MessageKind, message string) string {
+			formatted = append(formatted, message)
+			return message
+		})
+	var redactor any
+	skipped := features.New("secret").Assess("pass", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context { return ctx })
+	logged := features.New("logged").Assess("log", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+		redactor = ctx.Value(logRedactorKey{})
+		Logf(ctx, t, "token: %s", "secret")
+		return ctx
+	})
+	_ = env.Test(t, skipped.Feature(), logged.Feature())
+
+	if len(formatted) != 1 || strings.Contains(formatted[0], "secret") || !strings.Contains(formatted[0], "******") {
+		t.Errorf("expected the skip message to be redacted, got %v", formatted)
+	}
+	if redactor == nil {
+		t.Error("expected the redactor to be passed down to the steps")
+	}
+}
+
+func TestEnv_AppendCleanup(t *testing.T) {
+	env := NewWithConfig(envconf.New())
+	var executed []string
+	cleanup := func(name string) CleanupFunc {
+		return func(ctx context.Context, _ *envconf.Config) error {
+			executed = append(executed, name)
+			return nil
+		}
+	}
+	feat := features.New("cleanups").
+		Setup(func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			if err := AppendCleanup(ctx, cleanup("setup-cleanup")); err != nil {
+				t.Fatal(err)
+			}
+			return ctx
+		}).
+		Assess("assess", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			if err := AppendCleanup(ctx, cleanup("assess-cleanup")); err != nil {
+				t.Fatal(err)
+			}
+			return ctx
+		}).
+		Teardown(func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			executed = append(executed, "teardown")
+			return ctx
+		})
+	_ = env.Test(t, feat.Feature())
+
+	expected := []string{"teardown", "assess-cleanup", "setup-cleanup"}
+	if fmt.Sprint(executed) != fmt.Sprint(expected) {
+		t.Errorf("Expected:\n%v but got result:\n%v", expected, executed)
+	}
+	if err := AppendCleanup(context.Background(), cleanup("orphan")); err == nil {
+		t.Error("expected an error when registering a cleanup outside of a feature or test suite")
+	}
+}
+
+func TestEnv_AbortFeature(t *testing.T) {
+	env := NewWithConfig(envconf.New())
+	var executed []string
+	record := func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+		executed = append(executed, t.Name())
+		return ctx
+	}
+	feat := features.New("aborted").
+		Assess("abort", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			if err := AbortFeature(ctx, "backend not reachable"); err != nil {
+				t.Fatal(err)
+			}
+			return record(ctx, t, cfg)
+		}).
+		Assess("skipped", record).
+		Teardown(record)
+	other := features.New("other").Assess("assess", record)
+	_ = env.Test(t, feat.Feature(), other.Feature())
+
+	// the aborted feature still runs its teardown, and the next feature is not affected
+	expected := []string{"TestEnv_AbortFeature/aborted/abort", "TestEnv_AbortFeature/aborted", "TestEnv_AbortFeature/other/assess"}
+	if fmt.Sprint(executed) != fmt.Sprint(expected) {
+		t.Errorf("Expected:\n%v but got result:\n%v", expected, executed)
+	}
+	if err := AbortFeature(context.Background(), "orphan"); err == nil {
+		t.Error("expected an error when aborting outside of a feature")
+	}
+}
+
+func TestEnv_ClusterLabels(t *testing.T) {
+	feat := features.New("feature").WithLabel("type", "smoke").Feature()
+	tests := []struct {
+		provider string
+		skip     bool
+	}{
+		{provider: "gce"},
+		{provider: "kind", skip: true},
+	}
+	for _, test := range tests {
+		t.Run(test.provider, func(t *testing.T) {
+			cfg := envconf.New().
+				WithLabels(map[string][]string{"type": {"smoke"}, envconf.KubernetesMinorLabelKey: {"28"}}).
+				WithSkipLabels(map[string][]string{envconf.ClusterProviderLabelKey: {"kind"}}).
+				WithClusterLabel(envconf.ClusterProviderLabelKey, test.provider).
+				WithClusterLabel(envconf.KubernetesMinorLabelKey, "28")
+			env := NewWithConfig(cfg).(*testEnv)
+			if skip, _, message := env.requireFeatureProcessing(feat); skip != test.skip {
+				t.Errorf("expected skip to be %t, got %t: %s", test.skip, skip, message)
+			}
+		})
+	}
+}
+
+func TestEnv_ErrSkip(t *testing.T) {
+	notApplicable := fmt.Errorf("no GPU nodes: %w", ErrSkip)
+	tests := []struct {
+		name string
+		env  func() types.Environment
+	}{
+		{
+			name: "before each test",
+			env: func() types.Environment {
+				return NewWithConfig(envconf.New()).BeforeEachTest(func(ctx context.Context, _ *envconf.Config, _ *testing.T) (context.Context, error) {
+					return ctx, notApplicable
+				})
+			},
+		},
+		{
+			name: "before each feature",
+			env: func() types.Environment {
+				return NewWithConfig(envconf.New()).BeforeEachFeature(func(ctx context.Context, _ *envconf.Config, _ *testing.T, _ types.Feature) (context.Context, error) {
+					return ctx, notApplicable
+				})
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var executed, skipped, failed bool
+			feat := features.New("feature").Assess("assess", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+				executed = true
+				return ctx
+			})
+			t.Run("skip", func(t *testing.T) {
+				defer func() { skipped, failed = t.Skipped(), t.Failed() }()
+				_ = test.env().Test(t, feat.Feature())
+			})
+			if !skipped || failed || executed {
+				t.Errorf("expected the test to be skipped without running the feature, got skipped: %t, failed: %t, executed: %t", skipped, failed, executed)
+			}
+		})
+	}
+}
+
+func TestEnv_ParallelAssessments(t *testing.T) {
+	env := NewWithConfig(envconf.New())
+	type valueKey struct{}
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, name)
+	}
+	// each parallel assessment waits for the others to start, which only completes if they run concurrently
+	var started sync.WaitGroup
+	started.Add(2)
+	parallel := func(name string) features.Func {
+		return func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			started.Done()
+			done := make(chan struct{})
+			go func() {
+				started.Wait()
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(10 * time.Second):
+				t.Fatal("assessments did not run concurrently")
+			}
+			record(name)
+			return context.WithValue(ctx, valueKey{}, name)
+		}
+	}
+	feat := features.New("parallel").
+		WithParallelAssessments().
+		Assess("first", parallel("first")).
+		AssessLast("last", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			record("last")
+			return ctx
+		}).
+		Assess("second", parallel("second")).
+		Teardown(func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			if value := ctx.Value(valueKey{}); value != nil {
+				t.Errorf("expected the values of the parallel assessments to be discarded, got %v", value)
+			}
+			record("teardown")
+			return ctx
+		})
+	_ = env.Test(t, feat.Feature())
+
+	if len(order) != 4 || order[2] != "last" || order[3] != "teardown" {
+		t.Errorf("expected the parallel assessments to run before the last one and the teardown, got: %v", order)
+	}
+}
+
+func TestEnv_CleanupVerification(t *testing.T) {
+	env := NewWithConfig(envconf.New().WithClient(testutil.NewFakeClient(interceptor.Funcs{})))
+	configMap := func(name string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+	}
+	feat := features.New("leaky").
+		WithCleanupVerification().
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			for _, name := range []string{"deleted", "leaked"} {
+				if err := cfg.Client().Resources().Create(ctx, configMap(name)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			return ctx
+		}).
+		Teardown(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			if err := cfg.Client().Resources().Delete(ctx, configMap("deleted")); err != nil {
+				t.Fatal(err)
+			}
+			return ctx
+		})
+
+	// run the leaky feature in isolation to keep the failure from bubbling up to this test
+	outcome := testutil.RunIsolated("TestLeaky", func(t *testing.T) { _ = env.Test(t, feat.Feature()) })
+	if !outcome.Failed {
+		t.Error("expected the feature leaving a created object behind to fail")
+	}
+}
+
+func TestEnv_ConflictingFeatures(t *testing.T) {
+	env := NewWithConfig(envconf.New())
+	var assessed []string
+	assess := func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+		assessed = append(assessed, t.Name())
+		return ctx
+	}
+	leader := features.New("leader").Assess("assess", assess)
+	follower := features.New("follower").WithConflictsWith("leader", "absent").Assess("assess", assess)
+	other := features.New("other").Assess("assess", assess)
+
+	// run the conflicting features in isolation to keep the failure from bubbling up to this test
+	outcome := testutil.RunIsolated("TestConflicts", func(t *testing.T) { _ = env.Test(t, leader.Feature(), follower.Feature()) })
+	_ = env.Test(t, follower.Feature(), other.Feature())
+
+	if !outcome.Failed {
+		t.Error("expected the test of conflicting features to fail")
+	}
+	expected := []string{"TestEnv_ConflictingFeatures/follower/assess", "TestEnv_ConflictingFeatures/other/assess"}
+	if fmt.Sprint(assessed) != fmt.Sprint(expected) {
+		t.Errorf("Expected:\n%v but got result:\n%v", expected, assessed)
+	}
+	conflicts, unknown := featureConflicts([]types.Feature{leader.Feature(), follower.Feature()})
+	if fmt.Sprint(conflicts) != `["leader" <-> "follower"]` {
+		t.Errorf("unexpected conflicts: %v", conflicts)
+	}
+	if fmt.Sprint(unknown) != `["follower" -> "absent"]` {
+		t.Errorf("unexpected conflicts with unknown features: %v", unknown)
+	}
+	// the conflicts are kept by the leak detection wrapper
+	conflicts, _ = featureConflicts([]types.Feature{leader.Feature(), features.WithLeakDetection(follower.Feature())})
+	if fmt.Sprint(conflicts) != `["leader" <-> "follower"]` {
+		t.Errorf("unexpected conflicts with leak detection: %v", conflicts)
+	}
+}
+
+func TestWithDotEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.env")
+	content := "# local toggles\nDOTENV_LOADED=from-file\nexport DOTENV_QUOTED=\"quoted value\"\n\nDOTENV_OVERRIDDEN=from-file\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// registered to restore the variables loaded from the file once the test completes
+	t.Setenv("DOTENV_LOADED", "")
+	t.Setenv("DOTENV_QUOTED", "")
+	os.Unsetenv("DOTENV_LOADED")
+	os.Unsetenv("DOTENV_QUOTED")
+	t.Setenv("DOTENV_OVERRIDDEN", "from-env")
+
+	if err := WithDotEnv(path); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for key, expected := range map[string]string{"DOTENV_LOADED": "from-file", "DOTENV_QUOTED": "quoted value", "DOTENV_OVERRIDDEN": "from-env"} {
+		if value := os.Getenv(key); value != expected {
+			t.Errorf("unexpected value of %s: %q, expected %q", key, value, expected)
+		}
+	}
+
+	if err := WithDotEnv(filepath.Join(t.TempDir(), "missing.env")); err == nil {
+		t.Error("expected an error when the provided file is missing")
+	}
+}
+
+func TestEnv_BuildInfo(t *testing.T) {
+	env := NewWithConfig(envconf.New()).WithBuildInfo("v1.2.3", "abc123").(*testEnv)
+	if version, commit := env.buildInfo(); version != "v1.2.3" || commit != "abc123" {
+		t.Errorf("unexpected build info: %s %s", version, commit)
+	}
+
+	bi := &debug.BuildInfo{Main: debug.Module{Version: "(devel)"}, Settings: []debug.BuildSetting{{Key: "vcs.revision", Value: "def456"}}}
+	if version, commit := detectBuildInfo(bi); version != "" || commit != "def456" {
+		t.Errorf("unexpected detected build info: %q %q", version, commit)
+	}
+}
+
+func TestEnv_TimingMetrics(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "e2e.prom")
+	env := NewWithConfig(envconf.New().WithTimingMetrics(path)).(*testEnv)
+	f := features.New(`say "hello"`).
+		Assess("greet", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			time.Sleep(10 * time.Millisecond)
+			return ctx
+		}).
+		Assess("skip", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			t.Skip("not ready")
+			return ctx
+		})
+	_ = env.Test(t, f.Feature())
+	env.writeTimingMetrics()
+
+	samples, data := readTimingSamples(t, path)
+	name := t.Name() + `/say_\"hello\"`
+	for _, series := range []string{
+		`e2e_feature_duration_seconds{test="` + name + `",feature="say \"hello\"",result="pass"}`,
+		`e2e_assessment_duration_seconds{test="` + name + `/greet",feature="say \"hello\"",assessment="greet",result="pass"}`,
+		`e2e_assessment_duration_seconds{test="` + name + `/skip",feature="say \"hello\"",assessment="skip",result="skip"}`,
+	} {
+		if _, ok := samples[series]; !ok {
+			t.Errorf("missing sample %s in:\n%s", series, data)
+		}
+	}
+	if len(samples) != 3 {
+		t.Errorf("expected 3 samples, got:\n%s", data)
+	}
+	if seconds := samples[`e2e_assessment_duration_seconds{test="`+name+`/greet",feature="say \"hello\"",assessment="greet",result="pass"}`]; seconds < 0.01 {
+		t.Errorf("expected the greet assessment to last at least 10ms, got %vs", seconds)
+	}
+}
+
+// readTimingSamples returns the samples of the timing metrics file at path by series, and its
+// content, failing the test on a duplicate series
+func readTimingSamples(t *testing.T, path string) (map[string]float64, []byte) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples := map[string]float64{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndex(line, " ")
+		seconds, err := strconv.ParseFloat(line[i+1:], 64)
+		if err != nil {
+			t.Fatalf("invalid sample %q: %s", line, err)
+		}
+		if _, ok := samples[line[:i]]; ok {
+			t.Fatalf("duplicate series %s in:\n%s", line[:i], data)
+		}
+		samples[line[:i]] = seconds
+	}
+	return samples, data
+}
+
+func TestEnv_TimingMetrics_RepeatedRuns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "e2e.prom")
+	env := NewWithConfig(envconf.New().WithTimingMetrics(path)).(*testEnv)
+	// the first run fails slowly and the second one passes quickly, as a flaky test run with -count=2
+	run := 0
+	f := features.New("flaky").
+		Assess("check", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			run++
+			if run == 1 {
+				time.Sleep(100 * time.Millisecond)
+				t.Error("not yet")
+			}
+			return ctx
+		}).Feature()
+	for i := 0; i < 2; i++ {
+		_ = testutil.RunIsolated("TestFlaky", func(t *testing.T) { _ = env.Test(t, f) })
+	}
+	env.writeTimingMetrics()
+
+	samples, data := readTimingSamples(t, path)
+	if len(samples) != 2 {
+		t.Fatalf("expected 2 samples, got:\n%s", data)
+	}
+	for _, series := range []string{
+		`e2e_feature_duration_seconds{test="TestFlaky/flaky",feature="flaky",result="pass"}`,
+		`e2e_assessment_duration_seconds{test="TestFlaky/flaky/check",feature="flaky",assessment="check",result="pass"}`,
+	} {
+		seconds, ok := samples[series]
+		if !ok {
+			t.Errorf("missing sample %s in:\n%s", series, data)
+		}
+		if seconds >= 0.1 {
+			t.Errorf("expected the duration of the last run for %s, got %vs", series, seconds)
+		}
+	}
+}
+
+func TestEnv_TestWithContext(t *testing.T) {
+	type key struct{}
+	env := newTestEnv()
+	env.ctx = context.WithValue(env.ctx, key{}, "root")
+	var seen []string
+	record := func(step string, ctx context.Context) {
+		_, hasDeadline := ctx.Deadline()
+		seen = append(seen, fmt.Sprintf("%s:%v:%v", step, ctx.Value(key{}), hasDeadline))
+	}
+	env.BeforeEachTest(func(ctx context.Context, _ *envconf.Config, t *testing.T) (context.Context, error) {
+		record("before", ctx)
+		return ctx, nil
+	}).AfterEachTest(func(ctx context.Context, _ *envconf.Config, t *testing.T) (context.Context, error) {
+		record("after", ctx)
+		return ctx, nil
+	})
+	f := features.New("context").
+		Assess("assess", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			record("assess", ctx)
+			return ctx
+		}).Feature()
+
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), key{}, "call"), time.Minute)
+	defer cancel()
+	_ = env.TestWithContext(ctx, t, f)
+	_ = env.Test(t, f)
+
+	expected := []string{
+		"before:call:true", "assess:call:true", "after:call:true",
+		"before:root:false", "assess:root:false", "after:root:false",
+	}
+	if !reflect.DeepEqual(seen, expected) {
+		t.Errorf("expected:\n%v but got result:\n%v", expected, seen)
+	}
+}
+
+func TestEnv_SetupWithCleanup_FailedSetup(t *testing.T) {
+	var order []string
+	record := func(name string, err error) Func {
+		return func(ctx context.Context, _ *envconf.Config) (context.Context, error) {
+			order = append(order, name)
+			return ctx, err
+		}
+	}
+	env := New().
+		SetupWithCleanup(record("setup-1", nil), record("cleanup-1", nil)).
+		SetupWithCleanup(record("setup-2", errors.New("setup-2 failed")), record("cleanup-2", nil)).
+		SetupWithCleanup(record("setup-3", nil), record("cleanup-3", nil)).(*testEnv)
+
+	exitCode, _, err := env.run(func() int {
+		order = append(order, "tests")
+		return 0
+	}, false)
+	if err != nil {
+		t.Errorf("expected the failed setup to be logged, got %v", err)
+	}
+	if exitCode != 0 {
+		t.Errorf("expected the exit code of the tests, got %d", exitCode)
+	}
+	expected := []string{"setup-1", "setup-2", "tests", "cleanup-1"}
+	if !reflect.DeepEqual(order, expected) {
+		t.Errorf("Expected:\n%v but got result:\n%v", expected, order)
+	}
+}
+
+func TestEnv_RunE(t *testing.T) {
+	var order []string
+	record := func(name string, err error) Func {
+		return func(ctx context.Context, _ *envconf.Config) (context.Context, error) {
+			order = append(order, name)
+			return ctx, err
+		}
+	}
+	tests := []struct {
+		name         string
+		env          types.Environment
+		expectedErr  string
+		expectedCode int
+		expected     []string
+	}{
+		{
+			name: "failed setup",
+			env: New().
+				SetupWithCleanup(record("setup-1", nil), record("cleanup-1", nil)).
+				SetupWithCleanup(record("setup-2", errors.New("setup-2 failed")), record("cleanup-2", nil)).
+				SetupWithCleanup(record("setup-3", nil), record("cleanup-3", nil)).
+				Finish(record("finish", nil)),
+			expectedErr:  "setup-2 failed",
+			expectedCode: 1,
+			expected:     []string{"setup-1", "setup-2", "finish", "cleanup-1"},
+		},
+		{
+			name:     "skipped suite",
+			env:      New().Setup(record("setup", ErrSkip)).Finish(record("finish", nil)),
+			expected: []string{"setup", "finish"},
+		},
+		{
+			name:         "invalid configuration",
+			env:          NewWithConfig(envconf.New().WithShard(2, 2)).Setup(record("setup", nil)).Finish(record("finish", nil)),
+			expectedErr:  "invalid environment configuration",
+			expectedCode: 1,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			order = nil
+			// the tests are not run in these cases, so no testing.M is needed
+			exitCode, err := test.env.RunE(nil)
+			if test.expectedErr == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if test.expectedErr != "" && (err == nil || !strings.Contains(err.Error(), test.expectedErr)) {
+				t.Errorf("expected error containing %q, got %v", test.expectedErr, err)
+			}
+			if exitCode != test.expectedCode {
+				t.Errorf("expected exit code %d, got %d", test.expectedCode, exitCode)
+			}
+			if !reflect.DeepEqual(order, test.expected) {
+				t.Errorf("Expected:\n%v but got result:\n%v", test.expected, order)
+			}
+		})
+	}
+}
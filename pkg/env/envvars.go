@@ -0,0 +1,68 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"os"
+	"sync"
+
+	"sigs.k8s.io/e2e-framework/pkg/types"
+)
+
+// featureEnvMu keeps the features setting environment variables from running concurrently
+var featureEnvMu sync.Mutex
+
+// featureEnvVars returns the environment variables set while the feature runs, if any
+func featureEnvVars(f types.Feature) map[string]string {
+	if ef, ok := f.(types.EnvVarsFeature); ok {
+		return ef.EnvVars()
+	}
+	return nil
+}
+
+// setFeatureEnvVars sets the environment variables of the feature and returns a function
+// restoring their previous values. Upon error, the variables already set are restored.
+func setFeatureEnvVars(f types.Feature) (restore func(), err error) {
+	vars := featureEnvVars(f)
+	if len(vars) == 0 {
+		return func() {}, nil
+	}
+	featureEnvMu.Lock()
+	var restores []func()
+	restore = func() {
+		for i := len(restores) - 1; i >= 0; i-- {
+			restores[i]()
+		}
+		featureEnvMu.Unlock()
+	}
+	for name, value := range vars {
+		previous, found := os.LookupEnv(name)
+		if err := os.Setenv(name, value); err != nil {
+			restore()
+			return nil, err
+		}
+		name := name
+		restores = append(restores, func() {
+			if found {
+				_ = os.Setenv(name, previous)
+			} else {
+				_ = os.Unsetenv(name)
+			}
+		})
+	}
+	return restore, nil
+}
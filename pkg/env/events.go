@@ -0,0 +1,266 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	klog "k8s.io/klog/v2"
+
+	"sigs.k8s.io/e2e-framework/pkg/types"
+)
+
+type eventKind uint8
+
+const (
+	eventFeatureSkipped eventKind = iota
+	eventFeatureFailed
+	eventTestFailed
+	eventAssessmentAttributes
+	eventTiming
+)
+
+// event records something noteworthy that happened while processing
+// the features of an environment.
+type event struct {
+	kind        eventKind
+	test        string
+	feature     string
+	assessment  string
+	message     string
+	quarantined bool
+	metadata    map[string]any
+	// result and elapsed are the outcome and duration of the feature, or assessment, of an eventTiming
+	result  string
+	elapsed time.Duration
+}
+
+// eventStream collects the events recorded during a test run so that
+// they can be aggregated once the test suite completes. It is safe for
+// concurrent use as features can be processed in parallel.
+type eventStream struct {
+	mu     sync.Mutex
+	events []event
+	stats  types.RunStats
+	seen   map[string]struct{}
+}
+
+func (s *eventStream) record(ev event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, ev)
+}
+
+// markSeen records that a feature with the given name was part of the test suite
+func (s *eventStream) markSeen(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seen == nil {
+		s.seen = make(map[string]struct{})
+	}
+	s.seen[name] = struct{}{}
+}
+
+// unseen returns the names, in their original order, of the features that were never
+// part of the test suite
+func (s *eventStream) unseen(names []string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var result []string
+	for _, name := range names {
+		if _, ok := s.seen[name]; !ok {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// count updates the statistics of the run
+func (s *eventStream) count(fn func(stats *types.RunStats)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&s.stats)
+}
+
+// runStats returns a snapshot of the statistics of the run
+func (s *eventStream) runStats() types.RunStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// countAssessment updates the statistics of the run with the outcome of the assessment
+func (s *eventStream) countAssessment(t *testing.T) {
+	s.count(func(stats *types.RunStats) {
+		switch {
+		case t.Skipped():
+			stats.AssessmentsSkipped++
+		case t.Failed():
+			stats.AssessmentsRun++
+			stats.AssessmentsFailed++
+		default:
+			stats.AssessmentsRun++
+		}
+	})
+}
+
+// byKind returns a snapshot of the recorded events of the given kind
+func (s *eventStream) byKind(kind eventKind) []event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var result []event
+	for _, ev := range s.events {
+		if ev.kind == kind {
+			result = append(result, ev)
+		}
+	}
+	return result
+}
+
+// failedFeatures maps the names of the features that failed to their formatted, redacted metadata
+func (s *eventStream) failedFeatures(redact func(string) string) map[string]string {
+	failures := make(map[string]string)
+	for _, ev := range s.byKind(eventFeatureFailed) {
+		failures[ev.feature] = redact(formatMetadata(ev.metadata))
+	}
+	return failures
+}
+
+// failures returns the number of failures caused by quarantined features and
+// the number of any other failures recorded
+func (s *eventStream) failures() (quarantined, other int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ev := range s.events {
+		switch {
+		case ev.kind == eventFeatureFailed && ev.quarantined:
+			quarantined++
+		case ev.kind == eventFeatureFailed || ev.kind == eventTestFailed:
+			other++
+		}
+	}
+	return quarantined, other
+}
+
+// onlyQuarantinedFailures returns true if failures have been recorded for the
+// named test and all of them were caused by quarantined features
+func (s *eventStream) onlyQuarantinedFailures(test string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	found := false
+	for _, ev := range s.events {
+		if ev.test != test || (ev.kind != eventFeatureFailed && ev.kind != eventTestFailed) {
+			continue
+		}
+		if !ev.quarantined {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+// printSummary logs the aggregated view of the recorded events, the messages being redacted with redact
+func (s *eventStream) printSummary(redact func(string) string) {
+	if skipped := s.byKind(eventFeatureSkipped); len(skipped) > 0 {
+		for _, ev := range skipped {
+			klog.V(4).Info(redact(ev.message))
+			if len(ev.metadata) > 0 {
+				klog.V(4).Info(redact(fmt.Sprintf("Feature %q skipped, metadata: %s", ev.feature, formatMetadata(ev.metadata))))
+			}
+		}
+		klog.Infof("Skipped %d feature(s) not selected for the run", len(skipped))
+	}
+	for _, ev := range s.byKind(eventFeatureFailed) {
+		if len(ev.metadata) > 0 {
+			klog.Info(redact(fmt.Sprintf("Feature %q failed, metadata: %s", ev.feature, formatMetadata(ev.metadata))))
+		}
+	}
+	var quarantined []string
+	for _, ev := range s.byKind(eventFeatureFailed) {
+		if ev.quarantined {
+			quarantined = append(quarantined, ev.feature)
+		}
+	}
+	if len(quarantined) > 0 {
+		klog.Warningf("%d quarantined feature(s) failed without failing the test suite: %s", len(quarantined), strings.Join(quarantined, ", "))
+	}
+	for _, ev := range s.byKind(eventAssessmentAttributes) {
+		klog.Info(redact(fmt.Sprintf("Assessment %q of feature %q recorded attributes: %s", ev.assessment, ev.feature, formatMetadata(ev.metadata))))
+	}
+}
+
+// formatMetadata formats the metadata of a feature, or the attributes of an assessment, as key=value pairs sorted by key
+func formatMetadata(metadata map[string]any) string {
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", k, metadata[k]))
+	}
+	return strings.Join(pairs, " ")
+}
+
+// assessmentEventPrefix prefixes the assessment events logged on the test
+// output so that they can be told apart from the rest of the output
+const assessmentEventPrefix = "e2e-framework/assessment-event:"
+
+// assessmentEvent is logged as JSON at the start and end of each assessment
+// when assessment events are enabled. The field names follow the ones used by
+// go test -json so that they can be processed the same way.
+type assessmentEvent struct {
+	Action     string
+	Feature    string
+	Assessment string
+	Elapsed    float64        `json:",omitempty"`
+	Attributes map[string]any `json:",omitempty"`
+}
+
+// logf is implemented by *testing.T
+type logf interface {
+	Logf(format string, args ...any)
+}
+
+func logAssessmentEvent(t logf, ev assessmentEvent) {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		klog.ErrorS(err, "Failed to encode assessment event")
+		return
+	}
+	t.Logf("%s %s", assessmentEventPrefix, data)
+}
+
+// assessmentResult returns the go test -json action matching the outcome of the assessment
+func assessmentResult(t *testing.T) string {
+	switch {
+	case t.Failed():
+		return "fail"
+	case t.Skipped():
+		return "skip"
+	default:
+		return "pass"
+	}
+}
@@ -0,0 +1,205 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	klog "k8s.io/klog/v2"
+
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/types"
+)
+
+// fixturesKey is the context key of the values of the fixtures requested by the feature being executed
+type fixturesKey struct{}
+
+// RegisterFixture registers a fixture, such as a database or a mock server, that the features
+// request by name with features.FeatureBuilder.WithFixture. The fixture is set up right before
+// the first feature requesting it, after its preconditions are met, and is shared by the other
+// features of the same Test or TestInParallel call requesting it: it is torn down once the last
+// of them completed, after its teardown steps. The features of a later call set it up again.
+//
+// The value returned by setup is retrieved by the steps with Fixture, and passed to teardown,
+// which may be nil. A feature fails when the setup of one of its fixtures fails, the setup being
+// attempted again by the next feature requesting it. Registering a fixture again replaces it.
+func (e *testEnv) RegisterFixture(name string, setup types.FixtureSetupFunc, teardown types.FixtureTeardownFunc) types.Environment {
+	e.fixtures.register(&fixture{name: name, setup: setup, teardown: teardown})
+	return e
+}
+
+// Fixture returns the value of the fixture requested by the feature running with the context ctx,
+// see RegisterFixture. It returns false when the feature did not request the fixture.
+func Fixture(ctx context.Context, name string) (any, bool) {
+	values, _ := ctx.Value(fixturesKey{}).(map[string]any)
+	value, ok := values[name]
+	return value, ok
+}
+
+// featureFixtures returns the names of the fixtures requested by the feature, if any
+func featureFixtures(f types.Feature) []string {
+	if ff, ok := f.(types.FixtureFeature); ok {
+		return ff.Fixtures()
+	}
+	return nil
+}
+
+// fixtureRegistry holds the fixtures registered with RegisterFixture. It is shared by the
+// environments cloned from one another.
+type fixtureRegistry struct {
+	mu       sync.Mutex
+	fixtures map[string]*fixture
+}
+
+func (r *fixtureRegistry) register(f *fixture) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fixtures == nil {
+		r.fixtures = make(map[string]*fixture)
+	}
+	r.fixtures[f.name] = f
+}
+
+func (r *fixtureRegistry) get(name string) (*fixture, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f, ok := r.fixtures[name]
+	return f, ok
+}
+
+// fixture is a fixture registered with RegisterFixture, set up while features requesting it remain to complete
+type fixture struct {
+	name     string
+	setup    types.FixtureSetupFunc
+	teardown types.FixtureTeardownFunc
+
+	// mu is held while the fixture is set up or torn down so that concurrent features wait for it
+	mu sync.Mutex
+	// users is the number of features requesting the fixture that have not completed yet
+	users int
+	ready bool
+	value any
+}
+
+// acquire sets up the fixture unless it is already set up, and returns its value
+func (f *fixture) acquire(ctx context.Context, cfg *envconf.Config) (any, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.ready {
+		return f.value, nil
+	}
+	value, err := f.setup(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	f.ready, f.value = true, value
+	return value, nil
+}
+
+// release records that a feature requesting the fixture completed, and tears the fixture down
+// once it was the last one, unless keep is true
+func (f *fixture) release(ctx context.Context, cfg *envconf.Config, keep bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.users--
+	if f.users > 0 || !f.ready {
+		return nil
+	}
+	value := f.value
+	f.ready, f.value = false, nil
+	if keep {
+		klog.Warningf("Skipping the teardown of fixture %q: %s is set, its resources are kept", f.name, cfg.KeepEnv())
+		return nil
+	}
+	if f.teardown == nil {
+		return nil
+	}
+	return f.teardown(ctx, cfg, value)
+}
+
+// fixtureUsers tracks the fixtures requested by the features of a Test or TestInParallel call,
+// by position of the features, until the features complete
+type fixtureUsers struct {
+	mu        sync.Mutex
+	byFeature map[int][]*fixture
+}
+
+// reserveFixtures records the features requesting each registered fixture, so that the fixtures
+// are only torn down once the last of them completed. The fixtures that are not registered are
+// reported by the features requesting them.
+func (e *testEnv) reserveFixtures(testFeatures []types.Feature) *fixtureUsers {
+	users := &fixtureUsers{byFeature: make(map[int][]*fixture)}
+	for i, feature := range testFeatures {
+		for _, name := range featureFixtures(feature) {
+			f, ok := e.fixtures.get(name)
+			if !ok {
+				continue
+			}
+			f.mu.Lock()
+			f.users++
+			f.mu.Unlock()
+			users.byFeature[i] = append(users.byFeature[i], f)
+		}
+	}
+	return users
+}
+
+// releaseFixtures releases the fixtures requested by the feature at position i, once it completed,
+// or by all the features that did not complete when i is negative, e.g. when the test failed fast
+func (e *testEnv) releaseFixtures(ctx context.Context, t *testing.T, users *fixtureUsers, i int) {
+	users.mu.Lock()
+	var fixtures []*fixture
+	if i >= 0 {
+		fixtures = users.byFeature[i]
+		delete(users.byFeature, i)
+	} else {
+		for j, f := range users.byFeature {
+			fixtures = append(fixtures, f...)
+			delete(users.byFeature, j)
+		}
+	}
+	users.mu.Unlock()
+	for _, f := range fixtures {
+		if err := f.release(context.WithoutCancel(ctx), e.cfg, e.cfg.KeepResources()); err != nil {
+			e.errorf(t, "Fixture %q teardown failure: %s", f.name, err)
+		}
+	}
+}
+
+// acquireFixtures sets up the fixtures requested by the feature, unless already set up, and returns
+// the context of the feature carrying their values
+func (e *testEnv) acquireFixtures(ctx context.Context, t *testing.T, featName string, f types.Feature) context.Context {
+	names := featureFixtures(f)
+	if len(names) == 0 || e.cfg.DryRunMode() {
+		return ctx
+	}
+	values := make(map[string]any, len(names))
+	for _, name := range names {
+		fix, ok := e.fixtures.get(name)
+		if !ok {
+			e.fatalf(t, "Feature %q requests fixture %q, which is not registered", featName, name)
+		}
+		value, err := fix.acquire(context.WithoutCancel(ctx), e.cfg)
+		if err != nil {
+			e.fatalf(t, "Feature %q fixture %q setup failure: %s", featName, name, err)
+		}
+		values[name] = value
+	}
+	return context.WithValue(ctx, fixturesKey{}, values)
+}
@@ -0,0 +1,38 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"fmt"
+
+	klog "k8s.io/klog/v2"
+)
+
+// logKeptResources logs that the Finish operations and the cleanups of the test suite were skipped
+// because of the environment variable set with envconf.Config.WithKeepEnv, and how to clean up
+func (e *testEnv) logKeptResources() {
+	name := e.cfg.KeepEnv()
+	msg := fmt.Sprintf("%s is set: the teardowns, the Finish operations and the cleanups were skipped, the resources of the run are kept. "+
+		"Once inspected, delete them manually", name)
+	if id := RunID(e.ctx); id != "" {
+		msg += fmt.Sprintf(", e.g. the namespaces of the run with kubectl delete namespace -l %s=%s", RunIDLabelKey, id)
+	}
+	if manifest := e.cfg.ResourceManifest(); manifest != "" {
+		msg += fmt.Sprintf(", the objects created by the run being listed in %s", manifest)
+	}
+	klog.Warning(e.redact(msg))
+}
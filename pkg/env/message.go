@@ -0,0 +1,125 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"sigs.k8s.io/e2e-framework/pkg/types"
+)
+
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorYellow = "\033[33m"
+)
+
+// WithMessageFormatter registers a formatter of the skip and fatal failure messages reported by
+// the environment on the tests, e.g. to make them easier to scan in the output of large runs.
+// The formatter takes precedence over the colors enabled with envconf.Config.WithColor.
+func (e *testEnv) WithMessageFormatter(formatter types.MessageFormatter) types.Environment {
+	e.formatter = formatter
+	return e
+}
+
+// WithLogRedactor registers a redactor rewriting the messages emitted by the environment before
+// they are output, e.g. to mask secrets read from the cluster in world-readable CI logs. The
+// redactor applies to the skip reasons, failures and summaries reported by the framework, as
+// well as to the messages logged by steps with Logf. Messages logged by steps directly with
+// t.Log or t.Error bypass the redactor.
+func (e *testEnv) WithLogRedactor(redactor types.LogRedactor) types.Environment {
+	e.redactor = redactor
+	return e
+}
+
+// logRedactorKey is the context key of the redactor of the environment executing a feature
+type logRedactorKey struct{}
+
+// Logf formats its arguments like fmt.Sprintf and records the text in the log of the step,
+// redacted with the redactor of the environment executing the feature, if any (see WithLogRedactor).
+func Logf(ctx context.Context, t *testing.T, format string, args ...any) {
+	t.Helper()
+	message := fmt.Sprintf(format, args...)
+	if redactor, ok := ctx.Value(logRedactorKey{}).(types.LogRedactor); ok {
+		message = redactor(message)
+	}
+	t.Log(message)
+}
+
+// ColorMessageFormatter is the MessageFormatter coloring the skip messages in yellow and
+// the failure messages in red
+func ColorMessageFormatter(kind MessageKind, message string) string {
+	switch kind {
+	case MessageSkip:
+		return colorYellow + message + colorReset
+	case MessageFailure:
+		return colorRed + message + colorReset
+	default:
+		return message
+	}
+}
+
+// redact rewrites the message with the redactor of the environment, if any
+func (e *testEnv) redact(message string) string {
+	if e.redactor == nil {
+		return message
+	}
+	return e.redactor(message)
+}
+
+// redactError returns an error whose message is redacted with the redactor of the environment, if any
+func (e *testEnv) redactError(err error) error {
+	if e.redactor == nil {
+		return err
+	}
+	return errors.New(e.redactor(err.Error()))
+}
+
+// formatMessage redacts the message and formats it with the formatter of the environment, if any,
+// or colors it if requested by the config and not disabled by the NO_COLOR environment variable
+func (e *testEnv) formatMessage(kind MessageKind, message string) string {
+	message = e.redact(message)
+	if e.formatter != nil {
+		return e.formatter(kind, message)
+	}
+	if e.cfg.Color() && os.Getenv("NO_COLOR") == "" {
+		return ColorMessageFormatter(kind, message)
+	}
+	return message
+}
+
+// skipf skips the test with a formatted skip message
+func (e *testEnv) skipf(t *testing.T, format string, args ...any) {
+	t.Helper()
+	t.Skip(e.formatMessage(MessageSkip, fmt.Sprintf(format, args...)))
+}
+
+// errorf marks the test as failed with a redacted failure message
+func (e *testEnv) errorf(t *testing.T, format string, args ...any) {
+	t.Helper()
+	t.Error(e.redact(fmt.Sprintf(format, args...)))
+}
+
+// fatalf fails the test with a formatted failure message
+func (e *testEnv) fatalf(t *testing.T, format string, args ...any) {
+	t.Helper()
+	t.Fatal(e.formatMessage(MessageFailure, fmt.Sprintf(format, args...)))
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// onceStoreKey is the context key of the onceStore of the feature being executed
+type onceStoreKey struct{}
+
+// onceStore memoizes the values computed with Once during the execution of a feature
+type onceStore struct {
+	mu      sync.Mutex
+	entries map[string]*onceEntry
+}
+
+type onceEntry struct {
+	once  sync.Once
+	value any
+	err   error
+}
+
+func (s *onceStore) entry(key string) *onceEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.entries == nil {
+		s.entries = make(map[string]*onceEntry)
+	}
+	e, ok := s.entries[key]
+	if !ok {
+		e = &onceEntry{}
+		s.entries[key] = e
+	}
+	return e
+}
+
+// suiteOnceSteps tracks the setup steps declared with features.SetupOnce that were executed
+// successfully during the test suite. It is shared by the environments cloned from one another.
+type suiteOnceSteps struct {
+	mu    sync.Mutex
+	steps map[string]*suiteOnceStep
+}
+
+type suiteOnceStep struct {
+	// mu is held while the step is executed so that concurrent features wait for its outcome
+	mu   sync.Mutex
+	done bool
+}
+
+func (s *suiteOnceSteps) step(key string) *suiteOnceStep {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.steps == nil {
+		s.steps = make(map[string]*suiteOnceStep)
+	}
+	step, ok := s.steps[key]
+	if !ok {
+		step = &suiteOnceStep{}
+		s.steps[key] = step
+	}
+	return step
+}
+
+// Once calls fn the first time it is invoked with key during the execution of a feature and
+// returns the value and error returned by fn, which are memoized and returned as is by the next
+// invocations with the same key. This lets the steps of a feature share the result of an expensive
+// computation that is only performed if a step needs it.
+//
+// The memoized values are scoped to a single execution of a feature: they are shared by the steps of
+// the feature, but not by other features nor by another execution of the same feature. It is safe to
+// call Once concurrently: fn is called at most once per key, the other callers waiting for it to
+// complete. An error is returned if ctx is not the context of a feature step.
+func Once(ctx context.Context, key string, fn func() (any, error)) (any, error) {
+	store, ok := ctx.Value(onceStoreKey{}).(*onceStore)
+	if !ok {
+		return nil, fmt.Errorf("once %q: the context is not the context of a feature step", key)
+	}
+	e := store.entry(key)
+	e.once.Do(func() {
+		e.value, e.err = fn()
+	})
+	return e.value, e.err
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/pprof"
+
+	klog "k8s.io/klog/v2"
+
+	"sigs.k8s.io/e2e-framework/pkg/types"
+)
+
+// profileFileNameRegex matches the characters of a feature name that are replaced in the
+// name of its profile files
+var profileFileNameRegex = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// profileFeature starts the CPU profile of the feature, if requested, and returns a
+// function that stops it and writes the heap profile of the feature, if requested.
+// Profiling errors are logged and do not fail the feature.
+func profileFeature(featName string, f types.Feature) (stop func()) {
+	pf, ok := f.(types.ProfiledFeature)
+	if !ok {
+		return func() {}
+	}
+	cpuDir, memDir := pf.Profile()
+	fileName := profileFileNameRegex.ReplaceAllString(featName, "_")
+
+	var cpuFile *os.File
+	if cpuDir != "" {
+		var err error
+		if cpuFile, err = startCPUProfile(filepath.Join(cpuDir, fileName+".cpu.pprof")); err != nil {
+			klog.ErrorS(err, "Failed to start the CPU profile of the feature", "feature", featName)
+		}
+	}
+	return func() {
+		if cpuFile != nil {
+			pprof.StopCPUProfile()
+			if err := cpuFile.Close(); err != nil {
+				klog.ErrorS(err, "Failed to write the CPU profile of the feature", "feature", featName)
+			}
+		}
+		if memDir != "" {
+			if err := writeHeapProfile(filepath.Join(memDir, fileName+".mem.pprof")); err != nil {
+				klog.ErrorS(err, "Failed to write the heap profile of the feature", "feature", featName)
+			}
+		}
+	}
+}
+
+func startCPUProfile(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := pprof.StartCPUProfile(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("start CPU profile: %w", err)
+	}
+	return file, nil
+}
+
+func writeHeapProfile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	// get up-to-date statistics
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(file); err != nil {
+		return err
+	}
+	return file.Close()
+}
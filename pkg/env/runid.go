@@ -0,0 +1,58 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"context"
+
+	klog "k8s.io/klog/v2"
+
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/types"
+)
+
+// RunIDLabelKey is the label key set, with the run ID of the environment as value, on the
+// namespaces created by the framework helpers such as envfuncs.CreateNamespace. It allows
+// correlating the resources, and the logs of the controllers handling them, with a test run.
+const RunIDLabelKey = "e2e-framework.sigs.k8s.io/run-id"
+
+// runIDContextKey is the key of the run ID stored in the context of the environment
+type runIDContextKey struct{}
+
+// WithRunID sets a run-scoped correlation ID into the context of the environment, from which it
+// can be retrieved with RunID. A random ID is generated with the name generator of the environment
+// config (see envconf.Config.GenerateName) when id is empty. As the ID is stored in the context, it
+// must be set before the environment runs, e.g. right after creating it.
+func (e *testEnv) WithRunID(id string) types.Environment {
+	if id == "" {
+		generated, err := e.cfg.GenerateName("run")
+		if err != nil {
+			klog.ErrorS(err, "Failed to generate the run ID, using a random one")
+			generated = envconf.RandomName("run", 32)
+		}
+		id = generated
+	}
+	e.ctx = context.WithValue(e.ctx, runIDContextKey{}, id)
+	return e
+}
+
+// RunID returns the run ID set with WithRunID into the context of the environment, or an
+// empty string if no run ID was set
+func RunID(ctx context.Context) string {
+	id, _ := ctx.Value(runIDContextKey{}).(string)
+	return id
+}
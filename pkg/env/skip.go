@@ -0,0 +1,50 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"errors"
+)
+
+// ErrSkip can be returned, possibly wrapped, by an environment operation to declare that the tests
+// are not applicable rather than failing them, e.g. when the cluster lacks a capability:
+//
+//	return ctx, fmt.Errorf("no GPU nodes: %w", env.ErrSkip)
+//
+// It is recognized as follows:
+//
+//   - from a Setup operation of Run: the tests are not run, the Finish operations are, and the
+//     suite exits with a zero code
+//   - from a BeforeEachTest, BeforeEachFeature or suite setup operation: the test is skipped,
+//     along with its features that did not run yet
+//   - from an AfterEachTest or AfterEachFeature operation: the test is marked as skipped, unless
+//     it already failed
+//
+// The error is logged in all cases.
+var ErrSkip = errors.New("skipped")
+
+// Sources of the skips reported to the handler registered with WithSkipHandler
+const (
+	skipSourceRegex        = "regex"
+	skipSourceLabel        = "label"
+	skipSourceRerun        = "rerun"
+	skipSourceFeaturesFile = "features-file"
+	skipSourceShard        = "shard"
+	skipSourceVersion      = "version"
+	skipSourcePrecondition = "precondition"
+	skipSourceAbort        = "abort"
+)
@@ -0,0 +1,149 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package env
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	klog "k8s.io/klog/v2"
+)
+
+// recordTiming records the duration of the feature, or assessment, run as the test t
+// since start when the timing metrics are enabled, see envconf.Config.WithTimingMetrics
+func (e *testEnv) recordTiming(t *testing.T, featName, assessName string, start time.Time) {
+	e.events.record(event{
+		kind:       eventTiming,
+		test:       t.Name(),
+		feature:    featName,
+		assessment: assessName,
+		result:     assessmentResult(t),
+		elapsed:    time.Since(start),
+	})
+}
+
+// writeTimingMetrics writes the durations of the features and assessments of the run to the
+// timing metrics file, if any
+func (e *testEnv) writeTimingMetrics() {
+	path := e.cfg.TimingMetrics()
+	if path == "" {
+		return
+	}
+	if err := writeTimingMetrics(path, e.events.byKind(eventTiming)); err != nil {
+		klog.ErrorS(e.redactError(err), "Failed to write the timing metrics", "path", path)
+	}
+}
+
+// writeTimingMetrics writes the timings to the file at path in the Prometheus text exposition
+// format, e.g.
+//
+//	# HELP e2e_feature_duration_seconds Duration of the features of the test suite, including their setup and teardown.
+//	# TYPE e2e_feature_duration_seconds gauge
+//	e2e_feature_duration_seconds{test="TestPods/create",feature="create",result="pass"} 3.2
+//	# HELP e2e_assessment_duration_seconds Duration of the assessments of the features of the test suite.
+//	# TYPE e2e_assessment_duration_seconds gauge
+//	e2e_assessment_duration_seconds{test="TestPods/create/ready",feature="create",assessment="ready",result="pass"} 2.9
+//
+// The test label is the name of the Go subtest running the feature, or assessment, which
+// tells apart the features run by several tests. The result is pass, fail or skip. The file
+// is written to a temporary file renamed once complete, so that the textfile collector of
+// the node exporter does not read a partial file.
+//
+// A feature, or assessment, run several times by the same test, e.g. with go test -count or by
+// the test suites calling testing.RunTests repeatedly, is written once with the duration and
+// the result of its last run, so that the file has no duplicate series.
+func writeTimingMetrics(path string, timings []event) error {
+	timings = lastTimings(timings)
+	sort.SliceStable(timings, func(i, j int) bool {
+		return timings[i].test < timings[j].test
+	})
+	var buf bytes.Buffer
+	buf.WriteString("# HELP e2e_feature_duration_seconds Duration of the features of the test suite, including their setup and teardown.\n")
+	buf.WriteString("# TYPE e2e_feature_duration_seconds gauge\n")
+	for _, ev := range timings {
+		if ev.assessment == "" {
+			writeTimingSample(&buf, "e2e_feature_duration_seconds", ev, "test", ev.test, "feature", ev.feature, "result", ev.result)
+		}
+	}
+	buf.WriteString("# HELP e2e_assessment_duration_seconds Duration of the assessments of the features of the test suite.\n")
+	buf.WriteString("# TYPE e2e_assessment_duration_seconds gauge\n")
+	for _, ev := range timings {
+		if ev.assessment != "" {
+			writeTimingSample(&buf, "e2e_assessment_duration_seconds", ev, "test", ev.test, "feature", ev.feature, "assessment", ev.assessment, "result", ev.result)
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("timing metrics: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("timing metrics: %w", err)
+	}
+	// the permissions of os.CreateTemp would prevent the collector from reading the file
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("timing metrics: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("timing metrics: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("timing metrics: %w", err)
+	}
+	return nil
+}
+
+// lastTimings returns the last timing of each feature and assessment of each test, in the order
+// of their first run
+func lastTimings(timings []event) []event {
+	type series struct{ test, feature, assessment string }
+	index := make(map[series]int, len(timings))
+	var result []event
+	for _, ev := range timings {
+		key := series{test: ev.test, feature: ev.feature, assessment: ev.assessment}
+		if i, ok := index[key]; ok {
+			result[i] = ev
+			continue
+		}
+		index[key] = len(result)
+		result = append(result, ev)
+	}
+	return result
+}
+
+// labelValueEscaper escapes the label values as required by the text exposition format
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writeTimingSample writes the duration of the timing as a sample of the metric with the labels,
+// given as name and value pairs
+func writeTimingSample(buf *bytes.Buffer, metric string, timing event, labels ...string) {
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, labels[i], labelValueEscaper.Replace(labels[i+1])))
+	}
+	fmt.Fprintf(buf, "%s{%s} %s\n", metric, strings.Join(pairs, ","), strconv.FormatFloat(timing.elapsed.Seconds(), 'f', -1, 64))
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envconf
+
+import (
+	"fmt"
+
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/e2e-framework/klient"
+	"sigs.k8s.io/e2e-framework/klient/conf"
+)
+
+// RestConfigFunc customizes the *rest.Config of a cluster before its client
+// is created. It can be used to set up cluster specific authentication such
+// as a bearer token or a custom transport wrapper.
+type RestConfigFunc func(*rest.Config) (*rest.Config, error)
+
+// ClientFactory creates the klient.Client of a cluster from its *rest.Config
+type ClientFactory func(*rest.Config) (klient.Client, error)
+
+// clusterConfig stores how the client of a named cluster is created
+type clusterConfig struct {
+	kubeconfig      string
+	restConfigFuncs []RestConfigFunc
+	clientFactory   ClientFactory
+	client          klient.Client
+}
+
+// cluster returns the configuration of the named cluster, registering it if needed.
+// Any previously created client is discarded as the configuration is about to change.
+// It must be called with clustersMu held.
+func (c *Config) cluster(name string) *clusterConfig {
+	if c.clusters == nil {
+		c.clusters = make(map[string]*clusterConfig)
+	}
+	cluster, ok := c.clusters[name]
+	if !ok {
+		cluster = &clusterConfig{}
+		c.clusters[name] = cluster
+	}
+	cluster.client = nil
+	return cluster
+}
+
+// WithCluster registers a named cluster reachable with the provided kubeconfig file.
+// The client of the cluster is obtained with ClusterClient.
+func (c *Config) WithCluster(name, kubeconfig string) *Config {
+	c.clustersMu.Lock()
+	defer c.clustersMu.Unlock()
+	c.cluster(name).kubeconfig = kubeconfig
+	return c
+}
+
+// WithClusterRestConfigFunc adds a customization applied to the *rest.Config of the
+// named cluster before its client is created. Customizations are applied in the
+// order they were added.
+func (c *Config) WithClusterRestConfigFunc(name string, fn RestConfigFunc) *Config {
+	c.clustersMu.Lock()
+	defer c.clustersMu.Unlock()
+	cluster := c.cluster(name)
+	cluster.restConfigFuncs = append(cluster.restConfigFuncs, fn)
+	return c
+}
+
+// WithClusterClientFactory sets the factory used to create the client of the named
+// cluster. By default, klient.New is used.
+func (c *Config) WithClusterClientFactory(name string, factory ClientFactory) *Config {
+	c.clustersMu.Lock()
+	defer c.clustersMu.Unlock()
+	c.cluster(name).clientFactory = factory
+	return c
+}
+
+// ClusterClient returns the client of the named cluster, creating it on first use by
+// applying the customizations registered for that cluster. It returns an error if the
+// cluster has not been registered or if the client cannot be created.
+func (c *Config) ClusterClient(name string) (klient.Client, error) {
+	c.clustersMu.Lock()
+	defer c.clustersMu.Unlock()
+	cluster, ok := c.clusters[name]
+	if !ok {
+		return nil, fmt.Errorf("envconfig: cluster %q is not registered", name)
+	}
+	if cluster.client != nil {
+		return cluster.client, nil
+	}
+
+	restConfig, err := conf.New(cluster.kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("envconfig: cluster %q: rest config failed: %w", name, err)
+	}
+	for _, fn := range cluster.restConfigFuncs {
+		if restConfig, err = fn(restConfig); err != nil {
+			return nil, fmt.Errorf("envconfig: cluster %q: rest config customization failed: %w", name, err)
+		}
+	}
+	factory := cluster.clientFactory
+	if factory == nil {
+		factory = klient.New
+	}
+	client, err := factory(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("envconfig: cluster %q: client failed: %w", name, err)
+	}
+	cluster.client = client
+	return client, nil
+}
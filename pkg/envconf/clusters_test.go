@@ -0,0 +1,96 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envconf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/e2e-framework/klient"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: https://127.0.0.1:6443
+contexts:
+- name: test
+  context:
+    cluster: test
+    user: test
+current-context: test
+users:
+- name: test
+  user: {}
+`
+
+func withBearerToken(token string) RestConfigFunc {
+	return func(cfg *rest.Config) (*rest.Config, error) {
+		cfg.BearerToken = token
+		return cfg, nil
+	}
+}
+
+func TestConfig_ClusterClient(t *testing.T) {
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	if err := os.WriteFile(kubeconfig, []byte(testKubeconfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var factoryCalls int
+	cfg := New().
+		WithCluster("cluster-a", kubeconfig).
+		WithClusterRestConfigFunc("cluster-a", withBearerToken("token-a")).
+		WithCluster("cluster-b", kubeconfig).
+		WithClusterRestConfigFunc("cluster-b", withBearerToken("token-b")).
+		WithClusterClientFactory("cluster-b", func(cfg *rest.Config) (klient.Client, error) {
+			factoryCalls++
+			return klient.New(cfg)
+		})
+
+	clientA, err := cfg.ClusterClient("cluster-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token := clientA.RESTConfig().BearerToken; token != "token-a" {
+		t.Errorf("unexpected bearer token for cluster-a: %q", token)
+	}
+
+	clientB, err := cfg.ClusterClient("cluster-b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token := clientB.RESTConfig().BearerToken; token != "token-b" {
+		t.Errorf("unexpected bearer token for cluster-b: %q", token)
+	}
+
+	if _, err := cfg.ClusterClient("cluster-b"); err != nil {
+		t.Fatal(err)
+	}
+	if factoryCalls != 1 {
+		t.Errorf("expected the client of cluster-b to be created once, got %d", factoryCalls)
+	}
+
+	if _, err := cfg.ClusterClient("unknown"); err == nil {
+		t.Error("expected an error for an unregistered cluster")
+	}
+}
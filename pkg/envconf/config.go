@@ -18,22 +18,32 @@ package envconf
 
 import (
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"math/rand"
+	"os"
 	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"k8s.io/apimachinery/pkg/util/validation"
 	log "k8s.io/klog/v2"
 
 	"sigs.k8s.io/e2e-framework/klient"
+	"sigs.k8s.io/e2e-framework/klient/conf"
 	"sigs.k8s.io/e2e-framework/pkg/flags"
 )
 
 // Config represents and environment configuration
 type Config struct {
 	client                  klient.Client
+	baseClient              klient.Client
 	kubeconfig              string
+	inCluster               bool
 	namespace               string
+	externalNamespace       bool
 	assessmentRegex         *regexp.Regexp
 	featureRegex            *regexp.Regexp
 	labels                  flags.LabelsMap
@@ -45,6 +55,33 @@ type Config struct {
 	failFast                bool
 	disableGracefulTeardown bool
 	kubeContext             string
+	summarizeSkips          bool
+	parseErrors             []error
+	kubernetesVersion       string
+	shardIndex              int
+	shardCount              int
+	assessmentEvents        bool
+	reuseCluster            bool
+	failuresManifest        string
+	timingMetrics           string
+	rerunFeatures           map[string]struct{}
+	featureList             []string
+	envVars                 map[string]string
+	skipSetup               bool
+	skipFinish              bool
+	keepEnv                 string
+	color                   bool
+	assessmentTimeout       time.Duration
+	nameGenerator           func(prefix string) string
+	clusterLabels           map[string]string
+	resourceManifest        string
+	resourceLog             *resourceLog
+	clientRetry             *klient.RetryPolicy
+	clientOpTimeout         time.Duration
+	gvkCoverageReport       string
+	gvkCoverage             *gvkCoverage
+	clustersMu              sync.Mutex
+	clusters                map[string]*clusterConfig
 }
 
 // New creates and initializes an empty environment configuration
@@ -59,38 +96,195 @@ func NewWithKubeConfig(kubeconfig string) *Config {
 }
 
 // NewFromFlags initializes an environment config using flag values
-// parsed from command-line arguments and returns an error on parsing failure.
+// parsed from command-line arguments and returns an error on parsing failure,
+// e.g. when a regular expression provided with a flag does not compile.
 func NewFromFlags() (*Config, error) {
 	envFlags, err := flags.Parse()
 	if err != nil {
 		log.Fatalf("flags parse failed: %s", err)
 	}
 	e := New()
-	if envFlags.Assessment() != "" {
-		e.assessmentRegex = regexp.MustCompile(envFlags.Assessment())
-	}
-	if envFlags.Feature() != "" {
-		e.featureRegex = regexp.MustCompile(envFlags.Feature())
-	}
+	var regexErrs []error
+	e.assessmentRegex = compileFlagRegex("assess", envFlags.Assessment(), &regexErrs)
+	e.featureRegex = compileFlagRegex("feature", envFlags.Feature(), &regexErrs)
 	e.labels = envFlags.Labels()
 	e.namespace = envFlags.Namespace()
-	e.kubeconfig = envFlags.Kubeconfig()
-	if envFlags.SkipFeatures() != "" {
-		e.skipFeatureRegex = regexp.MustCompile(envFlags.SkipFeatures())
+	if e.namespace == "" && envFlags.NamespaceEnv() != "" {
+		e.WithNamespaceFromEnv(envFlags.NamespaceEnv())
 	}
-	if envFlags.SkipAssessment() != "" {
-		e.skipAssessmentRegex = regexp.MustCompile(envFlags.SkipAssessment())
+	e.kubeconfig = envFlags.Kubeconfig()
+	e.skipFeatureRegex = compileFlagRegex("skip-features", envFlags.SkipFeatures(), &regexErrs)
+	e.skipAssessmentRegex = compileFlagRegex("skip-assessment", envFlags.SkipAssessment(), &regexErrs)
+	if len(regexErrs) > 0 {
+		// an invalid filter must not silently select all the features of the suite
+		return nil, errors.Join(regexErrs...)
 	}
 	e.skipLabels = envFlags.SkipLabels()
+	e.parseErrors = append(e.parseErrors, envFlags.SelectorErrors()...)
 	e.parallelTests = envFlags.Parallel()
 	e.dryRun = envFlags.DryRun()
 	e.failFast = envFlags.FailFast()
 	e.disableGracefulTeardown = envFlags.DisableGracefulTeardown()
 	e.kubeContext = envFlags.KubeContext()
+	e.summarizeSkips = envFlags.SummarizeSkips()
+	e.shardIndex = envFlags.ShardIndex()
+	e.shardCount = envFlags.ShardCount()
+	e.assessmentEvents = envFlags.AssessmentEvents()
+	e.reuseCluster = envFlags.ReuseCluster()
+	e.failuresManifest = envFlags.FailuresManifest()
+	e.timingMetrics = envFlags.TimingMetrics()
+	e.skipSetup = envFlags.SkipSetup()
+	e.skipFinish = envFlags.SkipFinish()
+	e.color = envFlags.Color()
+	if manifest := envFlags.RerunFailed(); manifest != "" {
+		names, err := ReadFailuresManifest(manifest)
+		if err != nil {
+			e.parseErrors = append(e.parseErrors, fmt.Errorf("invalid --rerun-failed manifest: %w", err))
+		}
+		e.WithRerunFeatures(names...)
+	}
+	if path := envFlags.FeaturesFile(); path != "" {
+		names, err := ReadFailuresManifest(path)
+		if err != nil {
+			e.parseErrors = append(e.parseErrors, fmt.Errorf("invalid --features-file: %w", err))
+		}
+		e.WithFeatureList(names...)
+	}
 
 	return e, nil
 }
 
+// Clone returns a copy of the configuration that can be updated without
+// affecting the original one, e.g. to run a step against another namespace.
+// The client, if already created, is shared with the copy.
+func (c *Config) Clone() *Config {
+	clone := &Config{
+		client:                  c.client,
+		baseClient:              c.baseClient,
+		kubeconfig:              c.kubeconfig,
+		inCluster:               c.inCluster,
+		namespace:               c.namespace,
+		externalNamespace:       c.externalNamespace,
+		assessmentRegex:         c.assessmentRegex,
+		featureRegex:            c.featureRegex,
+		labels:                  c.labels,
+		skipFeatureRegex:        c.skipFeatureRegex,
+		skipLabels:              c.skipLabels,
+		skipAssessmentRegex:     c.skipAssessmentRegex,
+		parallelTests:           c.parallelTests,
+		dryRun:                  c.dryRun,
+		failFast:                c.failFast,
+		disableGracefulTeardown: c.disableGracefulTeardown,
+		kubeContext:             c.kubeContext,
+		summarizeSkips:          c.summarizeSkips,
+		parseErrors:             append([]error(nil), c.parseErrors...),
+		kubernetesVersion:       c.kubernetesVersion,
+		shardIndex:              c.shardIndex,
+		shardCount:              c.shardCount,
+		assessmentEvents:        c.assessmentEvents,
+		reuseCluster:            c.reuseCluster,
+		failuresManifest:        c.failuresManifest,
+		timingMetrics:           c.timingMetrics,
+		skipSetup:               c.skipSetup,
+		skipFinish:              c.skipFinish,
+		keepEnv:                 c.keepEnv,
+		color:                   c.color,
+		assessmentTimeout:       c.assessmentTimeout,
+		nameGenerator:           c.nameGenerator,
+		resourceManifest:        c.resourceManifest,
+		resourceLog:             c.resourceLog,
+		clientRetry:             c.clientRetry,
+		clientOpTimeout:         c.clientOpTimeout,
+		gvkCoverageReport:       c.gvkCoverageReport,
+		gvkCoverage:             c.gvkCoverage,
+	}
+	if c.rerunFeatures != nil {
+		clone.rerunFeatures = make(map[string]struct{}, len(c.rerunFeatures))
+		for name := range c.rerunFeatures {
+			clone.rerunFeatures[name] = struct{}{}
+		}
+	}
+	if c.featureList != nil {
+		clone.featureList = append([]string{}, c.featureList...)
+	}
+	for name, value := range c.envVars {
+		clone.WithEnvVar(name, value)
+	}
+	for key, value := range c.clusterLabels {
+		clone.WithClusterLabel(key, value)
+	}
+
+	c.clustersMu.Lock()
+	defer c.clustersMu.Unlock()
+	for name, cluster := range c.clusters {
+		if clone.clusters == nil {
+			clone.clusters = make(map[string]*clusterConfig, len(c.clusters))
+		}
+		clusterCopy := *cluster
+		clusterCopy.restConfigFuncs = append([]RestConfigFunc(nil), cluster.restConfigFuncs...)
+		clone.clusters[name] = &clusterCopy
+	}
+	return clone
+}
+
+// compileFlagRegex compiles the regular expression provided with the named flag. Compilation
+// errors are appended to errs instead of panicking during flag parsing
+func compileFlagRegex(flagName, expr string, errs *[]error) *regexp.Regexp {
+	if expr == "" {
+		return nil
+	}
+	regex, err := regexp.Compile(expr)
+	if err != nil {
+		*errs = append(*errs, fmt.Errorf("invalid --%s regular expression %q: %w", flagName, expr, err))
+		return nil
+	}
+	return regex
+}
+
+// Validate checks the environment configuration and returns an error listing
+// all the problems found, if any. It checks that the namespace is a valid DNS label,
+// that the kubeconfig file exists when one is provided, that the label selectors
+// provided via flags parse, that the label filters are valid label keys and values and
+// that the shard index is within the shard count.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.namespace != "" {
+		if msgs := validation.IsDNS1123Label(c.namespace); len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("invalid namespace %q: %s", c.namespace, strings.Join(msgs, ", ")))
+		}
+	}
+	if c.kubeconfig != "" {
+		if _, err := os.Stat(c.kubeconfig); err != nil {
+			errs = append(errs, fmt.Errorf("kubeconfig file cannot be resolved: %w", err))
+		}
+	}
+	errs = append(errs, c.parseErrors...)
+	errs = append(errs, validateLabels("labels", c.labels)...)
+	errs = append(errs, validateLabels("skip-labels", c.skipLabels)...)
+	if c.shardCount < 0 {
+		errs = append(errs, fmt.Errorf("invalid shard count %d: must not be negative", c.shardCount))
+	}
+	if c.shardCount > 0 && (c.shardIndex < 0 || c.shardIndex >= c.shardCount) {
+		errs = append(errs, fmt.Errorf("invalid shard index %d: must be in the range [0, %d)", c.shardIndex, c.shardCount))
+	}
+	return errors.Join(errs...)
+}
+
+func validateLabels(kind string, lbls map[string][]string) []error {
+	var errs []error
+	for key, vals := range lbls {
+		if msgs := validation.IsQualifiedName(key); len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("invalid %s key %q: %s", kind, key, strings.Join(msgs, ", ")))
+		}
+		for _, v := range vals {
+			if msgs := validation.IsValidLabelValue(v); len(msgs) > 0 {
+				errs = append(errs, fmt.Errorf("invalid %s value %q for key %q: %s", kind, v, key, strings.Join(msgs, ", ")))
+			}
+		}
+	}
+	return errs
+}
+
 // WithKubeconfigFile creates a new klient.Client and injects it in the cfg
 func (c *Config) WithKubeconfigFile(kubecfg string) *Config {
 	c.kubeconfig = kubecfg
@@ -101,12 +295,82 @@ func (c *Config) KubeconfigFile() string {
 	return c.kubeconfig
 }
 
+// WithInClusterConfig forces the client of the environment to be created from the in-cluster
+// configuration of the pod running the tests, i.e. its mounted service account, regardless of
+// any kubeconfig file. Without it, the in-cluster configuration is only used when no kubeconfig
+// file is provided nor found at the default locations.
+func (c *Config) WithInClusterConfig() *Config {
+	c.inCluster = true
+	return c
+}
+
+// InClusterConfig returns true if the client of the environment is created from the in-cluster configuration
+func (c *Config) InClusterConfig() bool {
+	return c.inCluster
+}
+
 // WithClient used to update the environment klient.Client
 func (c *Config) WithClient(client klient.Client) *Config {
-	c.client = client
+	// the client of the environment passed back is not wrapped twice
+	if client == nil || client != c.client {
+		c.baseClient = client
+	}
+	c.client = c.wrapClient(c.baseClient)
 	return c
 }
 
+// WithClientRetry retries the operations of the client of the environment failing with a
+// transient API error, such as a server timeout or a connection error, according
+// to the policy, see klient.NewRetryingClient. The client, whether already set, set later with
+// WithClient or created from the kubeconfig file, is wrapped transparently, which reduces the
+// flakiness of the helpers and the test steps performing their operations with it.
+func (c *Config) WithClientRetry(policy klient.RetryPolicy) *Config {
+	c.clientRetry = &policy
+	c.client = c.wrapClient(c.baseClient)
+	return c
+}
+
+// ClientRetry returns the retry policy of the client of the environment, if any
+func (c *Config) ClientRetry() (klient.RetryPolicy, bool) {
+	if c.clientRetry == nil {
+		return klient.RetryPolicy{}, false
+	}
+	return *c.clientRetry, true
+}
+
+// WithClientOpTimeout bounds each operation of the client of the environment by the timeout,
+// see klient.NewTimeoutClient, so that a hung API server call fails the step performing it
+// instead of blocking until the test times out. The client, whether already set, set later with
+// WithClient or created from the kubeconfig file, is wrapped transparently.
+//
+// The timeout is a default: the timeout set on the resources with WithOpTimeout takes precedence
+// over it, e.g. to give a long list more time, and a zero timeout disables the default.
+func (c *Config) WithClientOpTimeout(timeout time.Duration) *Config {
+	c.clientOpTimeout = timeout
+	c.client = c.wrapClient(c.baseClient)
+	return c
+}
+
+// ClientOpTimeout returns the default timeout of the operations of the client of the environment, zero if none
+func (c *Config) ClientOpTimeout() time.Duration {
+	return c.clientOpTimeout
+}
+
+// wrapClient wraps the client of the environment to bound, retry and count its operations and
+// record the objects it creates, as configured. The client is always wrapped from the base client,
+// the one set with WithClient or created from the kubeconfig file, in the same order whatever the
+// order of the options: the timeout bounds each attempt of the retries, and the recording and the
+// counting see the operations once.
+func (c *Config) wrapClient(client klient.Client) klient.Client {
+	if client != nil && c.clientOpTimeout > 0 {
+		client = klient.NewTimeoutClient(client, c.clientOpTimeout)
+	}
+	if client != nil && c.clientRetry != nil {
+		client = klient.NewRetryingClient(client, *c.clientRetry)
+	}
+	return c.countClient(c.recordClient(client))
+}
+
 // NewClient is a constructor function that returns a previously
 // created klient.Client or create a new one based on configuration
 // previously set. Will return an error if unable to do so.
@@ -115,15 +379,34 @@ func (c *Config) NewClient() (klient.Client, error) {
 		return c.client, nil
 	}
 
-	client, err := klient.NewWithKubeConfigFile(c.kubeconfig)
+	client, err := c.newClient()
 	if err != nil {
 		return nil, fmt.Errorf("envconfig: client failed: %w", err)
 	}
-	c.client = client
+	c.baseClient = client
+	c.client = c.wrapClient(client)
 
 	return c.client, nil
 }
 
+// newClient creates a client from the in-cluster configuration when forced, or from the
+// kubeconfig file otherwise, which falls back on the in-cluster configuration when no
+// kubeconfig file is found
+func (c *Config) newClient() (klient.Client, error) {
+	if c.inCluster {
+		restCfg, err := conf.NewInCluster()
+		if err != nil {
+			return nil, fmt.Errorf("in-cluster config: %w", err)
+		}
+		return klient.New(restCfg)
+	}
+	client, err := klient.NewWithKubeConfigFile(c.kubeconfig)
+	if err != nil && c.kubeconfig == "" && conf.ResolveKubeConfigFile() == "" {
+		return nil, fmt.Errorf("no kubeconfig file provided nor found in KUBECONFIG or $HOME/.kube/config, and no in-cluster config available: %w", err)
+	}
+	return client, err
+}
+
 // Client is a constructor function that returns a previously
 // created klient.Client or creates a new one based on configuration
 // previously set. Will panic on any error so it is recommended that you
@@ -134,32 +417,79 @@ func (c *Config) Client() klient.Client {
 		return c.client
 	}
 
-	client, err := klient.NewWithKubeConfigFile(c.kubeconfig)
+	client, err := c.newClient()
 	if err != nil {
 		panic(fmt.Errorf("envconfig: client failed: %w", err).Error())
 	}
-	c.client = client
+	c.baseClient = client
+	c.client = c.wrapClient(client)
 	return c.client
 }
 
+// ClientOrDie returns the klient.Client of the environment, creating it if needed like
+// NewClient, and panics with a message explaining how to configure it if it cannot be
+// created. Use NewClient to handle the error instead.
+func (c *Config) ClientOrDie() klient.Client {
+	client, err := c.NewClient()
+	if err != nil {
+		panic(fmt.Sprintf("envconf: no client available, set one with WithClient or provide a valid kubeconfig with WithKubeconfigFile or --kubeconfig: %s", err))
+	}
+	return client
+}
+
 // WithNamespace updates the environment namespace value
 func (c *Config) WithNamespace(ns string) *Config {
 	c.namespace = ns
+	c.externalNamespace = false
 	return c
 }
 
 // WithRandomNamespace sets the environment's namespace
-// to a random value
-func (c *Config) WithRandomNamespace() *Config {
-	c.namespace = randNS()
+// to a random value generated with GenerateName. The
+// namespace is left unchanged and an error is returned
+// when the generated name is invalid.
+func (c *Config) WithRandomNamespace() (*Config, error) {
+	ns, err := c.GenerateName("testns-")
+	if err != nil {
+		return c, err
+	}
+	c.namespace = ns
+	c.externalNamespace = false
+	return c, nil
+}
+
+// WithNamespaceFromEnv uses the namespace provided by the named environment variable, if set,
+// e.g. when the namespace is pre-created by the CI platform. Such a namespace is not owned by
+// the framework (see OwnsNamespace): it is neither created nor deleted by the namespace
+// functions of the envfuncs package.
+func (c *Config) WithNamespaceFromEnv(envVar string) *Config {
+	if ns := os.Getenv(envVar); ns != "" {
+		c.namespace = ns
+		c.externalNamespace = true
+	}
 	return c
 }
 
+// OwnsNamespace returns false when the namespace of the environment is provided externally
+// with WithNamespaceFromEnv, in which case the framework must not create or delete it
+func (c *Config) OwnsNamespace() bool {
+	return !c.externalNamespace
+}
+
 // Namespace returns the namespace for the environment
 func (c *Config) Namespace() string {
 	return c.namespace
 }
 
+// NamespaceOrDefault returns the namespace for the environment, or defaultNamespace when
+// the environment has no namespace
+func (c *Config) NamespaceOrDefault(defaultNamespace string) string {
+	if c.namespace == "" {
+		return defaultNamespace
+	}
+	return c.namespace
+}
+
 // WithAssessmentRegex sets the environment assessment regex filter
 func (c *Config) WithAssessmentRegex(regex string) *Config {
 	c.assessmentRegex = regexp.MustCompile(regex)
@@ -287,8 +617,309 @@ func (c *Config) KubeContext() string {
 	return c.kubeContext
 }
 
-func randNS() string {
-	return RandomName("testns-", 32)
+// WithSkipSummary can be used to report the features skipped by the
+// feature filters as a single summary line at the end of the run
+// instead of logging a skip message for each one of them
+func (c *Config) WithSkipSummary() *Config {
+	c.summarizeSkips = true
+	return c
+}
+
+// SkipSummary indicates if the skipped features are summarized at the
+// end of the run instead of being reported one by one
+func (c *Config) SkipSummary() bool {
+	return c.summarizeSkips
+}
+
+// WithKubernetesVersion records the Kubernetes version of the cluster
+// under test. This is used to filter features by version constraint.
+func (c *Config) WithKubernetesVersion(version string) *Config {
+	c.kubernetesVersion = version
+	return c
+}
+
+// KubernetesVersion returns the Kubernetes version of the cluster under test,
+// if it has been detected
+func (c *Config) KubernetesVersion() string {
+	return c.kubernetesVersion
+}
+
+// Reserved keys of the cluster labels recorded by envfuncs.DetectClusterLabels
+const (
+	// ClusterProviderLabelKey is the key of the cluster label holding the provider of the
+	// cluster, such as "kind", "aws" or "gce", as found in the provider ID of its nodes
+	ClusterProviderLabelKey = "provider"
+	// KubernetesMinorLabelKey is the key of the cluster label holding the minor version of
+	// Kubernetes run by the cluster, such as "28"
+	KubernetesMinorLabelKey = "k8s-minor"
+)
+
+// WithClusterLabel records a synthetic label describing the cluster under test, such as
+// its provider. Cluster labels are matched by the label filters (see WithLabels and
+// WithSkipLabels) as if every feature carried them, so that features can be filtered on
+// the facts of the cluster, e.g. with --labels 'provider!=kind'. The features should not
+// declare labels with the same keys, see the reserved keys ClusterProviderLabelKey and
+// KubernetesMinorLabelKey.
+func (c *Config) WithClusterLabel(key, value string) *Config {
+	if c.clusterLabels == nil {
+		c.clusterLabels = make(map[string]string)
+	}
+	c.clusterLabels[key] = value
+	return c
+}
+
+// ClusterLabels returns the synthetic labels describing the cluster under test
+func (c *Config) ClusterLabels() map[string]string {
+	return c.clusterLabels
+}
+
+// ClusterProvider returns the provider of the cluster under test, such as "kind" or "aws",
+// recorded as the cluster label ClusterProviderLabelKey, or an empty string if unknown
+func (c *Config) ClusterProvider() string {
+	return c.clusterLabels[ClusterProviderLabelKey]
+}
+
+// WithShard restricts the run to the features of the shard with the given zero based
+// index, out of count shards. Features are partitioned by a hash of their name so that
+// each shard runs a disjoint subset of them.
+func (c *Config) WithShard(index, count int) *Config {
+	c.shardIndex = index
+	c.shardCount = count
+	return c
+}
+
+// Shard returns the index of the shard to run and the number of shards. A count
+// of 0 means that sharding is disabled.
+func (c *Config) Shard() (index, count int) {
+	return c.shardIndex, c.shardCount
+}
+
+// WithAssessmentEvents enables logging a JSON event at the start and end of each
+// assessment. The events are part of the assessment output and can be extracted
+// from the go test -json output to surface assessment timings.
+func (c *Config) WithAssessmentEvents() *Config {
+	c.assessmentEvents = true
+	return c
+}
+
+// AssessmentEventsEnabled indicates if JSON events are logged at the start and
+// end of each assessment
+func (c *Config) AssessmentEventsEnabled() bool {
+	return c.assessmentEvents
+}
+
+// WithReuseCluster keeps the clusters created by the test suite at the end of
+// the run so that the next runs can reuse them instead of creating new ones.
+//
+// The state left behind in a reused cluster by previous runs, such as resources
+// that failed to be cleaned up, is visible to the next runs and can make them
+// behave differently than they would on a fresh cluster.
+func (c *Config) WithReuseCluster() *Config {
+	c.reuseCluster = true
+	return c
+}
+
+// ReuseCluster indicates if the clusters created by the test suite are kept at
+// the end of the run to be reused
+func (c *Config) ReuseCluster() bool {
+	return c.reuseCluster
+}
+
+// WithFailuresManifest sets the path of the file the names of the failed
+// features are written to at the end of the run. See WriteFailuresManifest
+// for the format of the file.
+func (c *Config) WithFailuresManifest(path string) *Config {
+	c.failuresManifest = path
+	return c
+}
+
+// FailuresManifest returns the path of the file the names of the failed
+// features are written to, if any
+func (c *Config) FailuresManifest() string {
+	return c.failuresManifest
+}
+
+// WithTimingMetrics sets the path of the file the durations of the features
+// and assessments are written to at the end of the run, in the Prometheus text
+// exposition format read by the textfile collector of the node exporter.
+// The durations are not measured when it is not set.
+func (c *Config) WithTimingMetrics(path string) *Config {
+	c.timingMetrics = path
+	return c
+}
+
+// TimingMetrics returns the path of the file the durations of the features
+// and assessments are written to, if any
+func (c *Config) TimingMetrics() string {
+	return c.timingMetrics
+}
+
+// WithRerunFeatures restricts the run to the features with the given names,
+// typically the failed features of a previous run read with ReadFailuresManifest.
+// When the list of names is empty, no feature is run.
+func (c *Config) WithRerunFeatures(names ...string) *Config {
+	c.rerunFeatures = make(map[string]struct{}, len(names))
+	for _, name := range names {
+		c.rerunFeatures[name] = struct{}{}
+	}
+	return c
+}
+
+// RerunFeature indicates if the named feature is selected to be run by
+// WithRerunFeatures. All features are selected when the run is not restricted.
+func (c *Config) RerunFeature(name string) bool {
+	if c.rerunFeatures == nil {
+		return true
+	}
+	_, ok := c.rerunFeatures[name]
+	return ok
+}
+
+// WithFeatureList restricts the run to the features with the given names and
+// runs them in the order of the list, which gives a precise control over the
+// sequence of features, e.g. to reproduce a specific run. The file read with
+// the --features-file flag uses the format described in ReadFailuresManifest.
+// When the list of names is empty, no feature is run.
+func (c *Config) WithFeatureList(names ...string) *Config {
+	c.featureList = append([]string{}, names...)
+	return c
+}
+
+// FeatureList returns the names of the features set with WithFeatureList,
+// or nil when the run is not restricted to a list of features
+func (c *Config) FeatureList() []string {
+	return c.featureList
+}
+
+// FeatureListed indicates if the named feature is selected to be run by
+// WithFeatureList. All features are selected when the run is not restricted.
+func (c *Config) FeatureListed(name string) bool {
+	if c.featureList == nil {
+		return true
+	}
+	for _, listed := range c.featureList {
+		if listed == name {
+			return true
+		}
+	}
+	return false
+}
+
+// WithSkipSetup skips the Setup operations of the test suite, which is meant to shorten
+// the development loop when running the features against an already prepared cluster.
+// The features may fail if the prerequisites set up by the skipped operations are not met.
+func (c *Config) WithSkipSetup() *Config {
+	c.skipSetup = true
+	return c
+}
+
+// SkipSetup indicates if the Setup operations of the test suite are skipped
+func (c *Config) SkipSetup() bool {
+	return c.skipSetup
+}
+
+// WithSkipFinish skips the Finish operations of the test suite, as well as the cleanups
+// of the setups, e.g. to keep the cluster prepared for the next runs.
+func (c *Config) WithSkipFinish() *Config {
+	c.skipFinish = true
+	return c
+}
+
+// SkipFinish indicates if the Finish operations of the test suite are skipped
+func (c *Config) SkipFinish() bool {
+	return c.skipFinish
+}
+
+// WithKeepEnv keeps the resources of the run when the environment variable named varName is set
+// to a true value, as parsed by strconv.ParseBool, e.g. KEEP=1: the teardown steps and cleanups of
+// the features, the teardowns of the suites, and the Finish operations and cleanups of the test
+// suite are skipped, whether the features pass or fail. This is meant to inspect the resources
+// after a local run while the CI runs always clean up. The variable is read when the resources
+// are about to be released, see KeepResources.
+func (c *Config) WithKeepEnv(varName string) *Config {
+	c.keepEnv = varName
+	return c
+}
+
+// KeepEnv returns the name of the environment variable set with WithKeepEnv, if any
+func (c *Config) KeepEnv() string {
+	return c.keepEnv
+}
+
+// KeepResources indicates if the resources of the run are kept, i.e. if the environment variable
+// set with WithKeepEnv is set to a true value
+func (c *Config) KeepResources() bool {
+	if c.keepEnv == "" {
+		return false
+	}
+	keep, err := strconv.ParseBool(os.Getenv(c.keepEnv))
+	return err == nil && keep
+}
+
+// WithDefaultAssessmentTimeout bounds the duration of each assessment lacking a timeout
+// of its own (see features.FeatureBuilder.AssessWithTimeout), as a safety net against
+// hanging assessments. A zero timeout, the default, means no timeout.
+func (c *Config) WithDefaultAssessmentTimeout(timeout time.Duration) *Config {
+	c.assessmentTimeout = timeout
+	return c
+}
+
+// DefaultAssessmentTimeout returns the timeout of the assessments lacking a timeout of their own
+func (c *Config) DefaultAssessmentTimeout() time.Duration {
+	return c.assessmentTimeout
+}
+
+// WithColor colors the skip and failure messages reported on the tests, unless the
+// NO_COLOR environment variable is set. Plain messages are the default, which suits
+// the parsers of CI logs.
+func (c *Config) WithColor() *Config {
+	c.color = true
+	return c
+}
+
+// Color indicates if the skip and failure messages are colored
+func (c *Config) Color() bool {
+	return c.color
+}
+
+// WithEnvVar records the value of an environment variable required by the
+// test suite (see env.RequireEnvVars)
+func (c *Config) WithEnvVar(name, value string) *Config {
+	if c.envVars == nil {
+		c.envVars = make(map[string]string)
+	}
+	c.envVars[name] = value
+	return c
+}
+
+// EnvVar returns the value of an environment variable required by the
+// test suite, so that steps do not need to read it again
+func (c *Config) EnvVar(name string) string {
+	return c.envVars[name]
+}
+
+// WithNameGenerator sets the function generating the random names of the namespaces and
+// other resources created by the framework from a prefix, as well as the generated run ID
+// (see env.Environment.WithRunID), e.g. to produce UUID-based names that are unique across a
+// shared cluster. By default, RandomName is used. The generator must be set before calling
+// WithRandomNamespace for the namespace to use it.
+func (c *Config) WithNameGenerator(fn func(prefix string) string) *Config {
+	c.nameGenerator = fn
+	return c
+}
+
+// GenerateName generates a random name with the provided prefix using the name generator of
+// the configuration (see WithNameGenerator). It returns an error if the generated name is not
+// a valid DNS label, as required for namespace names.
+func (c *Config) GenerateName(prefix string) (string, error) {
+	name := RandomName(prefix, 32)
+	if c.nameGenerator != nil {
+		name = c.nameGenerator(prefix)
+	}
+	if msgs := validation.IsDNS1123Label(name); len(msgs) > 0 {
+		return name, fmt.Errorf("invalid generated name %q: %s", name, strings.Join(msgs, ", "))
+	}
+	return name, nil
 }
 
 // RandomName generates a random name of n length with the provided
@@ -102,3 +102,181 @@ func TestRandomName(t *testing.T) {
 		}
 	})
 }
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    func() *Config
+		errors []string
+	}{
+		{
+			name: "empty config",
+			cfg:  New,
+		},
+		{
+			name: "valid config",
+			cfg: func() *Config {
+				return New().WithNamespace("test-ns").WithLabels(map[string][]string{"env": {"prod"}})
+			},
+		},
+		{
+			name: "all problems reported",
+			cfg: func() *Config {
+				return New().
+					WithNamespace("Invalid_NS").
+					WithKubeconfigFile("/does/not/exist").
+					WithLabels(map[string][]string{"bad key": {"v"}}).
+					WithSkipLabels(map[string][]string{"env": {"bad value!"}})
+			},
+			errors: []string{`invalid namespace "Invalid_NS"`, "kubeconfig file cannot be resolved", `invalid labels key "bad key"`, `invalid skip-labels value "bad value!"`},
+		},
+		{
+			name: "shard index out of range",
+			cfg: func() *Config {
+				return New().WithShard(3, 3)
+			},
+			errors: []string{"invalid shard index 3"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.cfg().Validate()
+			if len(test.errors) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected validation error: %s", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected validation error")
+			}
+			for _, msg := range test.errors {
+				if !strings.Contains(err.Error(), msg) {
+					t.Errorf("expected validation error to contain %q, got: %s", msg, err)
+				}
+			}
+		})
+	}
+}
+
+func TestConfig_New_InvalidRegexFlag(t *testing.T) {
+	flag.CommandLine = &flag.FlagSet{}
+	os.Args = []string{"test-binary", "-feature", "feat[", "-skip-assessment", "(assess"}
+	cfg, err := NewFromFlags()
+	if err == nil {
+		t.Fatalf("expected an error for invalid regular expressions, got config with feature regex %v", cfg.FeatureRegex())
+	}
+	for _, msg := range []string{"--feature", "--skip-assessment"} {
+		if !strings.Contains(err.Error(), msg) {
+			t.Errorf("expected error to mention %s, got: %s", msg, err)
+		}
+	}
+}
+
+func TestConfig_Validate_MalformedLabelSelector(t *testing.T) {
+	flag.CommandLine = &flag.FlagSet{}
+	os.Args = []string{"test-binary", "-labels", "env=prod,tier", "-skip-labels", "flaky"}
+	cfg, err := NewFromFlags()
+	if err != nil {
+		t.Fatal("failed to parse args", err)
+	}
+	err = cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation error for malformed label selectors")
+	}
+	for _, msg := range []string{`invalid --labels selector "env=prod,tier"`, `invalid --skip-labels selector "flaky"`} {
+		if !strings.Contains(err.Error(), msg) {
+			t.Errorf("expected validation error to contain %s, got: %s", msg, err)
+		}
+	}
+}
+
+func TestConfig_Clone(t *testing.T) {
+	cfg := New().WithNamespace("default").WithFailFast().WithEnvVar("TOKEN", "token").WithCluster("remote", "remote.kubeconfig")
+	clone := cfg.Clone().WithNamespace("assessment").WithEnvVar("TOKEN", "other").WithCluster("remote", "other.kubeconfig")
+
+	if cfg.Namespace() != "default" || clone.Namespace() != "assessment" {
+		t.Errorf("unexpected namespaces: %s and %s", cfg.Namespace(), clone.Namespace())
+	}
+	if !clone.FailFast() {
+		t.Error("expected the clone to keep the fail-fast setting")
+	}
+	if cfg.EnvVar("TOKEN") != "token" {
+		t.Errorf("unexpected value of the environment variable in the original config: %s", cfg.EnvVar("TOKEN"))
+	}
+	if cfg.clusters["remote"].kubeconfig != "remote.kubeconfig" {
+		t.Errorf("unexpected kubeconfig of the cluster in the original config: %s", cfg.clusters["remote"].kubeconfig)
+	}
+}
+
+func TestConfig_New_WithNamespaceEnv(t *testing.T) {
+	t.Setenv("TEST_NAMESPACE", "ci-namespace")
+
+	flag.CommandLine = &flag.FlagSet{}
+	os.Args = []string{"test-binary", "-namespace-env", "TEST_NAMESPACE"}
+	cfg, err := NewFromFlags()
+	if err != nil {
+		t.Fatal("failed to parse args", err)
+	}
+	if cfg.Namespace() != "ci-namespace" || cfg.OwnsNamespace() {
+		t.Errorf("expected the external namespace to be used and not owned, got %q owned: %t", cfg.Namespace(), cfg.OwnsNamespace())
+	}
+	if cfg.Clone().OwnsNamespace() {
+		t.Error("expected the clone to keep the ownership of the namespace")
+	}
+
+	flag.CommandLine = &flag.FlagSet{}
+	os.Args = []string{"test-binary", "-namespace-env", "TEST_NAMESPACE", "-namespace", "flag-namespace"}
+	cfg, err = NewFromFlags()
+	if err != nil {
+		t.Fatal("failed to parse args", err)
+	}
+	if cfg.Namespace() != "flag-namespace" || !cfg.OwnsNamespace() {
+		t.Errorf("expected the namespace flag to take precedence, got %q owned: %t", cfg.Namespace(), cfg.OwnsNamespace())
+	}
+}
+
+func TestConfig_NamespaceOrDefault(t *testing.T) {
+	if ns := New().NamespaceOrDefault("default"); ns != "default" {
+		t.Errorf("expected the default namespace, got %q", ns)
+	}
+	if ns := New().WithNamespace("test-ns").NamespaceOrDefault("default"); ns != "test-ns" {
+		t.Errorf("expected the namespace of the config, got %q", ns)
+	}
+}
+
+func TestConfig_WithInClusterConfig(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	t.Setenv("KUBERNETES_SERVICE_PORT", "")
+	_, err := New().WithKubeconfigFile("ignored.kubeconfig").WithInClusterConfig().NewClient()
+	if err == nil || !strings.Contains(err.Error(), "in-cluster config") {
+		t.Errorf("expected an in-cluster config error outside of a cluster, got: %v", err)
+	}
+}
+
+func TestConfig_WithNameGenerator(t *testing.T) {
+	cfg := New().WithNameGenerator(func(prefix string) string {
+		return prefix + "fixed"
+	})
+	name, err := cfg.GenerateName("test-")
+	if err != nil || name != "test-fixed" {
+		t.Errorf("expected the name of the custom generator, got %q (error: %v)", name, err)
+	}
+	if clone, err := cfg.Clone().WithRandomNamespace(); err != nil || clone.Namespace() != "testns-fixed" {
+		t.Errorf("expected the random namespace to use the custom generator, got %q (error: %v)", clone.Namespace(), err)
+	}
+
+	cfg = New().WithNameGenerator(func(prefix string) string {
+		return prefix + "Invalid_Name"
+	})
+	if _, err := cfg.GenerateName("test-"); err == nil {
+		t.Error("expected an error for a generated name that is not a DNS label")
+	}
+	if _, err := cfg.WithRandomNamespace(); err == nil || !strings.Contains(err.Error(), "invalid generated name") {
+		t.Errorf("expected an error for the invalid random namespace, got: %v", err)
+	}
+	if cfg.Namespace() != "" {
+		t.Errorf("expected the namespace to be left unchanged, got %q", cfg.Namespace())
+	}
+}
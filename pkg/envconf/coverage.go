@@ -0,0 +1,149 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envconf
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"sigs.k8s.io/e2e-framework/klient"
+)
+
+// GVKCoverage is the number of operations performed on the objects of a kind, see WithGVKCoverageReport
+type GVKCoverage struct {
+	GVK schema.GroupVersionKind
+	// Operations is the number of operations per operation name, such as klient.OperationGet
+	Operations map[string]int
+}
+
+// Total returns the number of operations performed on the objects of the kind
+func (c GVKCoverage) Total() int {
+	total := 0
+	for _, count := range c.Operations {
+		total += count
+	}
+	return total
+}
+
+// gvkCoverage counts the operations performed per kind through the clients of a configuration
+// and its clones
+type gvkCoverage struct {
+	mu     sync.Mutex
+	counts map[schema.GroupVersionKind]map[string]int
+}
+
+func (g *gvkCoverage) count(gvk schema.GroupVersionKind, operation string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.counts[gvk] == nil {
+		g.counts[gvk] = make(map[string]int)
+	}
+	g.counts[gvk][operation]++
+}
+
+// WithGVKCoverageReport counts the operations performed on each kind of objects through the
+// client of the configuration and writes a summary to a report at path once the test suite
+// launched with env.Run completes, e.g. to reveal the kinds the test suite does not exercise.
+// The format of the report is described in WriteGVKCoverageReport.
+//
+// The client, whether already set, set later with WithClient or created from the kubeconfig
+// file, is wrapped transparently, see klient.NewCountingClient: its operations and their results
+// are left unchanged. The counts are shared with the clones of the configuration.
+func (c *Config) WithGVKCoverageReport(path string) *Config {
+	c.gvkCoverageReport = path
+	if c.gvkCoverage == nil {
+		c.gvkCoverage = &gvkCoverage{counts: make(map[schema.GroupVersionKind]map[string]int)}
+	}
+	c.client = c.wrapClient(c.baseClient)
+	return c
+}
+
+// GVKCoverageReport returns the path of the file the coverage report is written to, if any
+func (c *Config) GVKCoverageReport() string {
+	return c.gvkCoverageReport
+}
+
+// GVKCoverage returns the operations counted per kind since WithGVKCoverageReport was called,
+// sorted by group, version and kind
+func (c *Config) GVKCoverage() []GVKCoverage {
+	if c.gvkCoverage == nil {
+		return nil
+	}
+	c.gvkCoverage.mu.Lock()
+	defer c.gvkCoverage.mu.Unlock()
+	result := make([]GVKCoverage, 0, len(c.gvkCoverage.counts))
+	for gvk, counts := range c.gvkCoverage.counts {
+		operations := make(map[string]int, len(counts))
+		for operation, count := range counts {
+			operations[operation] = count
+		}
+		result = append(result, GVKCoverage{GVK: gvk, Operations: operations})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i].GVK, result[j].GVK
+		if a.Group != b.Group {
+			return a.Group < b.Group
+		}
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+		return a.Kind < b.Kind
+	})
+	return result
+}
+
+// countClient wraps the client to count its operations per kind when a coverage report is set
+func (c *Config) countClient(client klient.Client) klient.Client {
+	if c.gvkCoverage == nil || client == nil {
+		return client
+	}
+	return klient.NewCountingClient(client, c.gvkCoverage.count)
+}
+
+// WriteGVKCoverageReport writes the operations performed per kind during a run to the file at path.
+//
+// The report is a plain text table with a line per kind, in the order of the coverage, giving its
+// apiVersion, its kind, the total number of operations and the number of each operation, sorted
+// by operation name, e.g.:
+//
+//	APIVERSION  KIND        TOTAL  OPERATIONS
+//	v1          ConfigMap   3      create=1 get=2
+//	apps/v1     Deployment  1      create=1
+func WriteGVKCoverageReport(path string, coverage []GVKCoverage) error {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "APIVERSION\tKIND\tTOTAL\tOPERATIONS")
+	for _, entry := range coverage {
+		operations := make([]string, 0, len(entry.Operations))
+		for operation, count := range entry.Operations {
+			operations = append(operations, fmt.Sprintf("%s=%d", operation, count))
+		}
+		sort.Strings(operations)
+		apiVersion, kind := entry.GVK.ToAPIVersionAndKind()
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", apiVersion, kind, entry.Total(), strings.Join(operations, " "))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envconf
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/e2e-framework/klient"
+)
+
+// WriteFailuresManifest writes the names of the failed features to the file at path.
+// failures maps the name of each failed feature to an optional annotation, such as
+// the metadata of the feature, written on a comment line above its name.
+//
+// The manifest is a plain text file listing one feature name per line, sorted by
+// name. Empty lines and lines starting with # are ignored when the manifest is read
+// back, so that CI systems can edit or annotate it before feeding it to the
+// --rerun-failed flag of a subsequent run.
+func WriteFailuresManifest(path string, failures map[string]string) error {
+	sorted := make([]string, 0, len(failures))
+	for name := range failures {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var b strings.Builder
+	for _, name := range sorted {
+		if annotation := failures[name]; annotation != "" {
+			b.WriteString("# ")
+			b.WriteString(annotation)
+			b.WriteString("\n")
+		}
+		b.WriteString(name)
+		b.WriteString("\n")
+	}
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
+
+// ReadFailuresManifest reads the names of the features listed in the manifest
+// at path. See WriteFailuresManifest for the format of the manifest.
+func ReadFailuresManifest(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var names []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		names = append(names, line)
+	}
+	return names, scanner.Err()
+}
+
+// resourceManifestEntry is the representation of an object in the resource manifest
+type resourceManifestEntry struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	Applied    bool   `json:"applied,omitempty"`
+}
+
+// WriteResourceManifest writes the objects created during a run to the file at path.
+//
+// The manifest is a JSON array listing the objects in the order of their creation, each
+// of them identified by its apiVersion, kind, namespace, unless cluster scoped, and name.
+// The objects applied with server-side apply, which may have existed before the run,
+// are marked as applied.
+func WriteResourceManifest(path string, objects []klient.CreatedObject) error {
+	entries := make([]resourceManifestEntry, 0, len(objects))
+	for _, obj := range objects {
+		apiVersion, kind := obj.GVK.ToAPIVersionAndKind()
+		entries = append(entries, resourceManifestEntry{
+			APIVersion: apiVersion,
+			Kind:       kind,
+			Namespace:  obj.Namespace,
+			Name:       obj.Name,
+			Applied:    obj.Applied,
+		})
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
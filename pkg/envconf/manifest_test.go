@@ -0,0 +1,75 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envconf
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFailuresManifest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "failures")
+	if err := WriteFailuresManifest(path, map[string]string{"feature-b": "owner=team-b", "feature-a": ""}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "feature-a\n# owner=team-b\nfeature-b\n" {
+		t.Errorf("unexpected manifest content: %q", data)
+	}
+
+	// comments and empty lines added by CI systems are ignored
+	if err := os.WriteFile(path, append([]byte("# failed on main\n\n"), data...), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	names, err := ReadFailuresManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"feature-a", "feature-b"}) {
+		t.Errorf("unexpected feature names read: %v", names)
+	}
+
+	cfg := New().WithRerunFeatures(names...)
+	if !cfg.RerunFeature("feature-a") || cfg.RerunFeature("feature-c") {
+		t.Error("unexpected features selected for the rerun")
+	}
+	if New().WithRerunFeatures().RerunFeature("feature-a") {
+		t.Error("expected no feature to be selected by an empty rerun list")
+	}
+}
+
+func TestConfig_WithFeatureList(t *testing.T) {
+	cfg := New()
+	if cfg.FeatureList() != nil || !cfg.FeatureListed("feature-a") {
+		t.Error("expected all features to be selected without a features list")
+	}
+	cfg.WithFeatureList("feature-b", "feature-a")
+	if !cfg.FeatureListed("feature-a") || cfg.FeatureListed("feature-c") {
+		t.Error("unexpected features selected by the features list")
+	}
+	if !reflect.DeepEqual(cfg.Clone().FeatureList(), []string{"feature-b", "feature-a"}) {
+		t.Errorf("unexpected features list in the clone: %v", cfg.Clone().FeatureList())
+	}
+	if New().WithFeatureList().FeatureListed("feature-a") {
+		t.Error("expected no feature to be selected by an empty features list")
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envconf
+
+import (
+	"sync"
+
+	"sigs.k8s.io/e2e-framework/klient"
+)
+
+// resourceLog collects the objects created through the clients of a configuration
+// and its clones
+type resourceLog struct {
+	mu      sync.Mutex
+	objects []klient.CreatedObject
+}
+
+func (l *resourceLog) record(obj klient.CreatedObject) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.objects = append(l.objects, obj)
+}
+
+// WithResourceManifest records the objects created through the client of the configuration,
+// with the Create operation of its Resources or with server-side apply, and writes them to a
+// manifest at path once the test suite launched with env.Run completes. The manifest is meant
+// to be consumed by external cleanup or leak detection tooling, its format is described in
+// WriteResourceManifest.
+//
+// The client, whether already set, set later with WithClient or created from the kubeconfig
+// file, is wrapped transparently: its operations and their results are left unchanged. The
+// recording is shared with the clones of the configuration.
+func (c *Config) WithResourceManifest(path string) *Config {
+	c.resourceManifest = path
+	if c.resourceLog == nil {
+		c.resourceLog = &resourceLog{}
+	}
+	c.client = c.wrapClient(c.baseClient)
+	return c
+}
+
+// ResourceManifest returns the path of the file the created objects are written to, if any
+func (c *Config) ResourceManifest() string {
+	return c.resourceManifest
+}
+
+// CreatedResources returns the objects recorded since WithResourceManifest was called, in the
+// order of their creation and without duplicates
+func (c *Config) CreatedResources() []klient.CreatedObject {
+	if c.resourceLog == nil {
+		return nil
+	}
+	c.resourceLog.mu.Lock()
+	defer c.resourceLog.mu.Unlock()
+	type key struct {
+		gvk       string
+		namespace string
+		name      string
+	}
+	seen := make(map[key]bool, len(c.resourceLog.objects))
+	var result []klient.CreatedObject
+	for _, obj := range c.resourceLog.objects {
+		k := key{gvk: obj.GVK.String(), namespace: obj.Namespace, name: obj.Name}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		result = append(result, obj)
+	}
+	return result
+}
+
+// recordClient wraps the client to record the objects it creates when a resource manifest is set
+func (c *Config) recordClient(client klient.Client) klient.Client {
+	if c.resourceLog == nil || client == nil {
+		return client
+	}
+	return klient.NewRecordingClient(client, c.resourceLog.record)
+}
@@ -0,0 +1,328 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envconf
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"sigs.k8s.io/e2e-framework/internal/testutil"
+	"sigs.k8s.io/e2e-framework/klient"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+)
+
+func TestConfig_WithResourceManifest(t *testing.T) {
+	ctx := context.Background()
+	// the fake client does not support server-side apply
+	client := testutil.NewFakeClient(interceptor.Funcs{
+		Patch: func(ctx context.Context, c cr.WithWatch, obj cr.Object, patch cr.Patch, opts ...cr.PatchOption) error {
+			if patch.Type() == types.ApplyPatchType {
+				return nil
+			}
+			return c.Patch(ctx, obj, patch, opts...)
+		},
+	})
+	path := filepath.Join(t.TempDir(), "resources.json")
+	cfg := New().WithResourceManifest(path).WithClient(client)
+	configMap := func(name string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+	}
+
+	if err := cfg.Client().Resources().Create(ctx, configMap("created")); err != nil {
+		t.Fatal(err)
+	}
+	// the objects created through the clones are recorded too, failed creations are not
+	if err := cfg.Clone().Client().Resources().Create(ctx, configMap("created")); err == nil {
+		t.Fatal("expected the creation of an existing object to fail")
+	}
+	if err := cfg.Clone().Client().Resources().Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "cloned"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Client().Resources().Patch(ctx, configMap("applied"), k8s.Patch{PatchType: types.ApplyPatchType, Data: []byte("{}")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteResourceManifest(cfg.ResourceManifest(), cfg.CreatedResources()); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []map[string]any
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatal(err)
+	}
+	expected := []map[string]any{
+		{"apiVersion": "v1", "kind": "ConfigMap", "namespace": "default", "name": "created"},
+		{"apiVersion": "v1", "kind": "Namespace", "name": "cloned"},
+		{"apiVersion": "v1", "kind": "ConfigMap", "namespace": "default", "name": "applied", "applied": true},
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("Expected:\n%v but got result:\n%v", expected, entries)
+	}
+}
+
+func TestConfig_WithClientRetry(t *testing.T) {
+	ctx := context.Background()
+	var attempts int
+	failures := 2
+	client := testutil.NewFakeClient(interceptor.Funcs{
+		Create: func(ctx context.Context, c cr.WithWatch, obj cr.Object, opts ...cr.CreateOption) error {
+			attempts++
+			if attempts <= failures {
+				return fmt.Errorf("dial: %w", syscall.ECONNREFUSED)
+			}
+			return c.Create(ctx, obj, opts...)
+		},
+	})
+	policy := klient.RetryPolicy{Attempts: 3, Backoff: time.Millisecond}
+	cfg := New().WithClient(client).WithClientRetry(policy)
+	if got, ok := cfg.ClientRetry(); !ok || got != policy {
+		t.Errorf("unexpected retry policy: %v", got)
+	}
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "retried", Namespace: "default"}}
+	if err := cfg.Client().Resources().Create(ctx, cm); err != nil || attempts != 3 {
+		t.Errorf("expected the creation to succeed after 3 attempts, got %d attempts (error: %v)", attempts, err)
+	}
+
+	// the operations are not retried by the clients wrapping the retrying one
+	attempts, failures = 0, 3
+	cm = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "exhausted", Namespace: "default"}}
+	if err := cfg.Clone().WithClient(klient.NewTrackingClient(cfg.Client())).Client().Resources().Create(ctx, cm); !errors.Is(err, syscall.ECONNREFUSED) || attempts != 3 {
+		t.Errorf("expected the creation to fail after 3 attempts, got %d attempts (error: %v)", attempts, err)
+	}
+
+	// the errors that are not transient and the cancellation of the context stop the retries
+	attempts, failures = 0, 0
+	if err := cfg.Client().Resources().Create(ctx, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "retried", Namespace: "default"}}); !apierrors.IsAlreadyExists(err) || attempts != 1 {
+		t.Errorf("expected a single attempt for a non transient error, got %d attempts (error: %v)", attempts, err)
+	}
+	attempts, failures = 0, 3
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	cfg = New().WithClient(client).WithClientRetry(klient.RetryPolicy{Attempts: 3, Backoff: time.Hour})
+	if err := cfg.Client().Resources().Create(cancelled, cm.DeepCopy()); !errors.Is(err, syscall.ECONNREFUSED) || attempts != 1 {
+		t.Errorf("expected the retries to stop with the context, got %d attempts (error: %v)", attempts, err)
+	}
+}
+
+func TestConfig_WithClientOpTimeout(t *testing.T) {
+	ctx := context.Background()
+	// the Get operations block until their context is done, reporting the time they were given
+	var given time.Duration
+	client := testutil.NewFakeClient(interceptor.Funcs{
+		Get: func(ctx context.Context, c cr.WithWatch, key cr.ObjectKey, obj cr.Object, opts ...cr.GetOption) error {
+			deadline, ok := ctx.Deadline()
+			if !ok {
+				given = 0
+				return nil
+			}
+			given = time.Until(deadline)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	})
+	cfg := New().WithClientOpTimeout(50 * time.Millisecond).WithClient(client)
+	if got := cfg.ClientOpTimeout(); got != 50*time.Millisecond {
+		t.Errorf("unexpected default timeout: %v", got)
+	}
+
+	tests := []struct {
+		name     string
+		res      *resources.Resources
+		expected time.Duration
+	}{
+		{name: "config default", res: cfg.Client().Resources(), expected: 50 * time.Millisecond},
+		{name: "operation timeout", res: cfg.Client().Resources().WithOpTimeout(100 * time.Millisecond), expected: 100 * time.Millisecond},
+		{name: "operation timeout on a clone", res: cfg.Clone().Client().Resources().WithOpTimeout(20 * time.Millisecond), expected: 20 * time.Millisecond},
+		{name: "unbounded operation", res: cfg.Client().Resources().WithOpTimeout(0)},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			err := test.res.Get(ctx, "name", "default", &corev1.ConfigMap{})
+			if test.expected == 0 {
+				if err != nil || given != 0 {
+					t.Errorf("expected an unbounded operation, got %v (error: %v)", given, err)
+				}
+				return
+			}
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("expected the operation to time out, got %v", err)
+			}
+			if given > test.expected || given < test.expected/2 {
+				t.Errorf("expected a timeout of %v, got %v", test.expected, given)
+			}
+		})
+	}
+
+	// without a default, the operations are only bounded by their own timeout
+	cfg = New().WithClient(client)
+	if err := cfg.Client().Resources().Get(ctx, "name", "default", &corev1.ConfigMap{}); err != nil || given != 0 {
+		t.Errorf("expected an unbounded operation, got %v (error: %v)", given, err)
+	}
+}
+
+func TestConfig_WithGVKCoverageReport(t *testing.T) {
+	ctx := context.Background()
+	client := testutil.NewFakeClient(interceptor.Funcs{})
+	path := filepath.Join(t.TempDir(), "coverage.txt")
+	cfg := New().WithClient(client).WithGVKCoverageReport(path)
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "covered", Namespace: "default"}}
+
+	if err := cfg.Client().Resources().Create(ctx, cm); err != nil {
+		t.Fatal(err)
+	}
+	// the operations of the clones, and the failed ones, are counted once
+	clone := cfg.Clone().WithClient(klient.NewTrackingClient(cfg.Client()))
+	if err := clone.Client().Resources().Get(ctx, "covered", "default", &corev1.ConfigMap{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := clone.Client().Resources().Get(ctx, "missing", "default", &corev1.ConfigMap{}); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+	if err := cfg.Client().Resources().List(ctx, &corev1.ConfigMapList{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Client().Resources().Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "covered"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteGVKCoverageReport(cfg.GVKCoverageReport(), cfg.GVKCoverage()); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "APIVERSION  KIND       TOTAL  OPERATIONS\n" +
+		"v1          ConfigMap  4      create=1 get=2 list=1\n" +
+		"v1          Namespace  1      create=1\n"
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s but got result:\n%s", expected, data)
+	}
+}
+
+func TestConfig_ClientWrapping(t *testing.T) {
+	ctx := context.Background()
+	// the Create operations fail with a transient error twice, reporting the deadlines they were given
+	var (
+		attempts  int
+		deadlines []time.Time
+	)
+	client := testutil.NewFakeClient(interceptor.Funcs{
+		Create: func(ctx context.Context, c cr.WithWatch, obj cr.Object, opts ...cr.CreateOption) error {
+			attempts++
+			deadline, _ := ctx.Deadline()
+			deadlines = append(deadlines, deadline)
+			if attempts <= 2 {
+				return fmt.Errorf("dial: %w", syscall.ECONNREFUSED)
+			}
+			return c.Create(ctx, obj, opts...)
+		},
+	})
+	policy := klient.RetryPolicy{Attempts: 3, Backoff: time.Millisecond}
+
+	tests := []struct {
+		name          string
+		cfg           func() *Config
+		wantDeadlines int
+	}{
+		{
+			name: "client set first",
+			cfg: func() *Config {
+				return New().WithClient(client).WithGVKCoverageReport("coverage.txt").WithClientRetry(policy).WithClientOpTimeout(time.Minute)
+			},
+			wantDeadlines: 3,
+		},
+		{
+			name: "client set last",
+			cfg: func() *Config {
+				return New().WithClientOpTimeout(time.Minute).WithClientRetry(policy).WithGVKCoverageReport("coverage.txt").WithClient(client)
+			},
+			wantDeadlines: 3,
+		},
+		{
+			name: "options set twice",
+			cfg: func() *Config {
+				cfg := New().WithClient(client).WithGVKCoverageReport("coverage.txt").WithClientOpTimeout(time.Minute).WithClientRetry(policy)
+				return cfg.WithGVKCoverageReport("coverage.txt").WithClientRetry(policy).WithClientOpTimeout(time.Minute)
+			},
+			wantDeadlines: 3,
+		},
+		{
+			name: "client of the environment set back",
+			cfg: func() *Config {
+				cfg := New().WithClient(client).WithGVKCoverageReport("coverage.txt").WithClientRetry(policy).WithClientOpTimeout(time.Minute)
+				return cfg.WithClient(cfg.Client())
+			},
+			wantDeadlines: 3,
+		},
+		{
+			name: "timeout removed",
+			cfg: func() *Config {
+				return New().WithClient(client).WithClientOpTimeout(time.Minute).WithGVKCoverageReport("coverage.txt").WithClientRetry(policy).WithClientOpTimeout(0)
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			attempts, deadlines = 0, nil
+			cfg := test.cfg()
+			cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{GenerateName: "wrapped-", Namespace: "default"}}
+			if err := cfg.Client().Resources().Create(ctx, cm); err != nil || attempts != 3 {
+				t.Fatalf("expected the creation to succeed after 3 attempts, got %d attempts (error: %v)", attempts, err)
+			}
+
+			// the timeout bounds each attempt rather than the retries as a whole
+			distinct := make(map[time.Time]struct{})
+			for _, deadline := range deadlines {
+				if !deadline.IsZero() {
+					distinct[deadline] = struct{}{}
+				}
+			}
+			if len(distinct) != test.wantDeadlines {
+				t.Errorf("expected %d bounded attempts, got deadlines %v", test.wantDeadlines, deadlines)
+			}
+
+			// the counting sees the retried operation once
+			coverage := cfg.GVKCoverage()
+			if len(coverage) != 1 || coverage[0].Operations["create"] != 1 {
+				t.Errorf("expected a single counted creation, got %+v", coverage)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	"sigs.k8s.io/e2e-framework/klient/decoder"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+// DefaultFieldManager is the field manager of the server-side apply operations of ServerSideApply
+const DefaultFieldManager = "e2e-framework"
+
+// ServerSideApply returns an env.Func that applies the objects with server-side apply, as the
+// DefaultFieldManager field manager. Unlike creating the objects, applying them is idempotent,
+// which makes the setups re-runnable, e.g. against a cluster reused with --reuse-cluster.
+// Conflicts with other field managers are forced. All the objects are applied, the errors
+// being aggregated.
+func ServerSideApply(objects ...k8s.Object) env.Func {
+	return ServerSideApplyWithFieldManager(DefaultFieldManager, objects...)
+}
+
+// ServerSideApplyWithFieldManager returns an env.Func that applies the objects like ServerSideApply
+// as the given field manager
+func ServerSideApplyWithFieldManager(fieldManager string, objects ...k8s.Object) env.Func {
+	return func(ctx context.Context, c *envconf.Config) (context.Context, error) {
+		client, err := c.NewClient()
+		if err != nil {
+			return ctx, fmt.Errorf("server-side apply func: %w", err)
+		}
+		var errs []error
+		for _, obj := range objects {
+			if err := serverSideApply(ctx, client.Resources(), fieldManager, obj); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if len(errs) > 0 {
+			return ctx, fmt.Errorf("server-side apply func: %w", errors.Join(errs...))
+		}
+		return ctx, nil
+	}
+}
+
+// ServerSideApplyFromFS returns an env.Func that applies the resources of the manifests of fsys
+// matching the globbing patterns like ServerSideApply, as the given field manager or as the
+// DefaultFieldManager when it is empty. All the resources are applied, the errors being aggregated.
+func ServerSideApplyFromFS(fsys fs.FS, fieldManager string, patterns ...string) env.Func {
+	if fieldManager == "" {
+		fieldManager = DefaultFieldManager
+	}
+	return func(ctx context.Context, c *envconf.Config) (context.Context, error) {
+		client, err := c.NewClient()
+		if err != nil {
+			return ctx, fmt.Errorf("server-side apply from fs func: %w", err)
+		}
+		var errs []error
+		for _, pattern := range patterns {
+			err := decoder.DecodeEachFile(ctx, fsys, pattern, func(ctx context.Context, obj k8s.Object) error {
+				if err := serverSideApply(ctx, client.Resources(), fieldManager, obj); err != nil {
+					errs = append(errs, err)
+				}
+				return nil
+			})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", pattern, err))
+			}
+		}
+		if len(errs) > 0 {
+			return ctx, fmt.Errorf("server-side apply from fs func: %w", errors.Join(errs...))
+		}
+		return ctx, nil
+	}
+}
+
+// serverSideApply applies the object with server-side apply, forcing the conflicts
+func serverSideApply(ctx context.Context, r *resources.Resources, fieldManager string, obj k8s.Object) error {
+	// the apply patch must carry the kind of the object, which typed objects usually omit
+	gvk, err := apiutil.GVKForObject(obj, r.GetScheme())
+	if err != nil {
+		return fmt.Errorf("%s: %w", obj.GetName(), err)
+	}
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+	obj.SetManagedFields(nil)
+	obj.SetResourceVersion("")
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", gvk.Kind, obj.GetName(), err)
+	}
+	force := true
+	err = r.Patch(ctx, obj, k8s.Patch{PatchType: types.ApplyPatchType, Data: data}, func(o *metav1.PatchOptions) {
+		o.FieldManager = fieldManager
+		o.Force = &force
+	})
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", gvk.Kind, obj.GetName(), err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs_test
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/envfuncs"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+)
+
+func TestServerSideApply(t *testing.T) {
+	name := envconf.RandomName("applied", 16)
+	configMap := func(value string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: v1.ObjectMeta{Name: name, Namespace: "default"}, Data: map[string]string{"key": value}}
+	}
+	feat := features.New("ServerSideApply").
+		Assess("apply is idempotent", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			for _, value := range []string{"first", "second"} {
+				if _, err := envfuncs.ServerSideApply(configMap(value))(ctx, cfg); err != nil {
+					t.Fatalf("Error applying the config map with value %s: %s", value, err)
+				}
+			}
+			var applied corev1.ConfigMap
+			if err := cfg.Client().Resources().Get(ctx, name, "default", &applied); err != nil {
+				t.Fatal("Error getting the applied config map", err)
+			}
+			if applied.Data["key"] != "second" {
+				t.Errorf("unexpected data of the applied config map: %v", applied.Data)
+			}
+			return ctx
+		}).
+		Teardown(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			if err := cfg.Client().Resources().Delete(ctx, configMap("")); err != nil {
+				t.Error("Error deleting the applied config map", err)
+			}
+			return ctx
+		}).
+		Feature()
+
+	nsTestenv.Test(t, feat)
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apimachinerywait "k8s.io/apimachinery/pkg/util/wait"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+// clusterDNSLabels select the deployment of the cluster DNS, CoreDNS or kube-dns, in the kube-system namespace
+var clusterDNSLabels = cr.MatchingLabels{"k8s-app": "kube-dns"}
+
+// WaitForClusterReady returns an env.Func that waits for all the nodes of the cluster to be Ready and for
+// the cluster DNS to be available, so that the features do not start while pods cannot be scheduled or
+// resolve services yet, e.g. on a freshly created multi-node kind cluster. The errors raised while the
+// API server is not reachable yet are retried. Once the timeout is exceeded, the error lists the nodes
+// and deployments that are not ready.
+//
+// NOTE: this should be used in a Environment.Setup step, after the cluster is created.
+func WaitForClusterReady(timeout time.Duration) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		client, err := cfg.NewClient()
+		if err != nil {
+			return ctx, fmt.Errorf("wait for cluster ready func: %w", err)
+		}
+		var notReady []string
+		err = wait.For(func(ctx context.Context) (bool, error) {
+			notReady = clusterNotReady(ctx, client.Resources())
+			return len(notReady) == 0, nil
+		}, wait.WithContext(ctx), wait.WithTimeout(timeout), wait.WithImmediate())
+		if err != nil && apimachinerywait.Interrupted(err) {
+			return ctx, fmt.Errorf("wait for cluster ready func: cluster not ready after %s: %s: %w", timeout, strings.Join(notReady, ", "), err)
+		}
+		if err != nil {
+			return ctx, fmt.Errorf("wait for cluster ready func: %w", err)
+		}
+		return ctx, nil
+	}
+}
+
+// clusterNotReady describes the nodes and cluster DNS deployments that are not ready yet
+func clusterNotReady(ctx context.Context, r *resources.Resources) []string {
+	var notReady []string
+	var nodes corev1.NodeList
+	if err := r.GetControllerRuntimeClient().List(ctx, &nodes); err != nil {
+		return []string{fmt.Sprintf("nodes cannot be listed: %s", err)}
+	}
+	if len(nodes.Items) == 0 {
+		notReady = append(notReady, "no node registered")
+	}
+	for _, node := range nodes.Items {
+		ready := false
+		for _, cond := range node.Status.Conditions {
+			if cond.Type == corev1.NodeReady && cond.Status == corev1.ConditionTrue {
+				ready = true
+			}
+		}
+		if !ready {
+			notReady = append(notReady, fmt.Sprintf("node %s", node.Name))
+		}
+	}
+
+	var deployments appsv1.DeploymentList
+	if err := r.GetControllerRuntimeClient().List(ctx, &deployments, cr.InNamespace("kube-system"), clusterDNSLabels); err != nil {
+		return append(notReady, fmt.Sprintf("cluster DNS cannot be listed: %s", err))
+	}
+	if len(deployments.Items) == 0 {
+		notReady = append(notReady, "no cluster DNS deployment found")
+	}
+	for _, deployment := range deployments.Items {
+		available := false
+		for _, cond := range deployment.Status.Conditions {
+			if cond.Type == appsv1.DeploymentAvailable && cond.Status == corev1.ConditionTrue {
+				available = true
+			}
+		}
+		if !available {
+			notReady = append(notReady, fmt.Sprintf("deployment %s/%s", deployment.Namespace, deployment.Name))
+		}
+	}
+	return notReady
+}
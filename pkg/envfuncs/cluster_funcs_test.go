@@ -0,0 +1,40 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/envfuncs"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+)
+
+func TestWaitForClusterReady(t *testing.T) {
+	feat := features.New("WaitForClusterReady").
+		Assess("cluster is ready", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			if _, err := envfuncs.WaitForClusterReady(5*time.Minute)(ctx, cfg); err != nil {
+				t.Fatal("Error waiting for the cluster to be ready", err)
+			}
+			return ctx
+		}).
+		Feature()
+
+	nsTestenv.Test(t, feat)
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/e2e-framework/support"
+)
+
+// The context keys below are used by the envfuncs of this package to store
+// values in the context. They are part of the stable API of the package so
+// that third party helpers can interoperate with the built-in ones:
+//
+//   - NamespaceContextKey(name) stores the corev1.Namespace created by CreateNamespace
+//   - ClusterNameContextKey(name) stores the support.E2EClusterProvider created by CreateCluster
+//   - KubeconfigContextKey(name) stores the kubeconfig file path of the cluster created by CreateCluster
+//   - LocalRegistryContextKey(name) stores the address of the local registry wired to the cluster by CreateLocalRegistry
+//   - ExportedKubeconfigContextKey{} stores the kubeconfig file exported by ExportKubeconfig
+//
+// Each key but ExportedKubeconfigContextKey is scoped by the name of the namespace or cluster it refers to. Prefer
+// the Get/Set accessors below over reading and writing the raw keys.
+type (
+	NamespaceContextKey   string
+	ClusterNameContextKey string
+	KubeconfigContextKey  string
+
+	LocalRegistryContextKey string
+
+	ExportedKubeconfigContextKey struct{}
+)
+
+// GetNamespaceFromContext extracts the namespace stored in the context under the given name
+func GetNamespaceFromContext(ctx context.Context, name string) (corev1.Namespace, bool) {
+	ns, ok := ctx.Value(NamespaceContextKey(name)).(corev1.Namespace)
+	return ns, ok
+}
+
+// SetNamespaceInContext returns a copy of ctx storing the namespace under its name
+func SetNamespaceInContext(ctx context.Context, ns corev1.Namespace) context.Context {
+	return context.WithValue(ctx, NamespaceContextKey(ns.Name), ns)
+}
+
+// GetClusterFromContext helps extract the E2EClusterProvider object from the context.
+// This can be used to setup and run tests of multi cluster e2e Prioviders.
+func GetClusterFromContext(ctx context.Context, clusterName string) (support.E2EClusterProvider, bool) {
+	c := ctx.Value(ClusterNameContextKey(clusterName))
+	if c == nil {
+		return nil, false
+	}
+	cluster, ok := c.(support.E2EClusterProvider)
+	return cluster, ok
+}
+
+// SetClusterInContext returns a copy of ctx storing the cluster provider under the cluster name
+func SetClusterInContext(ctx context.Context, clusterName string, cluster support.E2EClusterProvider) context.Context {
+	return context.WithValue(ctx, ClusterNameContextKey(clusterName), cluster)
+}
+
+// GetKubeconfigFromContext extracts the kubeconfig file path stored for the named cluster
+func GetKubeconfigFromContext(ctx context.Context, clusterName string) (string, bool) {
+	kubecfg, ok := ctx.Value(KubeconfigContextKey(clusterName)).(string)
+	return kubecfg, ok
+}
+
+// SetKubeconfigInContext returns a copy of ctx storing the kubeconfig file path for the named cluster
+func SetKubeconfigInContext(ctx context.Context, clusterName, kubeconfig string) context.Context {
+	return context.WithValue(ctx, KubeconfigContextKey(clusterName), kubeconfig)
+}
+
+// GetLocalRegistryFromContext extracts the address of the local registry wired to the named cluster
+func GetLocalRegistryFromContext(ctx context.Context, clusterName string) (string, bool) {
+	address, ok := ctx.Value(LocalRegistryContextKey(clusterName)).(string)
+	return address, ok
+}
+
+// SetLocalRegistryInContext returns a copy of ctx storing the address of the local registry wired to the named cluster
+func SetLocalRegistryInContext(ctx context.Context, clusterName, address string) context.Context {
+	return context.WithValue(ctx, LocalRegistryContextKey(clusterName), address)
+}
+
+// GetExportedKubeconfigFromContext extracts the path of the kubeconfig file exported by ExportKubeconfig
+func GetExportedKubeconfigFromContext(ctx context.Context) (string, bool) {
+	exported, ok := ctx.Value(ExportedKubeconfigContextKey{}).(exportedKubeconfig)
+	return exported.path, ok
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/discovery"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+// DetectKubernetesVersion returns an env.Func that discovers the version of the
+// Kubernetes API server and records it in the env config, so that features
+// declaring a Kubernetes version constraint can be filtered accordingly.
+//
+// NOTE: this should be used in a Environment.Setup step, after the cluster is created.
+func DetectKubernetesVersion() env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		client, err := cfg.NewClient()
+		if err != nil {
+			return ctx, fmt.Errorf("detect kubernetes version func: %w", err)
+		}
+		dc, err := discovery.NewDiscoveryClientForConfig(client.RESTConfig())
+		if err != nil {
+			return ctx, fmt.Errorf("detect kubernetes version func: %w", err)
+		}
+		info, err := dc.ServerVersion()
+		if err != nil {
+			return ctx, fmt.Errorf("detect kubernetes version func: %w", err)
+		}
+		cfg.WithKubernetesVersion(info.GitVersion)
+		return ctx, nil
+	}
+}
+
+// DetectClusterLabels returns an env.Func that discovers facts about the cluster and records them
+// as cluster labels in the env config, so that features can be filtered on them with the label
+// filters, e.g. --labels 'provider!=kind' or --skip-labels 'k8s-minor=27'. The following labels
+// are recorded:
+//
+//   - envconf.ClusterProviderLabelKey: the scheme of the provider ID of the nodes, such as "kind",
+//     "aws", "gce" or "azure", if the nodes have one
+//   - envconf.KubernetesMinorLabelKey: the minor version of the Kubernetes API server, such as "28"
+//
+// The Kubernetes version of the API server is recorded as well (see DetectKubernetesVersion).
+//
+// NOTE: this should be used in a Environment.Setup step, after the cluster is created.
+func DetectClusterLabels() env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		client, err := cfg.NewClient()
+		if err != nil {
+			return ctx, fmt.Errorf("detect cluster labels func: %w", err)
+		}
+		dc, err := discovery.NewDiscoveryClientForConfig(client.RESTConfig())
+		if err != nil {
+			return ctx, fmt.Errorf("detect cluster labels func: %w", err)
+		}
+		info, err := dc.ServerVersion()
+		if err != nil {
+			return ctx, fmt.Errorf("detect cluster labels func: %w", err)
+		}
+		cfg.WithKubernetesVersion(info.GitVersion)
+		// managed clusters may report minor versions such as "28+"
+		cfg.WithClusterLabel(envconf.KubernetesMinorLabelKey, strings.TrimRight(info.Minor, "+"))
+
+		var nodes corev1.NodeList
+		if err := client.Resources().List(ctx, &nodes); err != nil {
+			return ctx, fmt.Errorf("detect cluster labels func: %w", err)
+		}
+		for _, node := range nodes.Items {
+			if provider, _, found := strings.Cut(node.Spec.ProviderID, "://"); found && provider != "" {
+				cfg.WithClusterLabel(envconf.ClusterProviderLabelKey, provider)
+				break
+			}
+		}
+		return ctx, nil
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+// exportedKubeconfigName is the name of the cluster, user and context of the kubeconfig
+// files generated by ExportKubeconfig
+const exportedKubeconfigName = "e2e-framework"
+
+// exportedKubeconfig is the value stored under ExportedKubeconfigContextKey
+type exportedKubeconfig struct {
+	path string
+	// temp is true when the file was created by ExportKubeconfig in the temporary directory
+	temp bool
+}
+
+// ExportKubeconfig returns an EnvFunc that guarantees a kubeconfig file pointing at the
+// cluster of the environment exists on disk, for external tools such as kubectl or helm.
+// The kubeconfig file of the environment config is reused when it exists and path is
+// empty or the same file. Otherwise, a kubeconfig file is generated from the REST config
+// of the client of the environment and written to path, or to a temporary file when path
+// is empty. The path of the file is set as the kubeconfig file of the environment config
+// and stored in the context, where it can be retrieved with GetExportedKubeconfigFromContext.
+//
+// Temporary files are deleted by RemoveExportedKubeconfig, meant to be registered with Finish.
+func ExportKubeconfig(path string) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		if existing := cfg.KubeconfigFile(); existing != "" && (path == "" || path == existing) {
+			if _, err := os.Stat(existing); err == nil {
+				return context.WithValue(ctx, ExportedKubeconfigContextKey{}, exportedKubeconfig{path: existing}), nil
+			}
+		}
+
+		client, err := cfg.NewClient()
+		if err != nil {
+			return ctx, fmt.Errorf("export kubeconfig: %w", err)
+		}
+
+		temp := path == ""
+		if temp {
+			file, err := os.CreateTemp("", "e2e-kubeconfig-*")
+			if err != nil {
+				return ctx, fmt.Errorf("export kubeconfig: %w", err)
+			}
+			path = file.Name()
+			if err := file.Close(); err != nil {
+				return ctx, fmt.Errorf("export kubeconfig: %w", err)
+			}
+		}
+
+		if err := clientcmd.WriteToFile(kubeconfigFromRESTConfig(client.RESTConfig()), path); err != nil {
+			if temp {
+				_ = os.Remove(path)
+			}
+			return ctx, fmt.Errorf("export kubeconfig to %s: %w", path, err)
+		}
+
+		cfg.WithKubeconfigFile(path)
+		return context.WithValue(ctx, ExportedKubeconfigContextKey{}, exportedKubeconfig{path: path, temp: temp}), nil
+	}
+}
+
+// RemoveExportedKubeconfig returns an EnvFunc that deletes the kubeconfig file exported by
+// ExportKubeconfig when it is a temporary file. Kubeconfig files written to a path provided
+// to ExportKubeconfig are left in place.
+func RemoveExportedKubeconfig() env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		exported, ok := ctx.Value(ExportedKubeconfigContextKey{}).(exportedKubeconfig)
+		if !ok || !exported.temp {
+			return ctx, nil
+		}
+		if err := os.Remove(exported.path); err != nil && !os.IsNotExist(err) {
+			return ctx, fmt.Errorf("remove exported kubeconfig %s: %w", exported.path, err)
+		}
+		return ctx, nil
+	}
+}
+
+// kubeconfigFromRESTConfig converts a REST config to a kubeconfig with a single context
+func kubeconfigFromRESTConfig(restCfg *rest.Config) clientcmdapi.Config {
+	cluster := clientcmdapi.NewCluster()
+	cluster.Server = restCfg.Host
+	cluster.TLSServerName = restCfg.TLSClientConfig.ServerName
+	cluster.InsecureSkipTLSVerify = restCfg.TLSClientConfig.Insecure
+	cluster.CertificateAuthority = restCfg.TLSClientConfig.CAFile
+	cluster.CertificateAuthorityData = restCfg.TLSClientConfig.CAData
+
+	user := clientcmdapi.NewAuthInfo()
+	user.ClientCertificate = restCfg.TLSClientConfig.CertFile
+	user.ClientCertificateData = restCfg.TLSClientConfig.CertData
+	user.ClientKey = restCfg.TLSClientConfig.KeyFile
+	user.ClientKeyData = restCfg.TLSClientConfig.KeyData
+	user.Token = restCfg.BearerToken
+	user.TokenFile = restCfg.BearerTokenFile
+	user.Username = restCfg.Username
+	user.Password = restCfg.Password
+	user.Impersonate = restCfg.Impersonate.UserName
+	user.ImpersonateGroups = restCfg.Impersonate.Groups
+	user.Exec = restCfg.ExecProvider
+	user.AuthProvider = restCfg.AuthProvider
+
+	kubecfg := clientcmdapi.NewConfig()
+	kubecfg.Clusters[exportedKubeconfigName] = cluster
+	kubecfg.AuthInfos[exportedKubeconfigName] = user
+	kubecfg.Contexts[exportedKubeconfigName] = &clientcmdapi.Context{Cluster: exportedKubeconfigName, AuthInfo: exportedKubeconfigName}
+	kubecfg.CurrentContext = exportedKubeconfigName
+	return *kubecfg
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs
+
+import (
+	"context"
+	"fmt"
+
+	"sigs.k8s.io/e2e-framework/klient"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+// DrainNode returns an env.Func that drains the named node with klient.DrainNode, to test the
+// resilience of workloads to node disruptions, and registers the uncordon of the node as a cleanup
+// with env.AppendCleanup: when called from a feature step, the node is uncordoned once the feature
+// completes, and at the end of the test suite when used as a Setup operation. The uncordon is
+// registered before the node is cordoned, so that no failure leaves the node unschedulable.
+func DrainNode(name string, opts klient.DrainOptions) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		client, err := cfg.NewClient()
+		if err != nil {
+			return ctx, fmt.Errorf("drain node func: %w", err)
+		}
+		restConfig := client.RESTConfig()
+		err = env.AppendCleanup(ctx, func(ctx context.Context, _ *envconf.Config) error {
+			return klient.UncordonNode(ctx, restConfig, name)
+		})
+		if err != nil {
+			return ctx, fmt.Errorf("drain node func: %w", err)
+		}
+		if err := klient.DrainNode(ctx, restConfig, name, opts); err != nil {
+			return ctx, fmt.Errorf("drain node func: %w", err)
+		}
+		return ctx, nil
+	}
+}
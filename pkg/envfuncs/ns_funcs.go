@@ -19,16 +19,18 @@ package envfuncs
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klog "k8s.io/klog/v2"
 	"sigs.k8s.io/e2e-framework/klient"
 	"sigs.k8s.io/e2e-framework/pkg/env"
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
 )
 
-type NamespaceContextKey string
-
 type CreateNamespaceOpts func(klient.Client, *corev1.Namespace)
 
 // WithLabels provides an option to set custom labels on the namespace.
@@ -49,6 +51,14 @@ func WithAnnotations(annotations map[string]string) CreateNamespaceOpts {
 // creates a new namespace API object and stores it the context
 // using its name as key.
 //
+// When a run ID has been set with Environment.WithRunID, the namespace is
+// labeled with it under the env.RunIDLabelKey label.
+//
+// When the namespace of the env config is provided externally (see
+// envconf.Config.OwnsNamespace), the namespace is not created: the external
+// namespace is stored in the context under the name instead and is left in
+// place by DeleteNamespace.
+//
 // NOTE: the returned environment function automatically updates
 // the env config, it receives, with the namespace to make it available
 // for subsequent call.
@@ -59,30 +69,49 @@ func CreateNamespace(name string, opts ...CreateNamespaceOpts) env.Func {
 		if err != nil {
 			return ctx, fmt.Errorf("create namespace func: %w", err)
 		}
+		if !cfg.OwnsNamespace() {
+			klog.V(2).InfoS("Using the externally provided namespace instead of creating one", "namespace", cfg.Namespace(), "name", name)
+			var external corev1.Namespace
+			if err := client.Resources().Get(ctx, cfg.Namespace(), "", &external); err != nil {
+				return ctx, fmt.Errorf("create namespace func: external namespace: %w", err)
+			}
+			return context.WithValue(ctx, NamespaceContextKey(name), external), nil
+		}
 		for _, opt := range opts {
 			opt(client, &namespace)
 		}
+		if id := env.RunID(ctx); id != "" {
+			if namespace.Labels == nil {
+				namespace.Labels = make(map[string]string)
+			}
+			namespace.Labels[env.RunIDLabelKey] = id
+		}
 		if err := client.Resources().Create(ctx, &namespace); err != nil {
 			return ctx, fmt.Errorf("create namespace func: %w", err)
 		}
 		cfg.WithNamespace(name) // set env config default namespace
-		return context.WithValue(ctx, NamespaceContextKey(name), namespace), nil
+		return SetNamespaceInContext(ctx, namespace), nil
 	}
 }
 
 // DeleteNamespace provides an Environment.Func that deletes the named
 // namespace. It first searches for the ns in its context, if not found then
-// attempt to retrieve it from the API server. Then deletes it.
+// attempt to retrieve it from the API server. Then deletes it. The namespace
+// of the env config is never deleted when it is provided externally (see
+// envconf.Config.OwnsNamespace).
 func DeleteNamespace(name string) env.Func {
 	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
 		var namespace *corev1.Namespace
 
 		// attempt to retrieve from context
-		nsVal := ctx.Value(NamespaceContextKey(name))
-		if nsVal != nil {
-			if ns, ok := nsVal.(corev1.Namespace); ok {
-				namespace = &ns
-			}
+		if ns, ok := GetNamespaceFromContext(ctx, name); ok {
+			namespace = &ns
+		}
+
+		// the external namespace is stored under the name by CreateNamespace
+		if !cfg.OwnsNamespace() && (name == cfg.Namespace() || (namespace != nil && namespace.Name == cfg.Namespace())) {
+			klog.V(2).InfoS("Not deleting the externally provided namespace", "namespace", cfg.Namespace())
+			return ctx, nil
 		}
 
 		client, err := cfg.NewClient()
@@ -112,3 +141,41 @@ func DeleteNamespace(name string) env.Func {
 		return ctx, nil
 	}
 }
+
+// CleanupStaleNamespaces provides an Environment.Func that deletes the namespaces whose
+// name starts with prefix and that were created more than olderThan ago. It is meant to
+// be used as a Setup operation on shared clusters, to delete the namespaces left behind
+// by the runs that crashed before deleting them. The deleted namespaces are logged.
+//
+// The prefix must not be empty, and the default namespace as well as the kube-* system
+// namespaces are never deleted, whatever the prefix.
+func CleanupStaleNamespaces(prefix string, olderThan time.Duration) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		if prefix == "" {
+			return ctx, fmt.Errorf("cleanup stale namespaces func: the prefix of the namespaces must not be empty")
+		}
+		client, err := cfg.NewClient()
+		if err != nil {
+			return ctx, fmt.Errorf("cleanup stale namespaces func: %w", err)
+		}
+		var namespaces corev1.NamespaceList
+		if err := client.Resources().List(ctx, &namespaces); err != nil {
+			return ctx, fmt.Errorf("cleanup stale namespaces func: %w", err)
+		}
+		threshold := time.Now().Add(-olderThan)
+		for i := range namespaces.Items {
+			ns := &namespaces.Items[i]
+			if !strings.HasPrefix(ns.Name, prefix) || ns.DeletionTimestamp != nil || !ns.CreationTimestamp.Time.Before(threshold) {
+				continue
+			}
+			if ns.Name == metav1.NamespaceDefault || strings.HasPrefix(ns.Name, "kube-") {
+				continue
+			}
+			if err := client.Resources().Delete(ctx, ns); err != nil && !apierrors.IsNotFound(err) {
+				return ctx, fmt.Errorf("cleanup stale namespaces func: %w", err)
+			}
+			klog.InfoS("Deleted stale namespace", "namespace", ns.Name, "created", ns.CreationTimestamp.Time)
+		}
+		return ctx, nil
+	}
+}
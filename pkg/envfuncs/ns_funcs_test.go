@@ -20,10 +20,13 @@ import (
 	"context"
 	"reflect"
 	"testing"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/e2e-framework/internal/testutil"
 	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
 	"sigs.k8s.io/e2e-framework/klient/wait"
 	"sigs.k8s.io/e2e-framework/klient/wait/conditions"
@@ -154,3 +157,104 @@ func TestDeleteNamespace(t *testing.T) {
 
 	nsTestenv.Test(t, feat)
 }
+
+func TestCleanupStaleNamespaces(t *testing.T) {
+	namespace := envconf.RandomName("stale-ns", 16)
+	feat := features.New("CleanupStaleNamespaces").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.CreateNamespace(namespace)(ctx, cfg)
+			if err != nil {
+				t.Fatal("Error creating namespace", err)
+			}
+			return ctx
+		}).
+		Assess("recent namespace kept", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.CleanupStaleNamespaces("stale-ns", time.Hour)(ctx, cfg)
+			if err != nil {
+				t.Fatal("Unexpected error cleaning up stale namespaces", err)
+			}
+			var ns corev1.Namespace
+			if err := cfg.Client().Resources().Get(ctx, namespace, namespace, &ns); err != nil || ns.DeletionTimestamp != nil {
+				t.Error("expected the recent namespace to be kept", err)
+			}
+			return ctx
+		}).
+		Assess("stale namespace deleted", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			ctx, err := envfuncs.CleanupStaleNamespaces("stale-ns", 0)(ctx, cfg)
+			if err != nil {
+				t.Fatal("Unexpected error cleaning up stale namespaces", err)
+			}
+			err = wait.For(conditions.New(cfg.Client().Resources()).ResourceDeleted(&corev1.Namespace{ObjectMeta: v1.ObjectMeta{Name: namespace}}), wait.WithImmediate())
+			if err != nil {
+				t.Error("Timed out while waiting for the stale namespace to be deleted", err)
+			}
+			return ctx
+		}).
+		Feature()
+
+	nsTestenv.Test(t, feat)
+}
+
+func TestCleanupStaleNamespaces_Selection(t *testing.T) {
+	old := v1.NewTime(time.Now().Add(-2 * time.Hour))
+	namespace := func(name string, created v1.Time) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: v1.ObjectMeta{Name: name, CreationTimestamp: created}}
+	}
+	tests := []struct {
+		name     string
+		prefix   string
+		expected []string
+		wantErr  bool
+	}{
+		{
+			name:     "stale namespaces with the prefix are deleted",
+			prefix:   "stale-",
+			expected: []string{"default", "kube-system", "other", "stale-recent"},
+		},
+		{
+			name:     "system namespaces are kept",
+			prefix:   "kube-",
+			expected: []string{"default", "kube-system", "other", "stale-old", "stale-recent"},
+		},
+		{
+			name:     "default namespace is kept",
+			prefix:   "def",
+			expected: []string{"default", "kube-system", "other", "stale-old", "stale-recent"},
+		},
+		{
+			name:     "empty prefix is rejected",
+			prefix:   "",
+			expected: []string{"default", "kube-system", "other", "stale-old", "stale-recent"},
+			wantErr:  true,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			client := testutil.NewFakeClient(interceptor.Funcs{},
+				namespace("default", old),
+				namespace("kube-system", old),
+				namespace("other", old),
+				namespace("stale-old", old),
+				namespace("stale-recent", v1.Now()),
+			)
+			cfg := envconf.New().WithClient(client)
+			_, err := envfuncs.CleanupStaleNamespaces(test.prefix, time.Hour)(context.TODO(), cfg)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var namespaces corev1.NamespaceList
+			if err := client.Resources().List(context.TODO(), &namespaces); err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, ns := range namespaces.Items {
+				names = append(names, ns.Name)
+			}
+			if !reflect.DeepEqual(names, test.expected) {
+				t.Errorf("Expected:\n%v but got result:\n%v", test.expected, names)
+			}
+		})
+	}
+}
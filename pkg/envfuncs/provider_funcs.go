@@ -20,30 +20,24 @@ import (
 	"context"
 	"fmt"
 
+	klog "k8s.io/klog/v2"
+
 	"sigs.k8s.io/e2e-framework/pkg/env"
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
 	"sigs.k8s.io/e2e-framework/support"
 )
 
-type clusterNameContextKey string
-
 var LoadDockerImageToCluster = LoadImageToCluster
 
-// GetClusterFromContext helps extract the E2EClusterProvider object from the context.
-// This can be used to setup and run tests of multi cluster e2e Prioviders.
-func GetClusterFromContext(ctx context.Context, clusterName string) (support.E2EClusterProvider, bool) {
-	c := ctx.Value(clusterNameContextKey(clusterName))
-	if c == nil {
-		return nil, false
-	}
-	cluster, ok := c.(support.E2EClusterProvider)
-	return cluster, ok
-}
-
 // CreateCluster returns an env.Func that is used to
 // create an E2E provider cluster that is then injected in the context
 // using the name as a key.
 //
+// If a cluster with the same name already exists, it is reused and only
+// its kubeconfig is retrieved, whether or not the --reuse-cluster flag is set.
+// That flag (see envconf.Config.WithReuseCluster) only keeps DestroyCluster
+// from deleting the cluster, so that the next runs find it.
+//
 // NOTE: the returned function will update its env config with the
 // kubeconfig file for the config client.
 func CreateCluster(p support.E2EClusterProvider, clusterName string) env.Func {
@@ -62,14 +56,21 @@ func CreateCluster(p support.E2EClusterProvider, clusterName string) env.Func {
 			return ctx, err
 		}
 
-		// store entire cluster value in ctx for future access using the cluster name
-		return context.WithValue(ctx, clusterNameContextKey(clusterName), k), nil
+		// store entire cluster value and its kubeconfig in ctx for future access using the cluster name
+		ctx = SetKubeconfigInContext(ctx, clusterName, kubecfg)
+		return SetClusterInContext(ctx, clusterName, k), nil
 	}
 }
 
 // CreateClusterWithConfig returns an env.Func that is used to
 // create a e2e provider cluster that is then injected in the context
-// using the name as a key.
+// using the name as a key. Like CreateCluster, an existing cluster with
+// the same name is reused.
+//
+// The opts customize the cluster with the options of the provider, such as
+// kind.WithImage, or kind.WithConfig to pass an inline configuration instead
+// of a configuration file, configFilePath being empty then. The configuration
+// is validated by the provider before its CLI is invoked.
 //
 // NOTE: the returned function will update its env config with the
 // kubeconfig file for the config client.
@@ -89,18 +90,26 @@ func CreateClusterWithConfig(p support.E2EClusterProvider, clusterName, configFi
 			return ctx, err
 		}
 
-		// store entire cluster value in ctx for future access using the cluster name
-		return context.WithValue(ctx, clusterNameContextKey(clusterName), k), nil
+		// store entire cluster value and its kubeconfig in ctx for future access using the cluster name
+		ctx = SetKubeconfigInContext(ctx, clusterName, kubecfg)
+		return SetClusterInContext(ctx, clusterName, k), nil
 	}
 }
 
 // DestroyCluster returns an EnvFunc that
 // retrieves a previously saved e2e provider Cluster in the context (using the name), then deletes it.
 //
-// NOTE: this should be used in a Environment.Finish step.
+// NOTE: this should be used in a Environment.Finish step. When the --reuse-cluster
+// flag is set (see envconf.Config.WithReuseCluster), the cluster is kept and
+// nothing is deleted.
 func DestroyCluster(name string) env.Func {
 	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
-		clusterVal := ctx.Value(clusterNameContextKey(name))
+		if cfg.ReuseCluster() {
+			klog.V(2).InfoS("Keeping cluster to be reused by the next runs", "cluster", name)
+			return ctx, nil
+		}
+
+		clusterVal := ctx.Value(ClusterNameContextKey(name))
 		if clusterVal == nil {
 			return ctx, fmt.Errorf("destroy e2e provider cluster func: context cluster is nil")
 		}
@@ -123,7 +132,7 @@ func DestroyCluster(name string) env.Func {
 // from the host into the cluster.
 func LoadImageToCluster(name, image string) env.Func {
 	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
-		clusterVal := ctx.Value(clusterNameContextKey(name))
+		clusterVal := ctx.Value(ClusterNameContextKey(name))
 		if clusterVal == nil {
 			return ctx, fmt.Errorf("load image func: context cluster is nil")
 		}
@@ -146,7 +155,7 @@ func LoadImageToCluster(name, image string) env.Func {
 // from the host into the cluster.
 func LoadImageArchiveToCluster(name, imageArchive string) env.Func {
 	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
-		clusterVal := ctx.Value(clusterNameContextKey(name))
+		clusterVal := ctx.Value(ClusterNameContextKey(name))
 		if clusterVal == nil {
 			return ctx, fmt.Errorf("load image archive func: context cluster is nil")
 		}
@@ -169,7 +178,7 @@ func LoadImageArchiveToCluster(name, imageArchive string) env.Func {
 // in the provided destination.
 func ExportClusterLogs(name, dest string) env.Func {
 	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
-		clusterVal := ctx.Value(clusterNameContextKey(name))
+		clusterVal := ctx.Value(ClusterNameContextKey(name))
 		if clusterVal == nil {
 			return ctx, fmt.Errorf("export e2e provider cluster logs: context cluster is nil")
 		}
@@ -0,0 +1,68 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs_test
+
+import (
+	"context"
+	"testing"
+
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/envfuncs"
+	"sigs.k8s.io/e2e-framework/support"
+)
+
+// destroyRecorder is a cluster provider that only records whether it was destroyed
+type destroyRecorder struct {
+	support.E2EClusterProvider
+	destroyed bool
+}
+
+func (d *destroyRecorder) Destroy(context.Context) error {
+	d.destroyed = true
+	return nil
+}
+
+func TestDestroyCluster(t *testing.T) {
+	tests := []struct {
+		name          string
+		cfg           *envconf.Config
+		wantDestroyed bool
+	}{
+		{
+			name:          "cluster is destroyed",
+			cfg:           envconf.New(),
+			wantDestroyed: true,
+		},
+		{
+			name: "cluster is kept with reuse-cluster",
+			cfg:  envconf.New().WithReuseCluster(),
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			cluster := &destroyRecorder{}
+			ctx := envfuncs.SetClusterInContext(context.TODO(), "test", cluster)
+			if _, err := envfuncs.DestroyCluster("test")(ctx, test.cfg); err != nil {
+				t.Fatal(err)
+			}
+			if cluster.destroyed != test.wantDestroyed {
+				t.Errorf("expected destroyed to be %v, got %v", test.wantDestroyed, cluster.destroyed)
+			}
+		})
+	}
+}
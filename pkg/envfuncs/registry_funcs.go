@@ -0,0 +1,140 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envfuncs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/support/utils"
+)
+
+// localRegistryImage is the image of the registry started by CreateLocalRegistry
+const localRegistryImage = "registry:2"
+
+// localRegistryName returns the name of the registry container listening on the host port
+func localRegistryName(port int) string {
+	return fmt.Sprintf("e2e-registry-%d", port)
+}
+
+// CreateLocalRegistry returns an EnvFunc that starts a docker registry container listening
+// on localhost:port, unless it is already running, and configures the containerd of the nodes
+// of the named kind cluster to pull the images pushed to localhost:port from it. A stopped
+// registry container left by a previous run is started again instead of being recreated.
+// The address of the registry is stored in the context under the name of the cluster
+// and can be retrieved with GetLocalRegistryFromContext.
+//
+// This follows the local registry recipe of kind (https://kind.sigs.k8s.io/docs/user/local-registry/)
+// and expects the containerd of the nodes to load registry configurations from
+// /etc/containerd/certs.d, e.g. by creating the cluster with a configuration containing:
+//
+//	containerdConfigPatches:
+//	- |-
+//	  [plugins."io.containerd.grpc.v1.cri".registry]
+//	    config_path = "/etc/containerd/certs.d"
+//
+// NOTE: the function must run after the kind cluster has been created, e.g. with CreateCluster.
+func CreateLocalRegistry(clusterName string, port int) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		registry := localRegistryName(port)
+		address := fmt.Sprintf("localhost:%d", port)
+
+		// a stopped registry container, e.g. after a restart of docker, is started again, the
+		// registry container is only created when it does not exist
+		switch running := utils.FetchCommandOutput(fmt.Sprintf("docker inspect -f {{.State.Running}} %s", registry)); strings.TrimSpace(running) {
+		case "true":
+		case "false":
+			if p := utils.RunCommand(fmt.Sprintf("docker start %s", registry)); p.Err() != nil {
+				return ctx, fmt.Errorf("create local registry func: start registry %s: %s: %s", registry, p.Err(), p.Result())
+			}
+		default:
+			p := utils.RunCommand(fmt.Sprintf("docker run -d --restart=always -p 127.0.0.1:%d:5000 --network bridge --name %s %s", port, registry, localRegistryImage))
+			if p.Err() != nil {
+				return ctx, fmt.Errorf("create local registry func: start registry %s: %s: %s", registry, p.Err(), p.Result())
+			}
+		}
+
+		nodes := strings.Fields(utils.FetchCommandOutput(fmt.Sprintf("docker ps --filter label=io.x-k8s.kind.cluster=%s --format {{.Names}}", clusterName)))
+		if len(nodes) == 0 {
+			return ctx, fmt.Errorf("create local registry func: no node found for kind cluster %s", clusterName)
+		}
+		hosts, err := os.CreateTemp("", "hosts-*.toml")
+		if err != nil {
+			return ctx, fmt.Errorf("create local registry func: %w", err)
+		}
+		defer os.Remove(hosts.Name())
+		if _, err := fmt.Fprintf(hosts, "[host.\"http://%s:5000\"]\n", registry); err != nil {
+			return ctx, fmt.Errorf("create local registry func: %w", err)
+		}
+		if err := hosts.Close(); err != nil {
+			return ctx, fmt.Errorf("create local registry func: %w", err)
+		}
+		registryDir := fmt.Sprintf("/etc/containerd/certs.d/%s", address)
+		for _, node := range nodes {
+			if p := utils.RunCommand(fmt.Sprintf("docker exec %s mkdir -p %s", node, registryDir)); p.Err() != nil {
+				return ctx, fmt.Errorf("create local registry func: configure node %s: %s: %s", node, p.Err(), p.Result())
+			}
+			if p := utils.RunCommand(fmt.Sprintf("docker cp %s %s:%s/hosts.toml", hosts.Name(), node, registryDir)); p.Err() != nil {
+				return ctx, fmt.Errorf("create local registry func: configure node %s: %s: %s", node, p.Err(), p.Result())
+			}
+		}
+
+		// connect the registry to the network of the kind nodes, unless it is already connected
+		networks := utils.FetchCommandOutput(fmt.Sprintf("docker inspect -f {{json .NetworkSettings.Networks.kind}} %s", registry))
+		if strings.TrimSpace(networks) == "null" {
+			if p := utils.RunCommand(fmt.Sprintf("docker network connect kind %s", registry)); p.Err() != nil {
+				return ctx, fmt.Errorf("create local registry func: connect registry %s to the kind network: %s: %s", registry, p.Err(), p.Result())
+			}
+		}
+
+		// document the local registry, see https://github.com/kubernetes/enhancements/tree/master/keps/sig-cluster-lifecycle/generic/1755-communicating-a-local-registry
+		client, err := cfg.NewClient()
+		if err != nil {
+			return ctx, fmt.Errorf("create local registry func: %w", err)
+		}
+		hosting := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "local-registry-hosting", Namespace: "kube-public"},
+			Data: map[string]string{
+				"localRegistryHosting.v1": fmt.Sprintf("host: %q\nhelp: \"https://kind.sigs.k8s.io/docs/user/local-registry/\"\n", address),
+			},
+		}
+		if err := client.Resources().Create(ctx, hosting); err != nil && !apierrors.IsAlreadyExists(err) {
+			return ctx, fmt.Errorf("create local registry func: %w", err)
+		}
+
+		return SetLocalRegistryInContext(ctx, clusterName, address), nil
+	}
+}
+
+// DeleteLocalRegistry returns an EnvFunc that removes the registry container started by
+// CreateLocalRegistry for the given port. It is meant to be used as a Finish operation.
+func DeleteLocalRegistry(port int) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		registry := localRegistryName(port)
+		if p := utils.RunCommand(fmt.Sprintf("docker rm -f %s", registry)); p.Err() != nil {
+			return ctx, fmt.Errorf("delete local registry func: remove registry %s: %s: %s", registry, p.Err(), p.Result())
+		}
+		return ctx, nil
+	}
+}
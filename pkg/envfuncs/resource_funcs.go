@@ -18,6 +18,8 @@ package envfuncs
 
 import (
 	"context"
+	"fmt"
+	"io/fs"
 
 	"sigs.k8s.io/e2e-framework/klient/decoder"
 	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
@@ -49,3 +51,38 @@ func TeardownCRDs(crdPath, pattern string) env.Func {
 		return ctx, decoder.DeleteWithManifestDir(ctx, r, crdPath, pattern, []resources.DeleteOption{})
 	}
 }
+
+// ApplyFromFS returns an env.Func that creates the resources of the manifests of fsys matching the globbing
+// patterns. Reading the manifests from an fs.FS allows embedding them into the test binary with //go:embed,
+// which avoids depending on the working directory of the tests. Multi-document YAML files are supported.
+func ApplyFromFS(fsys fs.FS, patterns ...string) env.Func {
+	return func(ctx context.Context, c *envconf.Config) (context.Context, error) {
+		client, err := c.NewClient()
+		if err != nil {
+			return ctx, fmt.Errorf("apply from fs func: %w", err)
+		}
+		for _, pattern := range patterns {
+			if err := decoder.DecodeEachFile(ctx, fsys, pattern, decoder.CreateHandler(client.Resources())); err != nil {
+				return ctx, fmt.Errorf("apply from fs func: %s: %w", pattern, err)
+			}
+		}
+		return ctx, nil
+	}
+}
+
+// DeleteFromFS returns an env.Func that deletes the resources created by ApplyFromFS with the same
+// file system and patterns.
+func DeleteFromFS(fsys fs.FS, patterns ...string) env.Func {
+	return func(ctx context.Context, c *envconf.Config) (context.Context, error) {
+		client, err := c.NewClient()
+		if err != nil {
+			return ctx, fmt.Errorf("delete from fs func: %w", err)
+		}
+		for _, pattern := range patterns {
+			if err := decoder.DecodeEachFile(ctx, fsys, pattern, decoder.DeleteHandler(client.Resources())); err != nil {
+				return ctx, fmt.Errorf("delete from fs func: %s: %w", pattern, err)
+			}
+		}
+		return ctx, nil
+	}
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package features
+
+import (
+	"context"
+	"sync"
+
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+// BackgroundStartFunc starts a process running in the background of a feature, such as a log
+// collector, and returns the function stopping it
+type BackgroundStartFunc func(context.Context, *envconf.Config) (stop func(), err error)
+
+// backgroundStopKey is the context key of the stopper of the named background setup of a feature
+type backgroundStopKey struct {
+	feature string
+	name    string
+}
+
+// backgroundStopper stops a background process at most once
+type backgroundStopper struct {
+	once sync.Once
+	stop func()
+}
+
+func (s *backgroundStopper) run() {
+	s.once.Do(func() {
+		if s.stop != nil {
+			s.stop()
+		}
+	})
+}
@@ -17,8 +17,15 @@ limitations under the License.
 package features
 
 import (
+	"context"
 	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
 
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
 	"sigs.k8s.io/e2e-framework/pkg/types"
 )
 
@@ -42,6 +49,155 @@ func (b *FeatureBuilder) WithLabel(key, value string) *FeatureBuilder {
 	return b
 }
 
+// Quarantine labels the feature with the reserved quarantine label. The feature
+// still runs and reports its failures, but they do not fail the test suite.
+func (b *FeatureBuilder) Quarantine() *FeatureBuilder {
+	return b.WithLabel(QuarantineLabelKey, QuarantineLabelValue)
+}
+
+// WithMetadata attaches a metadata value to the feature, such as the owner team or the
+// ticket tracking it. Metadata is reported along with the results of the feature, in the
+// summary of the skipped and failed features and in the failures manifest, but does not
+// affect its execution.
+func (b *FeatureBuilder) WithMetadata(key string, value any) *FeatureBuilder {
+	if b.feat.metadata == nil {
+		b.feat.metadata = make(map[string]any)
+	}
+	b.feat.metadata[key] = value
+	return b
+}
+
+// WithKubernetesVersionConstraint restricts the feature to the Kubernetes versions
+// satisfying the semver range, such as ">=1.27.0 <1.30.0". The feature is skipped
+// when the Kubernetes version detected into the environment config (see
+// envfuncs.DetectKubernetesVersion) is out of the range.
+func (b *FeatureBuilder) WithKubernetesVersionConstraint(semverRange string) *FeatureBuilder {
+	b.feat.versionConstraint = semverRange
+	return b
+}
+
+// WithPrecondition gates the feature with a precondition, such as an operator being
+// installed, checked before any step of the feature runs. The feature is skipped when
+// the check returns false, rather than failed like a setup step would, and fails when
+// the check returns an error. A feature with several preconditions only runs when all
+// of them are met, the remaining ones not being checked once one is not. Preconditions
+// are not checked in dry-run mode.
+func (b *FeatureBuilder) WithPrecondition(name string, check PreconditionFunc) *FeatureBuilder {
+	b.feat.preconditions = append(b.feat.preconditions, types.Precondition{Name: name, Check: check})
+	return b
+}
+
+// WithConflictsWith declares that the feature conflicts with the named features, e.g.
+// because they share a singleton, so that they cannot be tested together: a Test or
+// TestInParallel call passed conflicting features fails before testing any of them. A
+// warning is logged for the names that match none of the features of the call.
+func (b *FeatureBuilder) WithConflictsWith(names ...string) *FeatureBuilder {
+	b.feat.conflictsWith = append(b.feat.conflictsWith, names...)
+	return b
+}
+
+// WithParallelAssessments runs the assessments of the feature concurrently, each as its own
+// subtest, once the setup steps have run. The post-assessment and teardown steps run once all
+// the assessments completed, as do the assessments added with AssessLast. As the assessments
+// run concurrently, they must be independent: each one receives the context produced by the
+// setup steps, and the context it returns is discarded, so that the values it adds are visible
+// neither to the other assessments nor to the following steps. Use AssessSerial for the
+// assessments that must not run concurrently with the serial assessments of other features.
+func (b *FeatureBuilder) WithParallelAssessments() *FeatureBuilder {
+	b.feat.parallelAssess = true
+	return b
+}
+
+// WithCleanupVerification verifies that the feature deletes the objects it creates: once its
+// teardown steps and cleanups have run, the feature fails if one of the objects created by its
+// steps still exists, the objects being deleted being considered as cleaned up. The objects
+// are tracked through the client of the environment config passed to the steps of the feature
+// (see klient.TrackingClient), so that the objects created with other clients, or by other means
+// than Create, such as server-side apply, are not verified.
+func (b *FeatureBuilder) WithCleanupVerification() *FeatureBuilder {
+	b.feat.verifyCleanup = true
+	return b
+}
+
+// WithEnvVars sets environment variables of the test process while the feature runs, e.g. to
+// configure a CLI the steps of the feature shell out to. The variables are set before the setup
+// steps and their previous values are restored once the teardown steps and cleanups have run,
+// even when a step panics. As the environment is global to the process, the features setting
+// environment variables never run concurrently with one another, and TestInParallel runs them
+// serially once the other features completed. The features of other tests running in parallel,
+// with t.Parallel, still observe the variables.
+func (b *FeatureBuilder) WithEnvVars(vars map[string]string) *FeatureBuilder {
+	if b.feat.envVars == nil {
+		b.feat.envVars = make(map[string]string, len(vars))
+	}
+	for name, value := range vars {
+		b.feat.envVars[name] = value
+	}
+	return b
+}
+
+// WithExclusive runs the feature in isolation: when the features of a TestInParallel call run
+// concurrently, no other feature of the call runs while the feature runs, e.g. for a destructive
+// feature deleting a namespace shared by the others. The feature waits for the features already
+// running to complete, and the features not started yet wait for it to complete. The features
+// of other tests running in parallel, with t.Parallel, are not affected.
+func (b *FeatureBuilder) WithExclusive() *FeatureBuilder {
+	b.feat.exclusive = true
+	return b
+}
+
+// WithFixture requests the fixtures registered on the environment with the given names, see
+// env.RegisterFixture. The fixtures are set up before the setup steps of the feature, unless
+// already set up for another feature, and their values are retrieved by the steps with
+// env.Fixture. The feature fails when a fixture is not registered or fails to set up.
+func (b *FeatureBuilder) WithFixture(names ...string) *FeatureBuilder {
+	b.feat.fixtures = append(b.feat.fixtures, names...)
+	return b
+}
+
+// WithFeatureTimeout bounds the duration of the whole feature. Once the timeout is
+// exceeded, the feature fails and its remaining assessments are not run, while its
+// post-assessment and teardown steps still run. The timeout is also set as the
+// deadline of the context passed to the setup steps and assessments. As steps
+// cannot be interrupted, a step that does not honor its context is not aborted
+// and the timeout is only reported once the step completes.
+func (b *FeatureBuilder) WithFeatureTimeout(timeout time.Duration) *FeatureBuilder {
+	b.feat.timeout = timeout
+	return b
+}
+
+// WithProfile profiles the test process while the feature runs. A CPU profile covering
+// the execution of the feature is written to cpuProfileDir and a heap profile taken at
+// the end of the feature is written to memProfileDir, the files being named after the
+// feature. An empty directory disables the corresponding profile. As CPU profiling is
+// process-wide, features running in parallel cannot be CPU profiled at the same time.
+func (b *FeatureBuilder) WithProfile(cpuProfileDir, memProfileDir string) *FeatureBuilder {
+	b.feat.cpuProfileDir = cpuProfileDir
+	b.feat.memProfileDir = memProfileDir
+	return b
+}
+
+// WithStateSnapshot dumps the resources of the given kinds found in the namespace of the
+// environment config as YAML, before the feature runs to dir/before and after its teardown
+// to dir/after, one file per kind. Diffing the two directories shows what the feature
+// changed, which helps debugging non-deterministic failures. The kinds that cannot be
+// listed or dumped are logged and do not fail the feature.
+func (b *FeatureBuilder) WithStateSnapshot(gvks []schema.GroupVersionKind, dir string) *FeatureBuilder {
+	before := newStep(fmt.Sprintf("%s-state-snapshot-before", b.feat.name), LevelPreSetup, func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		snapshotState(ctx, t, cfg, gvks, filepath.Join(dir, "before"))
+		return ctx
+	})
+	after := newStep(fmt.Sprintf("%s-state-snapshot-after", b.feat.name), LevelTeardown, func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		snapshotState(ctx, t, cfg, gvks, filepath.Join(dir, "after"))
+		return ctx
+	})
+	after.runLast = true
+	// the snapshot before the feature is taken ahead of the steps added so far
+	b.feat.steps = append([]types.Step{before}, b.feat.steps...)
+	b.feat.steps = append(b.feat.steps, after)
+	return b
+}
+
 // WithStep adds a new step that will be applied prior to feature test.
 func (b *FeatureBuilder) WithStep(name string, level Level, fn Func) *FeatureBuilder {
 	b.feat.steps = append(b.feat.steps, newStep(name, level, fn))
@@ -64,6 +220,42 @@ func (b *FeatureBuilder) WithSetup(name string, fn Func) *FeatureBuilder {
 	return b.WithStep(name, LevelSetup, fn)
 }
 
+// SetupOnce adds a setup step that runs at most once per test suite, the first time a
+// feature declaring a setup step with the same key is executed. This lazily provisions
+// the prerequisites shared by several features, such as a CRD, only when one of them
+// runs. The step runs again for the next feature if it failed. Note that the context
+// returned by the step is only passed down to the feature that executed it.
+func (b *FeatureBuilder) SetupOnce(key string, fn Func) *FeatureBuilder {
+	step := newStep(key, LevelSetup, fn)
+	step.onceKey = key
+	b.feat.steps = append(b.feat.steps, step)
+	return b
+}
+
+// WithBackgroundSetup adds a setup step starting a process that keeps running in the background
+// of the feature, such as a log collector or a metrics scraper, and a teardown step stopping it.
+// The feature fails if the process cannot be started. The process is also stopped when the
+// feature completes without running its teardown steps, e.g. after a failure or a panic.
+func (b *FeatureBuilder) WithBackgroundSetup(name string, start BackgroundStartFunc) *FeatureBuilder {
+	key := backgroundStopKey{feature: b.feat.name, name: name}
+	b.WithSetup(name, func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		stop, err := start(ctx, cfg)
+		if err != nil {
+			t.Fatalf("Failed to start the background setup %q: %s", name, err)
+		}
+		stopper := &backgroundStopper{stop: stop}
+		// stops the process when the feature completes, unless already stopped by the teardown step
+		t.Cleanup(stopper.run)
+		return context.WithValue(ctx, key, stopper)
+	})
+	return b.WithTeardown(fmt.Sprintf("%s-stop", name), func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		if stopper, ok := ctx.Value(key).(*backgroundStopper); ok {
+			stopper.run()
+		}
+		return ctx
+	})
+}
+
 // Teardown adds a new teardown step that will be applied after feature test.
 func (b *FeatureBuilder) Teardown(fn Func) *FeatureBuilder {
 	return b.WithTeardown(fmt.Sprintf("%s-teardown", b.feat.name), fn)
@@ -80,6 +272,47 @@ func (b *FeatureBuilder) Assess(desc string, fn Func) *FeatureBuilder {
 	return b.WithStep(desc, LevelAssess, fn)
 }
 
+// AssessCtx adds an assessment step receiving a TestContext that bundles the context,
+// *testing.T and environment config of the step. This is an alternative to Assess that
+// runs the same way.
+func (b *FeatureBuilder) AssessCtx(desc string, fn ContextFunc) *FeatureBuilder {
+	return b.Assess(desc, contextFunc(fn))
+}
+
+// AssessLast adds an assessment step that runs after all the other assessments of
+// the feature, regardless of declaration order. This is meant for assessments such
+// as verifying that no resources were leaked. Multiple assessments added with
+// AssessLast run at the end in the order they were added.
+func (b *FeatureBuilder) AssessLast(desc string, fn Func) *FeatureBuilder {
+	step := newStep(desc, LevelAssess, fn)
+	step.runLast = true
+	b.feat.steps = append(b.feat.steps, step)
+	return b
+}
+
+// AssessSerial adds an assessment step that never runs concurrently with the serial
+// assessments of the other features, including features of other environments, when
+// features run in parallel. This is meant for assessments using shared singletons, such
+// as a leader-elected controller. The other assessments still run in parallel.
+func (b *FeatureBuilder) AssessSerial(desc string, fn Func) *FeatureBuilder {
+	step := newStep(desc, LevelAssess, fn)
+	step.serial = true
+	b.feat.steps = append(b.feat.steps, step)
+	return b
+}
+
+// AssessWithTimeout adds an assessment step bound by a timeout, overriding the default
+// assessment timeout of the environment config (see envconf.Config.WithDefaultAssessmentTimeout).
+// The timeout is set as the deadline of the context passed to the step. As steps cannot
+// be interrupted, a step that does not honor its context is not aborted and the assessment
+// only fails once the step completes.
+func (b *FeatureBuilder) AssessWithTimeout(desc string, timeout time.Duration, fn Func) *FeatureBuilder {
+	step := newStep(desc, LevelAssess, fn)
+	step.timeout = timeout
+	b.feat.steps = append(b.feat.steps, step)
+	return b
+}
+
 func (b *FeatureBuilder) AssessWithDescription(name, description string, fn Func) *FeatureBuilder {
 	return b.WithStepDescription(name, description, LevelAssess, fn)
 }
@@ -17,8 +17,12 @@ limitations under the License.
 package features
 
 import (
+	"context"
 	"regexp"
+	"testing"
+	"time"
 
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
 	"sigs.k8s.io/e2e-framework/pkg/types"
 )
 
@@ -28,6 +32,8 @@ type (
 	Step    = types.Step
 	Func    = types.StepFunc
 	Level   = types.Level
+
+	PreconditionFunc = types.PreconditionFunc
 )
 
 const (
@@ -37,13 +43,43 @@ const (
 	LevelAssess = types.LevelAssess
 	// LevelTeardown when doing the teardown phase
 	LevelTeardown = types.LevelTeardown
+	// LevelPreSetup when doing the phase preceding the setup phase
+	LevelPreSetup = types.LevelPreSetup
+	// LevelPostAssess when doing the phase between the assess and teardown phases
+	LevelPostAssess = types.LevelPostAssess
+)
+
+const (
+	// QuarantineLabelKey is the reserved label key used to quarantine a feature. A feature
+	// labeled with QuarantineLabelKey=QuarantineLabelValue runs normally and reports its
+	// failures, but they do not fail the test suite.
+	QuarantineLabelKey = "quarantine"
+	// QuarantineLabelValue is the value of the QuarantineLabelKey label of a quarantined feature
+	QuarantineLabelValue = "true"
 )
 
+// IsQuarantined returns true if the feature carries the reserved quarantine label
+func IsQuarantined(f types.Feature) bool {
+	return f.Labels().Contains(QuarantineLabelKey, QuarantineLabelValue)
+}
+
 type defaultFeature struct {
-	name        string
-	description string
-	labels      types.Labels
-	steps       []types.Step
+	name              string
+	description       string
+	labels            types.Labels
+	steps             []types.Step
+	versionConstraint string
+	timeout           time.Duration
+	metadata          map[string]any
+	cpuProfileDir     string
+	memProfileDir     string
+	preconditions     []types.Precondition
+	conflictsWith     []string
+	parallelAssess    bool
+	verifyCleanup     bool
+	envVars           map[string]string
+	exclusive         bool
+	fixtures          []string
 }
 
 func newDefaultFeature(name, description string) *defaultFeature {
@@ -66,11 +102,59 @@ func (f *defaultFeature) Description() string {
 	return f.description
 }
 
+func (f *defaultFeature) KubernetesVersionConstraint() string {
+	return f.versionConstraint
+}
+
+func (f *defaultFeature) Timeout() time.Duration {
+	return f.timeout
+}
+
+func (f *defaultFeature) Metadata() map[string]any {
+	return f.metadata
+}
+
+func (f *defaultFeature) Preconditions() []types.Precondition {
+	return f.preconditions
+}
+
+func (f *defaultFeature) ConflictsWith() []string {
+	return f.conflictsWith
+}
+
+func (f *defaultFeature) ParallelAssessments() bool {
+	return f.parallelAssess
+}
+
+func (f *defaultFeature) CleanupVerification() bool {
+	return f.verifyCleanup
+}
+
+func (f *defaultFeature) EnvVars() map[string]string {
+	return f.envVars
+}
+
+func (f *defaultFeature) Exclusive() bool {
+	return f.exclusive
+}
+
+func (f *defaultFeature) Fixtures() []string {
+	return f.fixtures
+}
+
+func (f *defaultFeature) Profile() (cpuProfileDir, memProfileDir string) {
+	return f.cpuProfileDir, f.memProfileDir
+}
+
 type testStep struct {
 	name        string
 	description string
 	level       Level
 	fn          Func
+	runLast     bool
+	onceKey     string
+	serial      bool
+	timeout     time.Duration
 }
 
 func newStep(name string, level Level, fn Func) *testStep {
@@ -102,6 +186,22 @@ func (s *testStep) Description() string {
 	return s.description
 }
 
+func (s *testStep) RunLast() bool {
+	return s.runLast
+}
+
+func (s *testStep) OnceKey() string {
+	return s.onceKey
+}
+
+func (s *testStep) Serial() bool {
+	return s.serial
+}
+
+func (s *testStep) Timeout() time.Duration {
+	return s.timeout
+}
+
 func GetStepsByLevel(steps []types.Step, l types.Level) []types.Step {
 	if steps == nil {
 		return nil
@@ -129,3 +229,47 @@ func FilterStepsByName(steps []types.Step, regexName *regexp.Regexp) []types.Ste
 
 	return result
 }
+
+// RunStep executes a single feature step the same way the environment does
+// while testing a feature: setup and teardown steps are executed at the level
+// of t, while assessments are executed as a subtest of t named after the step.
+// The context returned by the step is surfaced so that it can be threaded
+// through the next steps of the feature.
+//
+// This enables custom runners to orchestrate the execution of the feature
+// steps themselves while keeping the execution semantics of the framework.
+func RunStep(ctx context.Context, t *testing.T, cfg *envconf.Config, step types.Step) context.Context {
+	if step == nil || step.Func() == nil {
+		return ctx
+	}
+	if step.Level() != types.LevelAssess {
+		return step.Func()(ctx, t, cfg)
+	}
+	if dStep, ok := step.(types.DescribableStep); ok && dStep.Description() != "" {
+		t.Logf("Processing Assessment: %s", dStep.Description())
+	}
+	t.Run(step.Name(), func(t *testing.T) {
+		ctx = step.Func()(ctx, t, cfg)
+	})
+	return ctx
+}
+
+// RunTeardown executes the teardown steps of the feature, and only them, threading the
+// context through the steps the same way the environment does. Steps that are required
+// to run last run after the other teardown steps. This is meant to clean up on demand
+// after inspecting a failed feature, and is safe to call whether or not the other steps
+// of the feature ran.
+func RunTeardown(ctx context.Context, t *testing.T, cfg *envconf.Config, f types.Feature) context.Context {
+	var last []types.Step
+	for _, step := range GetStepsByLevel(f.Steps(), types.LevelTeardown) {
+		if ordered, ok := step.(types.OrderedStep); ok && ordered.RunLast() {
+			last = append(last, step)
+			continue
+		}
+		ctx = RunStep(ctx, t, cfg, step)
+	}
+	for _, step := range last {
+		ctx = RunStep(ctx, t, cfg, step)
+	}
+	return ctx
+}
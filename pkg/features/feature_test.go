@@ -0,0 +1,342 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package features
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"sigs.k8s.io/e2e-framework/internal/testutil"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/types"
+)
+
+type ctxStepKey struct{}
+
+func TestRunStep(t *testing.T) {
+	var names []string
+	record := func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+		names = append(names, t.Name())
+		val, _ := ctx.Value(ctxStepKey{}).(int)
+		return context.WithValue(ctx, ctxStepKey{}, val+1)
+	}
+	f := New("run-step").
+		Setup(record).
+		Assess("assess", record).
+		Teardown(record).
+		WithStep("nil-step", types.LevelAssess, nil).
+		Feature()
+
+	ctx := context.TODO()
+	for _, step := range f.Steps() {
+		ctx = RunStep(ctx, t, envconf.New(), step)
+	}
+
+	if val := ctx.Value(ctxStepKey{}); val != 3 {
+		t.Errorf("expected context to be threaded through 3 steps, got %v", val)
+	}
+	expected := []string{t.Name(), t.Name() + "/assess", t.Name()}
+	if len(names) != len(expected) {
+		t.Fatalf("Expected:\n%v but got result:\n%v", expected, names)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Errorf("Expected:\n%v but got result:\n%v", expected, names)
+			break
+		}
+	}
+}
+
+func TestWithLeakDetection(t *testing.T) {
+	noop := func(ctx context.Context, _ *testing.T, _ *envconf.Config) context.Context { return ctx }
+	f := WithLeakDetection(NewWithDescription("leaky", "leaks").WithLabel("type", "leak").WithMetadata("owner", "team-a").
+		Setup(noop).Teardown(noop).Feature())
+
+	if !f.Labels().Contains("type", "leak") {
+		t.Error("expected the labels of the feature to be kept")
+	}
+	if df, ok := f.(types.DescribableFeature); !ok || df.Description() != "leaks" {
+		t.Error("expected the description of the feature to be kept")
+	}
+	if mf, ok := f.(types.MetadataFeature); !ok || mf.Metadata()["owner"] != "team-a" {
+		t.Error("expected the metadata of the feature to be kept")
+	}
+	steps := f.Steps()
+	if len(steps) != 4 {
+		t.Fatalf("expected the setup and teardown steps surrounded by the leak detection steps, got %d steps", len(steps))
+	}
+	if steps[0].Level() != types.LevelPreSetup || steps[0].Name() != "leaky-leak-snapshot" {
+		t.Errorf("unexpected first step %q at level %s", steps[0].Name(), steps[0].Level())
+	}
+	last, ok := steps[3].(types.OrderedStep)
+	if !ok || !last.RunLast() || last.Level() != types.LevelTeardown {
+		t.Errorf("expected the leak check to run after the other teardown steps")
+	}
+}
+
+func TestWithLeakDetection_OptionalInterfaces(t *testing.T) {
+	// optional interfaces of a feature that the leak detection wrapper must delegate
+	delegated := map[string]reflect.Type{
+		"DescribableFeature":         reflect.TypeOf((*types.DescribableFeature)(nil)).Elem(),
+		"VersionConstrainedFeature":  reflect.TypeOf((*types.VersionConstrainedFeature)(nil)).Elem(),
+		"TimeBoundFeature":           reflect.TypeOf((*types.TimeBoundFeature)(nil)).Elem(),
+		"MetadataFeature":            reflect.TypeOf((*types.MetadataFeature)(nil)).Elem(),
+		"PreconditionedFeature":      reflect.TypeOf((*types.PreconditionedFeature)(nil)).Elem(),
+		"ConflictingFeature":         reflect.TypeOf((*types.ConflictingFeature)(nil)).Elem(),
+		"ParallelAssessmentsFeature": reflect.TypeOf((*types.ParallelAssessmentsFeature)(nil)).Elem(),
+		"ExclusiveFeature":           reflect.TypeOf((*types.ExclusiveFeature)(nil)).Elem(),
+		"FixtureFeature":             reflect.TypeOf((*types.FixtureFeature)(nil)).Elem(),
+		"EnvVarsFeature":             reflect.TypeOf((*types.EnvVarsFeature)(nil)).Elem(),
+		"CleanupVerifiedFeature":     reflect.TypeOf((*types.CleanupVerifiedFeature)(nil)).Elem(),
+		"ProfiledFeature":            reflect.TypeOf((*types.ProfiledFeature)(nil)).Elem(),
+	}
+
+	// a new optional interface declared in the types package must be added to the wrapper
+	file, err := parser.ParseFile(token.NewFileSet(), filepath.Join("..", "types", "types.go"), nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			if _, ok := typeSpec.Type.(*ast.InterfaceType); !ok || typeSpec.Name.Name == "Feature" || !strings.HasSuffix(typeSpec.Name.Name, "Feature") {
+				continue
+			}
+			if _, ok := delegated[typeSpec.Name.Name]; !ok {
+				t.Errorf("types.%s is not delegated by the leak detection wrapper", typeSpec.Name.Name)
+			}
+		}
+	}
+
+	wrapper := reflect.TypeOf(&leakDetectionFeature{})
+	for name, iface := range delegated {
+		if !wrapper.Implements(iface) {
+			t.Errorf("expected the leak detection wrapper to implement types.%s", name)
+		}
+	}
+}
+
+func TestTakeLeakSnapshot(t *testing.T) {
+	deleting := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Name: "deleting", Namespace: "test-ns", Finalizers: []string{"test/finalizer"}, DeletionTimestamp: &metav1.Time{Time: time.Now()},
+	}}
+	kept := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "kept", Namespace: "test-ns"}}
+	other := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "other-ns"}}
+	cfg := envconf.New().WithClient(testutil.NewFakeClient(interceptor.Funcs{}, deleting, kept, other))
+
+	gvk := corev1.SchemeGroupVersion.WithKind("ConfigMap")
+	snapshot, err := takeLeakSnapshot(context.TODO(), cfg, "test-ns", []schema.GroupVersionKind{gvk})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leaked := snapshot.extras(&leakSnapshot{}, gvk); !reflect.DeepEqual(leaked, []string{"test-ns/kept"}) {
+		t.Errorf("expected only the resource of the namespace that is not being deleted, got %v", leaked)
+	}
+}
+
+func TestAssessCtx(t *testing.T) {
+	var namespace string
+	f := New("assess-ctx").
+		AssessCtx("with-context", func(tc *TestContext) {
+			namespace = tc.Namespace()
+			tc.Log("running %s", tc.T().Name())
+			tc.WithContext(context.WithValue(tc.Context(), ctxStepKey{}, 1))
+		}).
+		Assess("next", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			if val, _ := ctx.Value(ctxStepKey{}).(int); val != 1 {
+				t.Errorf("expected the context updated by the previous assessment, got value %d", val)
+			}
+			return ctx
+		}).Feature()
+
+	ctx := context.Background()
+	cfg := envconf.New().WithNamespace("test-ns")
+	for _, step := range f.Steps() {
+		ctx = RunStep(ctx, t, cfg, step)
+	}
+	if namespace != "test-ns" {
+		t.Errorf("unexpected namespace: %s", namespace)
+	}
+}
+
+func TestRunTeardown(t *testing.T) {
+	var names []string
+	record := func(name string) Func {
+		return func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			names = append(names, name)
+			val, _ := ctx.Value(ctxStepKey{}).(int)
+			return context.WithValue(ctx, ctxStepKey{}, val+1)
+		}
+	}
+	f := New("teardown").
+		Setup(record("setup")).
+		Assess("assess", record("assess")).
+		WithTeardown("teardown-1", record("teardown-1")).
+		WithTeardown("teardown-2", record("teardown-2")).
+		WithStep("nil-teardown", types.LevelTeardown, nil).
+		Feature()
+
+	ctx := RunTeardown(context.Background(), t, envconf.New(), f)
+	if expected := []string{"teardown-1", "teardown-2"}; len(names) != 2 || names[0] != expected[0] || names[1] != expected[1] {
+		t.Errorf("Expected:\n%v but got result:\n%v", expected, names)
+	}
+	if val, _ := ctx.Value(ctxStepKey{}).(int); val != 2 {
+		t.Errorf("expected the context to be threaded through the teardown steps, got value %d", val)
+	}
+}
+
+func TestWithStateSnapshot(t *testing.T) {
+	noop := func(ctx context.Context, _ *testing.T, _ *envconf.Config) context.Context { return ctx }
+	gvks := []schema.GroupVersionKind{{Group: "apps", Version: "v1", Kind: "Deployment"}}
+	f := New("snapshot").Setup(noop).WithStateSnapshot(gvks, t.TempDir()).Teardown(noop).Feature()
+
+	steps := f.Steps()
+	if len(steps) != 4 {
+		t.Fatalf("expected the setup and teardown steps surrounded by the snapshot steps, got %d steps", len(steps))
+	}
+	if steps[0].Level() != types.LevelPreSetup || steps[0].Name() != "snapshot-state-snapshot-before" {
+		t.Errorf("unexpected first step %q at level %s", steps[0].Name(), steps[0].Level())
+	}
+	last, ok := steps[2].(types.OrderedStep)
+	if !ok || !last.RunLast() || last.Level() != types.LevelTeardown {
+		t.Errorf("expected the snapshot after the feature to run after the other teardown steps")
+	}
+	if name := snapshotFileName(gvks[0]); name != "deployment.v1.apps.yaml" {
+		t.Errorf("unexpected snapshot file name: %s", name)
+	}
+}
+
+func TestSoftAssertions(t *testing.T) {
+	var msg string
+	var verified, failedOnReturn bool
+	// run the failing assertions in isolation to keep the failure from bubbling up to this test
+	outcome := testutil.RunIsolated("TestSoft", func(t *testing.T) {
+		t.Run("assess", func(t *testing.T) {
+			soft := NewSoftAssertions(t)
+			soft.Check(1 == 2, "expected %d to equal %d", 1, 2)
+			soft.Check(true, "never recorded")
+			soft.NoError(errors.New("boom"), "check service")
+			msg = soft.flush()
+			soft.Errorf("verified")
+			soft.Verify()
+			verified = t.Failed() && !soft.Failed()
+		})
+		// the failures of the soft assertions of a step added with AssessCtx are reported when it returns
+		step := contextFunc(func(tc *TestContext) {
+			tc.SoftAssertions().Errorf("reported on return")
+		})
+		t.Run("assess-ctx", func(t *testing.T) {
+			step(context.TODO(), t, envconf.New())
+			failedOnReturn = t.Failed()
+		})
+	})
+
+	if !outcome.Failed {
+		t.Error("expected the soft assertion failures to fail the test")
+	}
+	expected := "2 soft assertion(s) failed:\n  1. expected 1 to equal 2\n  2. check service: boom"
+	if msg != expected {
+		t.Errorf("Expected:\n%v but got result:\n%v", expected, msg)
+	}
+	if !verified {
+		t.Error("expected Verify to fail the test and clear the failures")
+	}
+	if !failedOnReturn {
+		t.Error("expected the step added with AssessCtx to fail before it returns")
+	}
+}
+
+func TestWithBackgroundSetup(t *testing.T) {
+	var events []string
+	start := func(ctx context.Context, _ *envconf.Config) (func(), error) {
+		events = append(events, "start")
+		return func() { events = append(events, "stop") }, nil
+	}
+	f := New("background").
+		WithBackgroundSetup("collector", start).
+		Teardown(func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			events = append(events, "teardown")
+			return ctx
+		}).Feature()
+
+	t.Run("feature", func(t *testing.T) {
+		ctx := context.Background()
+		for _, step := range f.Steps() {
+			ctx = RunStep(ctx, t, envconf.New(), step)
+		}
+	})
+	expected := []string{"start", "stop", "teardown"}
+	if fmt.Sprint(events) != fmt.Sprint(expected) {
+		t.Errorf("Expected:\n%v but got result:\n%v", expected, events)
+	}
+
+	// the process is stopped once the feature completes when its teardown steps are not run
+	events = nil
+	t.Run("failed feature", func(t *testing.T) {
+		_ = RunStep(context.Background(), t, envconf.New(), f.Steps()[0])
+	})
+	expected = []string{"start", "stop"}
+	if fmt.Sprint(events) != fmt.Sprint(expected) {
+		t.Errorf("Expected:\n%v but got result:\n%v", expected, events)
+	}
+}
+
+type replicas int
+
+func (r replicas) String() string {
+	return fmt.Sprintf("x%d", int(r))
+}
+
+func TestAssessEach(t *testing.T) {
+	var names []string
+	var sum int
+	f := AssessEach(New("each"), "replicas", []replicas{1, 3}, func(ctx context.Context, t *testing.T, _ *envconf.Config, item replicas) {
+		names = append(names, t.Name())
+		sum += int(item)
+	}).Feature()
+	AssessEach(New("plain"), "plain", []int{1}, func(context.Context, *testing.T, *envconf.Config, int) {})
+
+	ctx := context.Background()
+	for _, step := range f.Steps() {
+		ctx = RunStep(ctx, t, envconf.New(), step)
+	}
+	expected := []string{t.Name() + "/replicas-0-x1", t.Name() + "/replicas-1-x3"}
+	if fmt.Sprint(names) != fmt.Sprint(expected) {
+		t.Errorf("Expected:\n%v but got result:\n%v", expected, names)
+	}
+	if sum != 4 {
+		t.Errorf("expected each item to be assessed once, got sum %d", sum)
+	}
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package features
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/types"
+)
+
+// leakSnapshotKey is the context key of the snapshot taken before the setup of the named feature
+type leakSnapshotKey struct {
+	feature string
+}
+
+// leakSnapshot records the namespace and the names of the resources of each watched kind
+type leakSnapshot struct {
+	namespace string
+	names     map[schema.GroupVersionKind]map[string]struct{}
+}
+
+// leakDetectionFeature wraps a feature with the steps detecting the resources it leaks.
+// Everything but the steps is delegated to the wrapped feature, each optional interface of the
+// types package being implemented explicitly.
+type leakDetectionFeature struct {
+	types.Feature
+	steps []types.Step
+}
+
+var (
+	_ types.DescribableFeature         = (*leakDetectionFeature)(nil)
+	_ types.VersionConstrainedFeature  = (*leakDetectionFeature)(nil)
+	_ types.TimeBoundFeature           = (*leakDetectionFeature)(nil)
+	_ types.MetadataFeature            = (*leakDetectionFeature)(nil)
+	_ types.PreconditionedFeature      = (*leakDetectionFeature)(nil)
+	_ types.ConflictingFeature         = (*leakDetectionFeature)(nil)
+	_ types.ParallelAssessmentsFeature = (*leakDetectionFeature)(nil)
+	_ types.ExclusiveFeature           = (*leakDetectionFeature)(nil)
+	_ types.FixtureFeature             = (*leakDetectionFeature)(nil)
+	_ types.EnvVarsFeature             = (*leakDetectionFeature)(nil)
+	_ types.CleanupVerifiedFeature     = (*leakDetectionFeature)(nil)
+	_ types.ProfiledFeature            = (*leakDetectionFeature)(nil)
+)
+
+func (f *leakDetectionFeature) Steps() []types.Step {
+	return f.steps
+}
+
+func (f *leakDetectionFeature) Description() string {
+	if df, ok := f.Feature.(types.DescribableFeature); ok {
+		return df.Description()
+	}
+	return ""
+}
+
+func (f *leakDetectionFeature) KubernetesVersionConstraint() string {
+	if vf, ok := f.Feature.(types.VersionConstrainedFeature); ok {
+		return vf.KubernetesVersionConstraint()
+	}
+	return ""
+}
+
+func (f *leakDetectionFeature) Timeout() time.Duration {
+	if tf, ok := f.Feature.(types.TimeBoundFeature); ok {
+		return tf.Timeout()
+	}
+	return 0
+}
+
+func (f *leakDetectionFeature) Metadata() map[string]any {
+	if mf, ok := f.Feature.(types.MetadataFeature); ok {
+		return mf.Metadata()
+	}
+	return nil
+}
+
+func (f *leakDetectionFeature) Preconditions() []types.Precondition {
+	if pf, ok := f.Feature.(types.PreconditionedFeature); ok {
+		return pf.Preconditions()
+	}
+	return nil
+}
+
+func (f *leakDetectionFeature) ConflictsWith() []string {
+	if cf, ok := f.Feature.(types.ConflictingFeature); ok {
+		return cf.ConflictsWith()
+	}
+	return nil
+}
+
+func (f *leakDetectionFeature) ParallelAssessments() bool {
+	if pf, ok := f.Feature.(types.ParallelAssessmentsFeature); ok {
+		return pf.ParallelAssessments()
+	}
+	return false
+}
+
+func (f *leakDetectionFeature) Exclusive() bool {
+	if xf, ok := f.Feature.(types.ExclusiveFeature); ok {
+		return xf.Exclusive()
+	}
+	return false
+}
+
+func (f *leakDetectionFeature) Fixtures() []string {
+	if ff, ok := f.Feature.(types.FixtureFeature); ok {
+		return ff.Fixtures()
+	}
+	return nil
+}
+
+func (f *leakDetectionFeature) EnvVars() map[string]string {
+	if ef, ok := f.Feature.(types.EnvVarsFeature); ok {
+		return ef.EnvVars()
+	}
+	return nil
+}
+
+func (f *leakDetectionFeature) CleanupVerification() bool {
+	if cf, ok := f.Feature.(types.CleanupVerifiedFeature); ok {
+		return cf.CleanupVerification()
+	}
+	return false
+}
+
+func (f *leakDetectionFeature) Profile() (cpuProfileDir, memProfileDir string) {
+	if pf, ok := f.Feature.(types.ProfiledFeature); ok {
+		return pf.Profile()
+	}
+	return "", ""
+}
+
+// WithLeakDetection returns a feature wrapping f that verifies that it does not leak resources.
+// The resources of the given kinds found in the namespace of the environment config are listed
+// before the setup of the feature, and again after its teardown: the feature fails if resources
+// that were not there before its setup remain, the resources being deleted being ignored. All
+// namespaces are watched when the environment config has no namespace, which is only meant for
+// cluster-scoped kinds.
+func WithLeakDetection(f types.Feature, gvks ...schema.GroupVersionKind) types.Feature {
+	feat := &leakDetectionFeature{Feature: f}
+
+	key := leakSnapshotKey{feature: f.Name()}
+	feat.steps = append(feat.steps, newStep(fmt.Sprintf("%s-leak-snapshot", f.Name()), LevelPreSetup, func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		snapshot, err := takeLeakSnapshot(ctx, cfg, cfg.Namespace(), gvks)
+		if err != nil {
+			t.Fatalf("Failed to list the resources watched for leaks: %s", err)
+		}
+		return context.WithValue(ctx, key, snapshot)
+	}))
+	feat.steps = append(feat.steps, f.Steps()...)
+	check := newStep(fmt.Sprintf("%s-leak-check", f.Name()), LevelTeardown, func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		before, ok := ctx.Value(key).(*leakSnapshot)
+		if !ok {
+			t.Errorf("No snapshot of the resources watched for leaks found in the context")
+			return ctx
+		}
+		after, err := takeLeakSnapshot(ctx, cfg, before.namespace, gvks)
+		if err != nil {
+			t.Errorf("Failed to list the resources watched for leaks: %s", err)
+			return ctx
+		}
+		for _, gvk := range gvks {
+			if leaked := after.extras(before, gvk); len(leaked) > 0 {
+				t.Errorf("Feature %q leaked %d %s resource(s) in namespace %q: %v", f.Name(), len(leaked), gvk.Kind, before.namespace, leaked)
+			}
+		}
+		return ctx
+	})
+	// the check runs after the teardown steps of the feature
+	check.runLast = true
+	feat.steps = append(feat.steps, check)
+	return feat
+}
+
+// takeLeakSnapshot lists the resources of the given kinds in the namespace, but the ones being deleted
+func takeLeakSnapshot(ctx context.Context, cfg *envconf.Config, namespace string, gvks []schema.GroupVersionKind) (*leakSnapshot, error) {
+	client, err := cfg.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &leakSnapshot{namespace: namespace, names: make(map[schema.GroupVersionKind]map[string]struct{})}
+	for _, gvk := range gvks {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err := client.Resources().GetControllerRuntimeClient().List(ctx, list, cr.InNamespace(namespace)); err != nil {
+			return nil, fmt.Errorf("list %s: %w", gvk, err)
+		}
+		names := make(map[string]struct{}, len(list.Items))
+		for _, item := range list.Items {
+			// a resource being deleted, e.g. waiting for its finalizers, is not leaked
+			if item.GetDeletionTimestamp() != nil {
+				continue
+			}
+			names[item.GetNamespace()+"/"+item.GetName()] = struct{}{}
+		}
+		snapshot.names[gvk] = names
+	}
+	return snapshot, nil
+}
+
+// extras returns the sorted names of the resources of the kind that are not in the previous snapshot
+func (s *leakSnapshot) extras(previous *leakSnapshot, gvk schema.GroupVersionKind) []string {
+	var extras []string
+	for name := range s.names[gvk] {
+		if _, ok := previous.names[gvk][name]; !ok {
+			extras = append(extras, name)
+		}
+	}
+	sort.Strings(extras)
+	return extras
+}
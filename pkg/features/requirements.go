@@ -0,0 +1,90 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package features
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	klog "k8s.io/klog/v2"
+
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+// WithResourceRequirements gates the feature with a precondition on the capacity of the cluster:
+// the feature is skipped when the cluster has less than minNodes schedulable nodes or when the
+// CPU or memory allocatable by these nodes, summed up, is lower than minCPU or minMem. A zero
+// value disables the corresponding requirement. This prevents the features needing a large
+// cluster from failing misleadingly on an under-provisioned one, such as a local kind cluster.
+// The nodes are listed with the client of the environment config.
+func (b *FeatureBuilder) WithResourceRequirements(minNodes int, minCPU, minMem resource.Quantity) *FeatureBuilder {
+	name := resourceRequirementsName(minNodes, minCPU, minMem)
+	return b.WithPrecondition(name, func(ctx context.Context, cfg *envconf.Config) (bool, error) {
+		client, err := cfg.NewClient()
+		if err != nil {
+			return false, err
+		}
+		var nodes corev1.NodeList
+		if err := client.Resources().List(ctx, &nodes); err != nil {
+			return false, fmt.Errorf("failed to list the nodes: %w", err)
+		}
+		count, cpu, mem := allocatableCapacity(nodes.Items)
+		if count < minNodes || cpu.Cmp(minCPU) < 0 || mem.Cmp(minMem) < 0 {
+			klog.Infof("Feature %q requires a %s, the cluster has %d schedulable nodes with %s CPU and %s memory allocatable",
+				b.feat.name, name, count, cpu.String(), mem.String())
+			return false, nil
+		}
+		return true, nil
+	})
+}
+
+// allocatableCapacity returns the number of schedulable nodes and the sum of their allocatable CPU and memory
+func allocatableCapacity(nodes []corev1.Node) (count int, cpu, mem resource.Quantity) {
+	for _, node := range nodes {
+		if node.Spec.Unschedulable {
+			continue
+		}
+		count++
+		cpu.Add(node.Status.Allocatable[corev1.ResourceCPU])
+		mem.Add(node.Status.Allocatable[corev1.ResourceMemory])
+	}
+	return count, cpu, mem
+}
+
+// resourceRequirementsName describes the requirements, e.g. "cluster with 3 nodes, 4 CPU and 8Gi memory allocatable"
+func resourceRequirementsName(minNodes int, minCPU, minMem resource.Quantity) string {
+	var parts []string
+	if minNodes > 0 {
+		parts = append(parts, fmt.Sprintf("%d nodes", minNodes))
+	}
+	if !minCPU.IsZero() {
+		parts = append(parts, fmt.Sprintf("%s CPU", minCPU.String()))
+	}
+	if !minMem.IsZero() {
+		parts = append(parts, fmt.Sprintf("%s memory", minMem.String()))
+	}
+	if len(parts) == 0 {
+		return "cluster with allocatable resources"
+	}
+	if len(parts) > 1 {
+		parts = []string{strings.Join(parts[:len(parts)-1], ", ") + " and " + parts[len(parts)-1]}
+	}
+	return fmt.Sprintf("cluster with %s allocatable", parts[0])
+}
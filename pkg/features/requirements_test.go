@@ -0,0 +1,91 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package features
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"sigs.k8s.io/e2e-framework/internal/testutil"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/types"
+)
+
+func TestWithResourceRequirements(t *testing.T) {
+	node := func(name, cpu, mem string, unschedulable bool) cr.Object {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       corev1.NodeSpec{Unschedulable: unschedulable},
+			Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(mem),
+			}},
+		}
+	}
+	client := testutil.NewFakeClient(interceptor.Funcs{},
+		node("node-1", "2", "4Gi", false),
+		node("node-2", "1500m", "4Gi", false),
+		node("cordoned", "8", "32Gi", true),
+	)
+	cfg := envconf.New().WithClient(client)
+
+	tests := []struct {
+		name     string
+		minNodes int
+		minCPU   string
+		minMem   string
+		met      bool
+	}{
+		{name: "met", minNodes: 2, minCPU: "3500m", minMem: "8Gi", met: true},
+		{name: "not enough nodes", minNodes: 3, minCPU: "0", minMem: "0"},
+		{name: "not enough cpu", minNodes: 1, minCPU: "4", minMem: "0"},
+		{name: "not enough memory", minNodes: 0, minCPU: "0", minMem: "9Gi"},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			f := New("feature").WithResourceRequirements(test.minNodes, resource.MustParse(test.minCPU), resource.MustParse(test.minMem)).Feature()
+			preconditions := f.(types.PreconditionedFeature).Preconditions()
+			if len(preconditions) != 1 {
+				t.Fatalf("expected a single precondition, got %d", len(preconditions))
+			}
+			met, err := preconditions[0].Check(context.Background(), cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if met != test.met {
+				t.Errorf("expected the requirements to be met: %t, got %t", test.met, met)
+			}
+		})
+	}
+}
+
+func TestResourceRequirementsName(t *testing.T) {
+	name := resourceRequirementsName(3, resource.MustParse("4"), resource.MustParse("8Gi"))
+	if name != "cluster with 3 nodes, 4 CPU and 8Gi memory allocatable" {
+		t.Errorf("unexpected name: %s", name)
+	}
+	if name := resourceRequirementsName(0, resource.Quantity{}, resource.MustParse("1Gi")); name != "cluster with 1Gi memory allocatable" {
+		t.Errorf("unexpected name: %s", name)
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package features
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	cr "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+// snapshotState dumps the resources of the given kinds found in the namespace of the config
+// to dir, logging the kinds that cannot be listed or dumped
+func snapshotState(ctx context.Context, t *testing.T, cfg *envconf.Config, gvks []schema.GroupVersionKind, dir string) {
+	client, err := cfg.NewClient()
+	if err != nil {
+		t.Logf("Failed to snapshot the state of the cluster to %s: %s", dir, err)
+		return
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Logf("Failed to snapshot the state of the cluster to %s: %s", dir, err)
+		return
+	}
+	for _, gvk := range gvks {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err := client.Resources().GetControllerRuntimeClient().List(ctx, list, cr.InNamespace(cfg.Namespace())); err != nil {
+			t.Logf("Failed to list the %s resources of the state snapshot: %s", gvk, err)
+			continue
+		}
+		data, err := marshalSnapshot(list.Items)
+		if err != nil {
+			t.Logf("Failed to dump the %s resources of the state snapshot: %s", gvk, err)
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(dir, snapshotFileName(gvk)), data, 0o644); err != nil {
+			t.Logf("Failed to dump the %s resources of the state snapshot: %s", gvk, err)
+		}
+	}
+}
+
+// marshalSnapshot marshals the resources sorted by namespace and name as a multi-document YAML
+func marshalSnapshot(items []unstructured.Unstructured) ([]byte, error) {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].GetNamespace() != items[j].GetNamespace() {
+			return items[i].GetNamespace() < items[j].GetNamespace()
+		}
+		return items[i].GetName() < items[j].GetName()
+	})
+	var buf bytes.Buffer
+	for _, item := range items {
+		data, err := yaml.Marshal(item.Object)
+		if err != nil {
+			return nil, fmt.Errorf("marshal %s/%s: %w", item.GetNamespace(), item.GetName(), err)
+		}
+		buf.WriteString("---\n")
+		buf.Write(data)
+	}
+	return buf.Bytes(), nil
+}
+
+// snapshotFileName returns the name of the file the resources of the kind are dumped to
+func snapshotFileName(gvk schema.GroupVersionKind) string {
+	name := gvk.Kind + "." + gvk.Version
+	if gvk.Group != "" {
+		name += "." + gvk.Group
+	}
+	return strings.ToLower(name) + ".yaml"
+}
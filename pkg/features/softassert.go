@@ -0,0 +1,107 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package features
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// SoftAssertions collects the failures of several checks of a step so that they are all
+// reported, instead of the step stopping at the first one. The collected failures are reported
+// at once with t.Error by Verify, which fails the test of the step. Verify should be called
+// before the step returns, so that the failures are accounted for in the result of the step.
+//
+//	Assess("deployment", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+//		soft := features.NewSoftAssertions(t)
+//		defer soft.Verify()
+//		soft.Check(dep.Status.ReadyReplicas == 3, "expected 3 ready replicas, got %d", dep.Status.ReadyReplicas)
+//		soft.NoError(checkService(ctx, cfg), "service")
+//		return ctx
+//	})
+type SoftAssertions struct {
+	mu       sync.Mutex
+	t        *testing.T
+	failures []string
+}
+
+// NewSoftAssertions returns soft assertions whose failures are reported on t by Verify. The
+// failures not verified yet when the test completes are still reported then, but only once
+// the events and the results of the step have been recorded by the environment.
+func NewSoftAssertions(t *testing.T) *SoftAssertions {
+	sa := &SoftAssertions{t: t}
+	t.Cleanup(sa.Verify)
+	return sa
+}
+
+// Verify reports the failures recorded since the last call at once with t.Error, which fails
+// the test, and clears them
+func (sa *SoftAssertions) Verify() {
+	if msg := sa.flush(); msg != "" {
+		sa.t.Helper()
+		sa.t.Error(msg)
+	}
+}
+
+// Errorf records a failure formatted like fmt.Sprintf
+func (sa *SoftAssertions) Errorf(format string, args ...any) {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	sa.failures = append(sa.failures, fmt.Sprintf(format, args...))
+}
+
+// Check records a failure formatted like fmt.Sprintf when the condition is false, and
+// returns the condition
+func (sa *SoftAssertions) Check(condition bool, format string, args ...any) bool {
+	if !condition {
+		sa.Errorf(format, args...)
+	}
+	return condition
+}
+
+// NoError records a failure prefixed with msg when err is not nil, and returns true if it is nil
+func (sa *SoftAssertions) NoError(err error, msg string) bool {
+	if err != nil {
+		sa.Errorf("%s: %s", msg, err)
+	}
+	return err == nil
+}
+
+// Failed returns true if a failure was recorded since the last call of Verify
+func (sa *SoftAssertions) Failed() bool {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	return len(sa.failures) > 0
+}
+
+// flush returns the aggregated message of the recorded failures, if any, and clears them
+func (sa *SoftAssertions) flush() string {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	if len(sa.failures) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d soft assertion(s) failed:", len(sa.failures))
+	for i, failure := range sa.failures {
+		fmt.Fprintf(&sb, "\n  %d. %s", i+1, failure)
+	}
+	sa.failures = nil
+	return sb.String()
+}
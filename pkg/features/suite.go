@@ -0,0 +1,78 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package features
+
+import (
+	"sigs.k8s.io/e2e-framework/pkg/types"
+)
+
+// SuiteBuilder is a type to define a named suite of
+// features sharing setup and teardown operations
+type SuiteBuilder struct {
+	suite *defaultSuite
+}
+
+type defaultSuite struct {
+	name      string
+	setups    []types.EnvFunc
+	teardowns []types.EnvFunc
+	features  []types.Feature
+}
+
+// NewSuite creates a builder for a suite with the given name
+func NewSuite(name string) *SuiteBuilder {
+	return &SuiteBuilder{suite: &defaultSuite{name: name}}
+}
+
+// Setup adds operations executed before the features of the suite
+func (b *SuiteBuilder) Setup(funcs ...types.EnvFunc) *SuiteBuilder {
+	b.suite.setups = append(b.suite.setups, funcs...)
+	return b
+}
+
+// Teardown adds operations executed after the features of the suite
+func (b *SuiteBuilder) Teardown(funcs ...types.EnvFunc) *SuiteBuilder {
+	b.suite.teardowns = append(b.suite.teardowns, funcs...)
+	return b
+}
+
+// WithFeatures adds features to the suite
+func (b *SuiteBuilder) WithFeatures(features ...types.Feature) *SuiteBuilder {
+	b.suite.features = append(b.suite.features, features...)
+	return b
+}
+
+// Suite returns the suite configured by the builder
+func (b *SuiteBuilder) Suite() types.Suite {
+	return b.suite
+}
+
+func (s *defaultSuite) Name() string {
+	return s.name
+}
+
+func (s *defaultSuite) Setups() []types.EnvFunc {
+	return s.setups
+}
+
+func (s *defaultSuite) Teardowns() []types.EnvFunc {
+	return s.teardowns
+}
+
+func (s *defaultSuite) Features() []types.Feature {
+	return s.features
+}
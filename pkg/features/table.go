@@ -17,7 +17,11 @@ limitations under the License.
 package features
 
 import (
+	"context"
 	"fmt"
+	"testing"
+
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
 )
 
 type TableRow struct {
@@ -54,3 +58,23 @@ func (table Table) Build(args ...string) *FeatureBuilder {
 	}
 	return f
 }
+
+// AssessEach adds an assessment step per item to the feature, which makes table-driven
+// assessments built in a loop ergonomic. The assessments are named after baseName and the
+// index of their item, e.g. "replicas-0", followed by the item when it is a fmt.Stringer,
+// e.g. "replicas-0-small". The index keeps the names unique, so that each assessment can be
+// selected with the -run flag of go test or the --assess flag.
+func AssessEach[T any](b *FeatureBuilder, baseName string, items []T, fn func(ctx context.Context, t *testing.T, cfg *envconf.Config, item T)) *FeatureBuilder {
+	for i, item := range items {
+		item := item
+		name := fmt.Sprintf("%s-%d", baseName, i)
+		if s, ok := any(item).(fmt.Stringer); ok {
+			name = fmt.Sprintf("%s-%s", name, s.String())
+		}
+		b.Assess(name, func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			fn(ctx, t, cfg, item)
+			return ctx
+		})
+	}
+	return b
+}
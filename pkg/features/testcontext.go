@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package features
+
+import (
+	"context"
+	"testing"
+
+	"sigs.k8s.io/e2e-framework/klient"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+// TestContext bundles the context, *testing.T and environment config passed to a step,
+// along with convenience accessors. It is used by the steps added with AssessCtx.
+type TestContext struct {
+	ctx context.Context
+	t   *testing.T
+	cfg *envconf.Config
+	// soft is created on the first call of SoftAssertions
+	soft *SoftAssertions
+}
+
+// ContextFunc is the signature of the assessments added with AssessCtx
+type ContextFunc func(tc *TestContext)
+
+// Context returns the context of the step
+func (tc *TestContext) Context() context.Context {
+	return tc.ctx
+}
+
+// WithContext replaces the context of the step. The context is passed down to the next
+// steps of the feature once the step completes, like the context returned by a Func.
+func (tc *TestContext) WithContext(ctx context.Context) {
+	tc.ctx = ctx
+}
+
+// T returns the *testing.T of the step
+func (tc *TestContext) T() *testing.T {
+	return tc.t
+}
+
+// Config returns the environment config
+func (tc *TestContext) Config() *envconf.Config {
+	return tc.cfg
+}
+
+// Client returns the client of the environment config, failing the step if it cannot be created
+func (tc *TestContext) Client() klient.Client {
+	client, err := tc.cfg.NewClient()
+	if err != nil {
+		tc.t.Fatalf("Failed to create the client of the environment: %s", err)
+	}
+	return client
+}
+
+// Namespace returns the namespace of the environment config
+func (tc *TestContext) Namespace() string {
+	return tc.cfg.Namespace()
+}
+
+// Log formats its arguments like fmt.Sprintf and records the text in the log of the step
+func (tc *TestContext) Log(format string, args ...any) {
+	tc.t.Helper()
+	tc.t.Logf(format, args...)
+}
+
+// SoftAssertions returns the soft assertions of the step, whose failures are all reported when the step returns
+func (tc *TestContext) SoftAssertions() *SoftAssertions {
+	if tc.soft == nil {
+		tc.soft = NewSoftAssertions(tc.t)
+	}
+	return tc.soft
+}
+
+// contextFunc adapts a ContextFunc to a Func
+func contextFunc(fn ContextFunc) Func {
+	return func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		tc := &TestContext{ctx: ctx, t: t, cfg: cfg}
+		defer func() {
+			if tc.soft != nil {
+				tc.soft.Verify()
+			}
+		}()
+		fn(tc)
+		return tc.ctx
+	}
+}
@@ -40,6 +40,19 @@ const (
 	flagFailFast                = "fail-fast"
 	flagDisableGracefulTeardown = "disable-graceful-teardown"
 	flagContext                 = "context"
+	flagSummarizeSkips          = "summarize-skips"
+	flagShardIndex              = "shard-index"
+	flagShardCount              = "shard-count"
+	flagAssessmentEvents        = "assessment-events"
+	flagReuseCluster            = "reuse-cluster"
+	flagFailuresManifest        = "failures-manifest"
+	flagRerunFailed             = "rerun-failed"
+	flagSkipSetup               = "skip-setup"
+	flagSkipFinish              = "skip-finish"
+	flagColor                   = "color"
+	flagNamespaceEnv            = "namespace-env"
+	flagFeaturesFile            = "features-file"
+	flagTimingMetrics           = "timing-metrics"
 )
 
 // Supported flag definitions
@@ -54,7 +67,7 @@ var (
 	}
 	labelsFlag = flag.Flag{
 		Name:  flagLabelsName,
-		Usage: "Comma-separated key=value to filter features by labels",
+		Usage: "Comma-separated key=value to filter features by labels, key!=value excluding the features with the label",
 	}
 	kubecfgFlag = flag.Flag{
 		Name:  flagKubecofigName,
@@ -96,6 +109,58 @@ var (
 		Name:  flagContext,
 		Usage: "The name of the kubeconfig context to use",
 	}
+	summarizeSkipsFlag = flag.Flag{
+		Name:  flagSummarizeSkips,
+		Usage: "Report features skipped by the filters as a single summary line at the end of the run instead of one message each",
+	}
+	shardIndexFlag = flag.Flag{
+		Name:  flagShardIndex,
+		Usage: "Zero based index of the shard of features to run. Used together with --shard-count",
+	}
+	shardCountFlag = flag.Flag{
+		Name:  flagShardCount,
+		Usage: "Number of shards the features are partitioned into. Each feature runs on exactly one shard (optional)",
+	}
+	assessmentEventsFlag = flag.Flag{
+		Name:  flagAssessmentEvents,
+		Usage: "Log a JSON event at the start and end of each assessment, to surface assessment timings in the go test -json output",
+	}
+	reuseClusterFlag = flag.Flag{
+		Name:  flagReuseCluster,
+		Usage: "Keep the clusters at the end of the run instead of destroying them, so that the next runs can reuse them",
+	}
+	failuresManifestFlag = flag.Flag{
+		Name:  flagFailuresManifest,
+		Usage: "Path of a file to write the names of the failed features to at the end of the run, one per line (optional)",
+	}
+	rerunFailedFlag = flag.Flag{
+		Name:  flagRerunFailed,
+		Usage: "Path of a failures manifest written by a previous run with --failures-manifest. Only the features listed in it are run (optional)",
+	}
+	skipSetupFlag = flag.Flag{
+		Name:  flagSkipSetup,
+		Usage: "Skip the Setup operations of the test suite, e.g. to run the features against an already prepared cluster",
+	}
+	skipFinishFlag = flag.Flag{
+		Name:  flagSkipFinish,
+		Usage: "Skip the Finish operations of the test suite, e.g. to keep the cluster prepared for the next runs",
+	}
+	namespaceEnvFlag = flag.Flag{
+		Name:  flagNamespaceEnv,
+		Usage: "Name of an environment variable providing a pre-created namespace to use for testing when --namespace is not set (optional)",
+	}
+	featuresFileFlag = flag.Flag{
+		Name:  flagFeaturesFile,
+		Usage: "Path of a file listing the names of the features to run, one per line. Only the listed features are run, in the order of the file (optional)",
+	}
+	colorFlag = flag.Flag{
+		Name:  flagColor,
+		Usage: "Color the skip and failure messages, unless the NO_COLOR environment variable is set",
+	}
+	timingMetricsFlag = flag.Flag{
+		Name:  flagTimingMetrics,
+		Usage: "Path of a .prom file to write the durations of the features and assessments to at the end of the run, for the node exporter textfile collector (optional)",
+	}
 )
 
 // EnvFlags surfaces all resolved flag values for the testing framework
@@ -113,6 +178,20 @@ type EnvFlags struct {
 	failFast                bool
 	disableGracefulTeardown bool
 	kubeContext             string
+	summarizeSkips          bool
+	shardIndex              int
+	shardCount              int
+	assessmentEvents        bool
+	reuseCluster            bool
+	failuresManifest        string
+	rerunFailed             string
+	skipSetup               bool
+	skipFinish              bool
+	color                   bool
+	namespaceEnv            string
+	featuresFile            string
+	timingMetrics           string
+	selectorErrors          []error
 }
 
 // Feature returns value for `-feature` flag
@@ -192,6 +271,82 @@ func (f *EnvFlags) KubeContext() string {
 	return f.kubeContext
 }
 
+// SummarizeSkips is used to indicate if the features skipped by the filters should be
+// reported as a single summary at the end of the run instead of one skip message each
+func (f *EnvFlags) SummarizeSkips() bool {
+	return f.summarizeSkips
+}
+
+// ShardIndex returns the zero based index of the shard of features to run
+func (f *EnvFlags) ShardIndex() int {
+	return f.shardIndex
+}
+
+// ShardCount returns the number of shards the features are partitioned into.
+// A value of 0 disables sharding.
+func (f *EnvFlags) ShardCount() int {
+	return f.shardCount
+}
+
+// AssessmentEvents is used to indicate if JSON events should be logged at the start
+// and end of each assessment
+func (f *EnvFlags) AssessmentEvents() bool {
+	return f.assessmentEvents
+}
+
+// ReuseCluster is used to indicate if the clusters created by the test suite should be
+// kept at the end of the run so that they can be reused by the next runs
+func (f *EnvFlags) ReuseCluster() bool {
+	return f.reuseCluster
+}
+
+// FailuresManifest returns the path of the file the failed features are written to
+func (f *EnvFlags) FailuresManifest() string {
+	return f.failuresManifest
+}
+
+// RerunFailed returns the path of the failures manifest listing the features to run
+func (f *EnvFlags) RerunFailed() string {
+	return f.rerunFailed
+}
+
+// SkipSetup is used to indicate if the Setup operations of the test suite should be skipped
+func (f *EnvFlags) SkipSetup() bool {
+	return f.skipSetup
+}
+
+// SkipFinish is used to indicate if the Finish operations of the test suite should be skipped
+func (f *EnvFlags) SkipFinish() bool {
+	return f.skipFinish
+}
+
+// Color is used to indicate if the skip and failure messages should be colored
+func (f *EnvFlags) Color() bool {
+	return f.color
+}
+
+// NamespaceEnv returns the name of the environment variable providing the namespace when the namespace flag is not set
+func (f *EnvFlags) NamespaceEnv() string {
+	return f.namespaceEnv
+}
+
+// FeaturesFile returns the path of the file listing the features to run
+func (f *EnvFlags) FeaturesFile() string {
+	return f.featuresFile
+}
+
+// TimingMetrics returns the path of the file the durations of the features and assessments are written to
+func (f *EnvFlags) TimingMetrics() string {
+	return f.timingMetrics
+}
+
+// SelectorErrors returns the errors raised while parsing the `-labels` and `-skip-labels`
+// selectors. Malformed selectors do not fail the parsing of the flags so that they can be
+// reported along with the other problems of the environment configuration.
+func (f *EnvFlags) SelectorErrors() []error {
+	return f.selectorErrors
+}
+
 // ParseArgs parses the specified args from global flag.CommandLine
 // and returns a set of environment flag values.
 func ParseArgs(args []string) (*EnvFlags, error) {
@@ -207,10 +362,24 @@ func ParseArgs(args []string) (*EnvFlags, error) {
 		failFast                bool
 		disableGracefulTeardown bool
 		kubeContext             string
+		summarizeSkips          bool
+		shardIndex              int
+		shardCount              int
+		assessmentEvents        bool
+		reuseCluster            bool
+		failuresManifest        string
+		rerunFailed             string
+		skipSetup               bool
+		skipFinish              bool
+		color                   bool
+		namespaceEnv            string
+		featuresFile            string
+		timingMetrics           string
 	)
 
 	labels := make(LabelsMap)
 	skipLabels := make(LabelsMap)
+	var selectorErrors []error
 
 	if flag.Lookup(featureFlag.Name) == nil {
 		flag.StringVar(&feature, featureFlag.Name, featureFlag.DefValue, featureFlag.Usage)
@@ -229,11 +398,11 @@ func ParseArgs(args []string) (*EnvFlags, error) {
 	}
 
 	if flag.Lookup(labelsFlag.Name) == nil {
-		flag.Var(&labels, labelsFlag.Name, labelsFlag.Usage)
+		flag.Var(&selectorValue{flagName: labelsFlag.Name, labels: labels, negated: skipLabels, errs: &selectorErrors}, labelsFlag.Name, labelsFlag.Usage)
 	}
 
 	if flag.Lookup(skipLabelsFlag.Name) == nil {
-		flag.Var(&skipLabels, skipLabelsFlag.Name, skipLabelsFlag.Usage)
+		flag.Var(&selectorValue{flagName: skipLabelsFlag.Name, labels: skipLabels, errs: &selectorErrors}, skipLabelsFlag.Name, skipLabelsFlag.Usage)
 	}
 
 	if flag.Lookup(skipAssessmentFlag.Name) == nil {
@@ -264,6 +433,58 @@ func ParseArgs(args []string) (*EnvFlags, error) {
 		flag.StringVar(&kubeContext, contextFlag.Name, contextFlag.DefValue, contextFlag.Usage)
 	}
 
+	if flag.Lookup(summarizeSkipsFlag.Name) == nil {
+		flag.BoolVar(&summarizeSkips, summarizeSkipsFlag.Name, false, summarizeSkipsFlag.Usage)
+	}
+
+	if flag.Lookup(shardIndexFlag.Name) == nil {
+		flag.IntVar(&shardIndex, shardIndexFlag.Name, 0, shardIndexFlag.Usage)
+	}
+
+	if flag.Lookup(shardCountFlag.Name) == nil {
+		flag.IntVar(&shardCount, shardCountFlag.Name, 0, shardCountFlag.Usage)
+	}
+
+	if flag.Lookup(assessmentEventsFlag.Name) == nil {
+		flag.BoolVar(&assessmentEvents, assessmentEventsFlag.Name, false, assessmentEventsFlag.Usage)
+	}
+
+	if flag.Lookup(reuseClusterFlag.Name) == nil {
+		flag.BoolVar(&reuseCluster, reuseClusterFlag.Name, false, reuseClusterFlag.Usage)
+	}
+
+	if flag.Lookup(failuresManifestFlag.Name) == nil {
+		flag.StringVar(&failuresManifest, failuresManifestFlag.Name, failuresManifestFlag.DefValue, failuresManifestFlag.Usage)
+	}
+
+	if flag.Lookup(rerunFailedFlag.Name) == nil {
+		flag.StringVar(&rerunFailed, rerunFailedFlag.Name, rerunFailedFlag.DefValue, rerunFailedFlag.Usage)
+	}
+
+	if flag.Lookup(skipSetupFlag.Name) == nil {
+		flag.BoolVar(&skipSetup, skipSetupFlag.Name, false, skipSetupFlag.Usage)
+	}
+
+	if flag.Lookup(skipFinishFlag.Name) == nil {
+		flag.BoolVar(&skipFinish, skipFinishFlag.Name, false, skipFinishFlag.Usage)
+	}
+
+	if flag.Lookup(colorFlag.Name) == nil {
+		flag.BoolVar(&color, colorFlag.Name, false, colorFlag.Usage)
+	}
+
+	if flag.Lookup(namespaceEnvFlag.Name) == nil {
+		flag.StringVar(&namespaceEnv, namespaceEnvFlag.Name, namespaceEnvFlag.DefValue, namespaceEnvFlag.Usage)
+	}
+
+	if flag.Lookup(featuresFileFlag.Name) == nil {
+		flag.StringVar(&featuresFile, featuresFileFlag.Name, featuresFileFlag.DefValue, featuresFileFlag.Usage)
+	}
+
+	if flag.Lookup(timingMetricsFlag.Name) == nil {
+		flag.StringVar(&timingMetrics, timingMetricsFlag.Name, timingMetricsFlag.DefValue, timingMetricsFlag.Usage)
+	}
+
 	flag.Var(featuregate.FeatureGate, "feature-gates", "A set of key=value pairs that describe feature gates for alpha/experimental features. Options are: \n"+strings.Join(featuregate.FeatureGate.KnownFeatures(), "\n"))
 
 	// Enable klog/v2 flag integration
@@ -297,9 +518,57 @@ func ParseArgs(args []string) (*EnvFlags, error) {
 		failFast:                failFast,
 		disableGracefulTeardown: disableGracefulTeardown,
 		kubeContext:             kubeContext,
+		summarizeSkips:          summarizeSkips,
+		shardIndex:              shardIndex,
+		shardCount:              shardCount,
+		assessmentEvents:        assessmentEvents,
+		reuseCluster:            reuseCluster,
+		failuresManifest:        failuresManifest,
+		rerunFailed:             rerunFailed,
+		skipSetup:               skipSetup,
+		skipFinish:              skipFinish,
+		color:                   color,
+		namespaceEnv:            namespaceEnv,
+		featuresFile:            featuresFile,
+		timingMetrics:           timingMetrics,
+		selectorErrors:          selectorErrors,
 	}, nil
 }
 
+// selectorValue parses the label selectors of the named flag into labels. The key!=value
+// selectors are parsed into negated, when set, i.e. `--labels k!=v` is the same as
+// `--skip-labels k=v`. Instead of failing the parsing of the flags, malformed selectors are
+// recorded in errs along with the parse error.
+type selectorValue struct {
+	flagName string
+	labels   LabelsMap
+	negated  LabelsMap
+	errs     *[]error
+}
+
+func (v *selectorValue) String() string {
+	return v.labels.String()
+}
+
+func (v *selectorValue) Set(val string) error {
+	for _, selector := range strings.Split(val, ",") {
+		var err error
+		if key, value, found := strings.Cut(selector, "!="); found {
+			if v.negated == nil {
+				err = fmt.Errorf("label format error: %q cannot be negated", selector)
+			} else {
+				err = v.negated.Set(key + "=" + value)
+			}
+		} else {
+			err = v.labels.Set(selector)
+		}
+		if err != nil {
+			*v.errs = append(*v.errs, fmt.Errorf("invalid --%s selector %q: %w", v.flagName, val, err))
+		}
+	}
+	return nil
+}
+
 type LabelsMap map[string][]string
 
 func (m LabelsMap) String() string {
@@ -313,7 +582,7 @@ func (m LabelsMap) Set(val string) error {
 		// split into k,v
 		kv := strings.Split(label, "=")
 		if len(kv) != 2 {
-			return fmt.Errorf("label format error: %s", label)
+			return fmt.Errorf("label format error: %q is not a key=value pair", label)
 		}
 		k := strings.TrimSpace(kv[0])
 		v := strings.TrimSpace(kv[1])
@@ -32,7 +32,7 @@ func TestParseFlags(t *testing.T) {
 	}{
 		{
 			name:  "with all",
-			args:  []string{"-assess", "volume test", "--feature", "beta", "--labels", "k0=v0, k0=v01, k1=v1, k1=v11, k2=v2", "--skip-labels", "k0=v0, k1=v1", "-skip-features", "networking", "-skip-assessment", "volume test", "-parallel", "--dry-run", "--disable-graceful-teardown", "--feature-gates", "ReverseTestFinishExecutionOrder=true"},
+			args:  []string{"-assess", "volume test", "--feature", "beta", "--labels", "k0=v0, k0=v01, k1=v1, k1=v11, k2=v2", "--skip-labels", "k0=v0, k1=v1", "-skip-features", "networking", "-skip-assessment", "volume test", "-parallel", "--dry-run", "--disable-graceful-teardown", "--skip-setup", "--skip-finish", "--feature-gates", "ReverseTestFinishExecutionOrder=true"},
 			flags: &EnvFlags{assess: "volume test", feature: "beta", labels: LabelsMap{"k0": {"v0", "v01"}, "k1": {"v1", "v11"}, "k2": {"v2"}}, skiplabels: LabelsMap{"k0": {"v0"}, "k1": {"v1"}}, skipFeatures: "networking", skipAssessments: "volume test"},
 		},
 	}
@@ -84,6 +84,10 @@ func TestParseFlags(t *testing.T) {
 				t.Errorf("unmatched flag parsed. Expected disableGracefulTeardown to be true")
 			}
 
+			if !testFlags.SkipSetup() || !testFlags.SkipFinish() {
+				t.Errorf("unmatched flag parsed. Expected skipSetup and skipFinish to be true")
+			}
+
 			if !featuregate.DefaultFeatureGate.Enabled(featuregate.ReverseTestFinishExecutionOrder) {
 				t.Errorf("unmatched flag parsed. Expected feature gate to be enabled")
 			}
@@ -91,6 +95,23 @@ func TestParseFlags(t *testing.T) {
 	}
 }
 
+func TestParseFlags_NegatedLabels(t *testing.T) {
+	flag.CommandLine = &flag.FlagSet{}
+	testFlags, err := ParseArgs([]string{"--labels", "env=prod,provider!=kind", "--skip-labels", "tier!=db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(testFlags.Labels(), LabelsMap{"env": {"prod"}}) {
+		t.Errorf("unexpected labels: %v", testFlags.Labels())
+	}
+	if !reflect.DeepEqual(testFlags.SkipLabels(), LabelsMap{"provider": {"kind"}}) {
+		t.Errorf("expected the negated label to be skipped, got: %v", testFlags.SkipLabels())
+	}
+	if errs := testFlags.SelectorErrors(); len(errs) != 1 {
+		t.Errorf("expected an error for the negated skip label, got: %v", errs)
+	}
+}
+
 func TestLabelsMap_Contains(t *testing.T) {
 	type args struct {
 		key string
@@ -18,7 +18,10 @@ package types
 
 import (
 	"context"
+	"fmt"
+	"sort"
 	"testing"
+	"time"
 
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
 	"sigs.k8s.io/e2e-framework/pkg/flags"
@@ -44,16 +47,133 @@ type FeatureEnvFunc func(context.Context, *envconf.Config, *testing.T, Feature)
 // to caller. Meant for use with before/after test hooks.
 type TestEnvFunc func(context.Context, *envconf.Config, *testing.T) (context.Context, error)
 
+// MessageKind identifies the kind of a message formatted by a MessageFormatter
+type MessageKind string
+
+const (
+	// MessageSkip is the kind of the messages reporting why a feature, suite or assessment is skipped
+	MessageSkip MessageKind = "skip"
+	// MessageFailure is the kind of the messages reporting a fatal failure of a test
+	MessageFailure MessageKind = "failure"
+)
+
+// MessageFormatter formats the skip and fatal failure messages reported by the environment
+type MessageFormatter func(kind MessageKind, message string) string
+
+// LogRedactor rewrites a message logged by the environment, e.g. to mask sensitive values
+type LogRedactor func(message string) string
+
+// SkipHandler is invoked with the name of a feature and the reason why the
+// feature, or one of its assessments, is skipped
+type SkipHandler func(feature, reason string)
+
+// FixtureSetupFunc sets up a fixture registered on the environment and returns its value,
+// e.g. the address of a mock server, made available to the features requesting the fixture
+type FixtureSetupFunc func(context.Context, *envconf.Config) (any, error)
+
+// FixtureTeardownFunc tears down a fixture, given the value returned by its setup
+type FixtureTeardownFunc func(ctx context.Context, cfg *envconf.Config, value any) error
+
+// PanicHandler is invoked with the names of the feature and step that
+// panicked, the recovered value and the stack trace of the panic.
+type PanicHandler func(feature, step string, recovered any, stack []byte)
+
+// ActionInfo describes an action registered on an environment, e.g. with
+// Setup or BeforeEachFeature. It is a snapshot that can be used to introspect
+// the lifecycle of an environment without running it.
+type ActionInfo struct {
+	// Role is the name of the method used to register the action, e.g. "Setup"
+	Role string
+	// Funcs is the number of functions of the action
+	Funcs int
+	// HasCleanup is true for setup actions registered with SetupWithCleanup
+	HasCleanup bool
+}
+
+// RunStats counts the features and assessments processed during a test run.
+// Features and assessments are counted as run when they are executed, failed
+// ones included, and as skipped when they are not selected for the run.
+type RunStats struct {
+	FeaturesRun        int
+	FeaturesSkipped    int
+	FeaturesFailed     int
+	AssessmentsRun     int
+	AssessmentsSkipped int
+	AssessmentsFailed  int
+}
+
 // Environment represents an environment where
 // features can be tested.
 type Environment interface {
-	// WithContext returns a new Environment with a new context
+	// WithContext returns a new Environment with a new context and
+	// a copy of the actions registered so far
 	WithContext(context.Context) Environment
 
+	// WithMergedActions returns a new Environment combining the actions
+	// of the environment, run first, with the actions of another one
+	WithMergedActions(Environment) Environment
+
+	// Context returns the root context of the environment. Once Run has
+	// executed the Setup operations, this is the context they produced and
+	// it is the starting point of every subsequent Test call of the suite.
+	Context() context.Context
+
 	// Setup registers environment operations that are executed once
 	// prior to the environment being ready and prior to any test.
 	Setup(...EnvFunc) Environment
 
+	// SetupWithCleanup registers a setup operation paired with a cleanup
+	// operation that is executed at the end of the test suite only if
+	// the setup succeeded. Cleanups run after the Finish operations, in
+	// the reverse order of their setups.
+	SetupWithCleanup(setup, cleanup EnvFunc) Environment
+
+	// SetupOnProvider registers environment operations that are executed
+	// like the Setup ones, only when the cluster under test runs on the
+	// given provider. They are skipped on the other providers.
+	SetupOnProvider(provider string, funcs ...EnvFunc) Environment
+
+	// SetupWithTimeout registers environment operations that are executed
+	// like the Setup ones, each of them within its own timeout.
+	SetupWithTimeout(timeout time.Duration, funcs ...EnvFunc) Environment
+
+	// RegisterFixture registers a fixture set up before the first feature
+	// requesting it by name, shared by the features requesting it, and torn
+	// down once the last of them completed.
+	RegisterFixture(name string, setup FixtureSetupFunc, teardown FixtureTeardownFunc) Environment
+
+	// WithRunID sets a run-scoped correlation ID into the context of the
+	// environment, a random one being generated when the ID is empty
+	WithRunID(id string) Environment
+
+	// RequireEnvVars declares environment variables that must be set
+	// for the test suite to run. Run fails fast when any of them is missing.
+	RequireEnvVars(...string) Environment
+
+	// WithPerAssessmentNamespace runs each assessment in its own namespace,
+	// created with the given name prefix and deleted after the assessment.
+	WithPerAssessmentNamespace(prefix string) Environment
+
+	// WithMessageFormatter registers a formatter of the skip and fatal
+	// failure messages reported on the tests
+	WithMessageFormatter(MessageFormatter) Environment
+
+	// WithLogRedactor registers a redactor of the messages logged by the
+	// environment, such as skip reasons, failures and summaries
+	WithLogRedactor(LogRedactor) Environment
+
+	// WithBuildInfo sets the version and commit of the code under test,
+	// reported in the summary of the run
+	WithBuildInfo(version, commit string) Environment
+
+	// WithPanicHandler registers a handler invoked when a step of a
+	// feature panics, before the panic is converted to a test failure
+	WithPanicHandler(PanicHandler) Environment
+
+	// WithSkipHandler registers a handler invoked when a feature or one
+	// of its assessments is skipped, with the reason of the skip.
+	WithSkipHandler(SkipHandler) Environment
+
 	// BeforeEachTest registers environment funcs that are executed
 	// before each Env.Test(...)
 	BeforeEachTest(...TestEnvFunc) Environment
@@ -70,6 +190,16 @@ type Environment interface {
 	// This method surfaces context for further updates.
 	Test(*testing.T, ...Feature) context.Context
 
+	// TestWithContext executes test features the same way Test does,
+	// starting from the provided context instead of the root context
+	// of the environment.
+	TestWithContext(context.Context, *testing.T, ...Feature) context.Context
+
+	// TestSuite executes the features of a suite, surrounded by the setup
+	// and teardown operations of the suite. The suite operations are only
+	// executed if at least one of its features is selected to run.
+	TestSuite(*testing.T, Suite) context.Context
+
 	// TestInParallel executes a series of test features defined in a
 	// TestXXX function in parallel. This works the same way Test method
 	// does with the caveat that the features will all be run in parallel
@@ -83,8 +213,38 @@ type Environment interface {
 	// test suite.
 	Finish(...EnvFunc) Environment
 
+	// FinishWithTimeout registers environment operations that are executed
+	// like the Finish ones, each of them within its own timeout.
+	FinishWithTimeout(timeout time.Duration, funcs ...EnvFunc) Environment
+
+	// Actions returns a snapshot of the actions registered on the
+	// environment, in the order they were registered
+	Actions() []ActionInfo
+
 	// Run Launches the test suite from within a TestMain
 	Run(*testing.M) int
+
+	// RunE launches the test suite like Run but returns the error that
+	// prevented the tests from running instead of logging it, the tests
+	// not being run when a Setup operation fails
+	RunE(*testing.M) (int, error)
+
+	// RunWithStats launches the test suite like Run and also returns
+	// the statistics of the features and assessments of the run
+	RunWithStats(*testing.M) (int, RunStats)
+}
+
+// Suite groups features sharing setup and teardown operations
+// that are only worth executing when one of the features runs.
+type Suite interface {
+	// Name is the suite name
+	Name() string
+	// Setups returns the operations executed before the features of the suite
+	Setups() []EnvFunc
+	// Teardowns returns the operations executed after the features of the suite
+	Teardowns() []EnvFunc
+	// Features returns the features of the suite
+	Features() []Feature
 }
 
 type Labels = flags.LabelsMap
@@ -98,6 +258,10 @@ type Feature interface {
 	Steps() []Step
 }
 
+// Level is the phase of the lifecycle of a feature a step belongs to.
+// The steps of a feature are executed level by level, in the order of
+// the level weights (see Weight), and in the order they were added
+// within a level.
 type Level uint8
 
 const (
@@ -107,8 +271,64 @@ const (
 	LevelAssess
 	// LevelTeardown when doing the teardown phase
 	LevelTeardown
+	// LevelPreSetup when doing the phase preceding the setup phase
+	LevelPreSetup
+	// LevelPostAssess when doing the phase between the assess and teardown phases
+	LevelPostAssess
 )
 
+// levelWeights maps the levels to their execution order. The weights of the
+// levels are spaced out so that intermediate levels can be added later on
+// without changing the weights of the existing ones.
+var levelWeights = map[Level]int{
+	LevelPreSetup:   10,
+	LevelSetup:      20,
+	LevelAssess:     30,
+	LevelPostAssess: 40,
+	LevelTeardown:   50,
+}
+
+// Weight returns the execution order of the level: the steps of a level
+// with a lower weight are executed first. The weights are:
+//
+//	LevelPreSetup:   10
+//	LevelSetup:      20
+//	LevelAssess:     30
+//	LevelPostAssess: 40
+//	LevelTeardown:   50
+func (l Level) Weight() int {
+	return levelWeights[l]
+}
+
+func (l Level) String() string {
+	switch l {
+	case LevelPreSetup:
+		return "PreSetup"
+	case LevelSetup:
+		return "Setup"
+	case LevelAssess:
+		return "Assess"
+	case LevelPostAssess:
+		return "PostAssess"
+	case LevelTeardown:
+		return "Teardown"
+	default:
+		return fmt.Sprintf("Level(%d)", uint8(l))
+	}
+}
+
+// Levels returns all the levels, sorted by weight
+func Levels() []Level {
+	levels := make([]Level, 0, len(levelWeights))
+	for l := range levelWeights {
+		levels = append(levels, l)
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		return levels[i].Weight() < levels[j].Weight()
+	})
+	return levels
+}
+
 type StepFunc func(context.Context, *testing.T, *envconf.Config) context.Context
 
 type Step interface {
@@ -127,6 +347,39 @@ type DescribableStep interface {
 	Description() string
 }
 
+// OrderedStep is implemented by the steps that can be required to run
+// after the other steps of their level, regardless of declaration order
+type OrderedStep interface {
+	Step
+	// RunLast returns true if the step must run after the other steps of its level
+	RunLast() bool
+}
+
+// SerialStep is implemented by the assessments that must not run concurrently
+// with one another, e.g. because they use a shared singleton
+type SerialStep interface {
+	Step
+	// Serial returns true if the assessment must not run concurrently with other serial assessments
+	Serial() bool
+}
+
+// TimeBoundStep is implemented by the assessments whose execution is bound by a timeout
+// overriding the default assessment timeout of the environment config
+type TimeBoundStep interface {
+	Step
+	// Timeout returns the maximum duration of the assessment, zero meaning the default one
+	Timeout() time.Duration
+}
+
+// SuiteOnceStep is implemented by the setup steps that run at most once per test
+// suite, the first time a feature declaring them is executed
+type SuiteOnceStep interface {
+	Step
+	// OnceKey identifies the step across the features of the suite, the steps
+	// sharing the same key being considered identical
+	OnceKey() string
+}
+
 type DescribableFeature interface {
 	Feature
 
@@ -135,3 +388,111 @@ type DescribableFeature interface {
 	// feature.
 	Description() string
 }
+
+// VersionConstrainedFeature is a Feature that only applies to the Kubernetes
+// versions satisfying its version constraint.
+type VersionConstrainedFeature interface {
+	Feature
+
+	// KubernetesVersionConstraint returns a semver range, such as ">=1.27.0 <1.30.0", that
+	// the Kubernetes version of the cluster must satisfy for the feature to be tested.
+	KubernetesVersionConstraint() string
+}
+
+// TimeBoundFeature is a Feature whose whole execution is bound by a timeout.
+type TimeBoundFeature interface {
+	Feature
+
+	// Timeout returns the maximum duration of the feature, zero meaning no timeout.
+	Timeout() time.Duration
+}
+
+// MetadataFeature is a Feature carrying metadata, such as the owner team or the
+// ticket tracking it. The metadata is reported with the results of the feature
+// but does not affect its execution.
+type MetadataFeature interface {
+	Feature
+
+	// Metadata returns the metadata of the feature by key
+	Metadata() map[string]any
+}
+
+// PreconditionFunc checks whether a precondition of a feature is met
+type PreconditionFunc func(context.Context, *envconf.Config) (bool, error)
+
+// Precondition is a named check that must be met for a feature to be tested
+type Precondition struct {
+	// Name describes the precondition, e.g. "operator is installed"
+	Name string
+	// Check returns false when the precondition is not met
+	Check PreconditionFunc
+}
+
+// PreconditionedFeature is a Feature gated by preconditions. The feature is skipped
+// when one of its preconditions is not met and fails when one cannot be checked.
+type PreconditionedFeature interface {
+	Feature
+
+	// Preconditions returns the preconditions of the feature, in the order they are checked
+	Preconditions() []Precondition
+}
+
+// ConflictingFeature is a Feature that cannot be tested along with the named features,
+// e.g. because they conflict on a shared singleton.
+type ConflictingFeature interface {
+	Feature
+
+	// ConflictsWith returns the names of the features conflicting with the feature
+	ConflictsWith() []string
+}
+
+// ParallelAssessmentsFeature is a Feature whose assessments are independent from one
+// another and can run concurrently.
+type ParallelAssessmentsFeature interface {
+	Feature
+
+	// ParallelAssessments returns true if the assessments of the feature run concurrently
+	ParallelAssessments() bool
+}
+
+// ExclusiveFeature is a Feature that must not run concurrently with the other features of a test.
+type ExclusiveFeature interface {
+	Feature
+
+	// Exclusive returns true if no other feature may run while the feature runs
+	Exclusive() bool
+}
+
+// FixtureFeature is a Feature requesting fixtures registered on the environment.
+type FixtureFeature interface {
+	Feature
+
+	// Fixtures returns the names of the fixtures requested by the feature
+	Fixtures() []string
+}
+
+// EnvVarsFeature is a Feature setting environment variables of the test process while it runs.
+type EnvVarsFeature interface {
+	Feature
+
+	// EnvVars returns the environment variables set while the feature runs
+	EnvVars() map[string]string
+}
+
+// CleanupVerifiedFeature is a Feature verifying that the objects it creates are deleted
+// once its teardown steps have run.
+type CleanupVerifiedFeature interface {
+	Feature
+
+	// CleanupVerification returns true if the cleanup of the feature is verified
+	CleanupVerification() bool
+}
+
+// ProfiledFeature is a Feature requesting the test process to be profiled while it runs.
+type ProfiledFeature interface {
+	Feature
+
+	// Profile returns the directories the CPU and heap profiles of the feature are
+	// written to, an empty directory disabling the corresponding profile.
+	Profile() (cpuProfileDir, memProfileDir string)
+}
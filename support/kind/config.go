@@ -0,0 +1,117 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kind
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/e2e-framework/support"
+)
+
+// Node roles of a kind cluster
+const (
+	ControlPlaneRole = "control-plane"
+	WorkerRole       = "worker"
+)
+
+// Config is an inline kind cluster configuration, serialized to the kind configuration
+// format when the cluster is created, which avoids maintaining a configuration file
+// for the common settings. See https://kind.sigs.k8s.io/docs/user/configuration/.
+type Config struct {
+	// Nodes are the nodes of the cluster, a single control plane node being
+	// created when empty
+	Nodes []Node `json:"nodes,omitempty"`
+	// FeatureGates are the Kubernetes feature gates enabled or disabled cluster-wide
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+	// RuntimeConfig are the API server runtime-config settings, such as "api/alpha": "false"
+	RuntimeConfig map[string]string `json:"runtimeConfig,omitempty"`
+}
+
+// Node is a node of an inline kind cluster configuration
+type Node struct {
+	// Role is ControlPlaneRole or WorkerRole
+	Role string `json:"role"`
+	// Image is the node image of the node, overriding the image of the cluster
+	Image string `json:"image,omitempty"`
+	// Labels are the labels of the node
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Validate checks the configuration before the kind CLI is invoked, so that a
+// mistake is reported without waiting for the cluster creation to fail
+func (c Config) Validate() error {
+	var errs []error
+	controlPlanes := 0
+	for i, node := range c.Nodes {
+		switch node.Role {
+		case ControlPlaneRole:
+			controlPlanes++
+		case WorkerRole:
+		default:
+			errs = append(errs, fmt.Errorf("node %d: invalid role %q, expected %q or %q", i, node.Role, ControlPlaneRole, WorkerRole))
+		}
+	}
+	if len(c.Nodes) > 0 && controlPlanes == 0 {
+		errs = append(errs, fmt.Errorf("at least one node with the %q role is required", ControlPlaneRole))
+	}
+	return errors.Join(errs...)
+}
+
+// Marshal serializes the configuration to the kind configuration format
+func (c Config) Marshal() ([]byte, error) {
+	return yaml.Marshal(struct {
+		Kind       string `json:"kind"`
+		APIVersion string `json:"apiVersion"`
+		Config     `json:",inline"`
+	}{Kind: "Cluster", APIVersion: "kind.x-k8s.io/v1alpha4", Config: c})
+}
+
+// WithConfig creates the cluster with the inline configuration, which is validated before
+// the kind CLI is invoked. It cannot be combined with a configuration file passed to
+// CreateWithConfig or with a --config argument of Create.
+func WithConfig(config Config) support.ClusterOpts {
+	return func(c support.E2EClusterProvider) {
+		k, ok := c.(*Cluster)
+		if ok {
+			k.config = &config
+		}
+	}
+}
+
+// writeConfig writes the inline configuration to a temporary file, returning its path and a
+// function removing it
+func (k *Cluster) writeConfig() (string, func(), error) {
+	data, err := k.config.Marshal()
+	if err != nil {
+		return "", nil, fmt.Errorf("kind: config of cluster %q: %w", k.name, err)
+	}
+	file, err := os.CreateTemp("", fmt.Sprintf("kind-config-%s-*.yaml", k.name))
+	if err != nil {
+		return "", nil, fmt.Errorf("kind: config file: %w", err)
+	}
+	defer file.Close()
+	remove := func() { _ = os.Remove(file.Name()) }
+	if _, err := file.Write(data); err != nil {
+		remove()
+		return "", nil, fmt.Errorf("kind: config file: %w", err)
+	}
+	return file.Name(), remove, nil
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kind
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr []string
+	}{
+		{
+			name: "empty config",
+		},
+		{
+			name:   "control plane and workers",
+			config: Config{Nodes: []Node{{Role: ControlPlaneRole}, {Role: WorkerRole}, {Role: WorkerRole}}},
+		},
+		{
+			name:    "invalid role",
+			config:  Config{Nodes: []Node{{Role: ControlPlaneRole}, {Role: "master"}}},
+			wantErr: []string{`node 1: invalid role "master"`},
+		},
+		{
+			name:    "no control plane",
+			config:  Config{Nodes: []Node{{Role: WorkerRole}, {}}},
+			wantErr: []string{`node 1: invalid role ""`, `at least one node with the "control-plane" role is required`},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			err := test.config.Validate()
+			if len(test.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range test.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("expected error %q to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestConfig_Marshal(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		expected string
+	}{
+		{
+			name: "empty config",
+			expected: `apiVersion: kind.x-k8s.io/v1alpha4
+kind: Cluster
+`,
+		},
+		{
+			name: "full config",
+			config: Config{
+				Nodes: []Node{
+					{Role: ControlPlaneRole},
+					{Role: WorkerRole, Image: "kindest/node:v1.29.2", Labels: map[string]string{"zone": "a"}},
+				},
+				FeatureGates:  map[string]bool{"SidecarContainers": true},
+				RuntimeConfig: map[string]string{"api/alpha": "false"},
+			},
+			expected: `apiVersion: kind.x-k8s.io/v1alpha4
+featureGates:
+  SidecarContainers: true
+kind: Cluster
+nodes:
+- role: control-plane
+- image: kindest/node:v1.29.2
+  labels:
+    zone: a
+  role: worker
+runtimeConfig:
+  api/alpha: "false"
+`,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			data, err := test.config.Marshal()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != test.expected {
+				t.Errorf("expected config:\n%s\ngot:\n%s", test.expected, data)
+			}
+		})
+	}
+}
@@ -46,6 +46,7 @@ type Cluster struct {
 	kubecfgFile string
 	version     string
 	image       string
+	config      *Config
 	rc          *rest.Config
 }
 
@@ -142,28 +143,57 @@ func (k *Cluster) clusterExists(name string) (string, bool) {
 	return clusters, false
 }
 
+// CreateWithConfig creates the cluster with the kind configuration file, which must exist.
+// An inline configuration set with WithConfig is used when the file path is empty.
 func (k *Cluster) CreateWithConfig(ctx context.Context, kindConfigFile string) (string, error) {
 	var args []string
 	if kindConfigFile != "" {
+		if _, err := os.Stat(kindConfigFile); err != nil {
+			return "", fmt.Errorf("kind: config file of cluster %q: %w", k.name, err)
+		}
 		args = append(args, "--config", kindConfigFile)
 	}
 	return k.Create(ctx, args...)
 }
 
+// Create creates the cluster with the additional kind create cluster arguments, and
+// returns the path of its kubeconfig file. When a cluster with the same name already
+// exists, it is reused as is and args are ignored. A --config argument cannot be combined
+// with an inline configuration set with WithConfig.
 func (k *Cluster) Create(ctx context.Context, args ...string) (string, error) {
 	log.V(4).Info("Creating kind cluster ", k.name)
+	if k.config != nil {
+		if hasConfigArg(args) {
+			return "", fmt.Errorf("kind: cluster %q: a config file and an inline config cannot be combined", k.name)
+		}
+		if err := k.config.Validate(); err != nil {
+			return "", fmt.Errorf("kind: invalid config of cluster %q: %w", k.name, err)
+		}
+	}
 	if err := k.findOrInstallKind(); err != nil {
 		return "", err
 	}
 
 	if _, ok := k.clusterExists(k.name); ok {
 		log.V(4).Info("Skipping Kind Cluster.Create: cluster already created: ", k.name)
-		return k.getKubeconfig()
+		kConfig, err := k.getKubeconfig()
+		if err != nil {
+			return "", err
+		}
+		return kConfig, k.initKubernetesAccessClients()
 	}
 
 	if k.image != "" {
 		args = append(args, "--image", k.image)
 	}
+	if k.config != nil {
+		configFile, remove, err := k.writeConfig()
+		if err != nil {
+			return "", err
+		}
+		defer remove()
+		args = append(args, "--config", configFile)
+	}
 
 	command := fmt.Sprintf(`%s create cluster --name %s`, k.path, k.name)
 	if len(args) > 0 {
@@ -191,6 +221,16 @@ func (k *Cluster) Create(ctx context.Context, args ...string) (string, error) {
 	return kConfig, k.initKubernetesAccessClients()
 }
 
+// hasConfigArg reports whether the kind create cluster arguments set a configuration file
+func hasConfigArg(args []string) bool {
+	for _, arg := range args {
+		if arg == "--config" || strings.HasPrefix(arg, "--config=") {
+			return true
+		}
+	}
+	return false
+}
+
 func (k *Cluster) initKubernetesAccessClients() error {
 	cfg, err := conf.New(k.kubecfgFile)
 	if err != nil {
@@ -292,7 +332,7 @@ func (k *Cluster) WaitForControlPlane(ctx context.Context, client klient.Client)
 		if err != nil {
 			return err
 		}
-		err = wait.For(conditions.New(r).ResourceListN(&v1.PodList{}, len(sl.Values), resources.WithLabelSelector(selector.String())))
+		err = wait.For(conditions.New(r).ResourceListN(&v1.PodList{}, len(sl.Values), resources.WithLabelSelector(selector.String())), wait.WithContext(ctx))
 		if err != nil {
 			return err
 		}
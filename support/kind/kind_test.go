@@ -0,0 +1,194 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kind
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const fakeKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: kind-test
+  cluster:
+    server: https://127.0.0.1:6443
+contexts:
+- name: kind-test
+  context:
+    cluster: kind-test
+    user: kind-test
+current-context: kind-test
+users:
+- name: kind-test
+  user:
+    token: test
+`
+
+// fakeKind writes a kind script to a temporary directory that lists the given
+// clusters, registers the clusters it creates and logs each invocation to calls.
+func fakeKind(t *testing.T, clusters ...string) (path, calls string) {
+	t.Helper()
+	dir := t.TempDir()
+	path = filepath.Join(dir, "kind")
+	calls = filepath.Join(dir, "calls")
+	list := filepath.Join(dir, "clusters")
+	if err := os.WriteFile(list, []byte(strings.Join(clusters, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	kubeconfig := filepath.Join(dir, "kubeconfig")
+	if err := os.WriteFile(kubeconfig, []byte(fakeKubeconfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	script := fmt.Sprintf(`#!/bin/sh
+echo "$@" >> %[1]s
+case "$1 $2" in
+"get clusters") cat %[2]s ;;
+"get kubeconfig") cat %[3]s ;;
+"create cluster") echo "$4" >> %[2]s ;;
+esac
+`, calls, list, kubeconfig)
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path, calls
+}
+
+func TestCluster_Create(t *testing.T) {
+	tests := []struct {
+		name       string
+		existing   []string
+		wantCreate bool
+	}{
+		{
+			name:       "cluster is created when it does not exist",
+			existing:   []string{"other"},
+			wantCreate: true,
+		},
+		{
+			name:     "existing cluster is reused",
+			existing: []string{"other", "test"},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			path, calls := fakeKind(t, test.existing...)
+			k := NewCluster("test")
+			k.WithPath(path)
+			kubecfg, err := k.Create(context.TODO(), "--retain")
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { os.Remove(kubecfg) })
+
+			if kubecfg != k.GetKubeconfig() {
+				t.Errorf("expected the kubeconfig file %q, got %q", k.GetKubeconfig(), kubecfg)
+			}
+			if k.KubernetesRestConfig() == nil || k.KubernetesRestConfig().Host != "https://127.0.0.1:6443" {
+				t.Errorf("expected the rest config to be loaded from the kubeconfig, got %v", k.KubernetesRestConfig())
+			}
+
+			out, err := os.ReadFile(calls)
+			if err != nil {
+				t.Fatal(err)
+			}
+			created := strings.Contains(string(out), "create cluster --name test --retain")
+			if created != test.wantCreate {
+				t.Errorf("expected cluster creation to be %v, got calls:\n%s", test.wantCreate, out)
+			}
+		})
+	}
+}
+
+func TestCluster_Create_InlineConfig(t *testing.T) {
+	config := Config{Nodes: []Node{{Role: ControlPlaneRole}, {Role: WorkerRole}}}
+	tests := []struct {
+		name    string
+		create  func(k *Cluster) (string, error)
+		wantErr string
+	}{
+		{
+			name:   "inline config passed to kind",
+			create: func(k *Cluster) (string, error) { return k.Create(context.TODO()) },
+		},
+		{
+			name:    "config argument rejected",
+			create:  func(k *Cluster) (string, error) { return k.Create(context.TODO(), "--config", "kind.yaml") },
+			wantErr: "a config file and an inline config cannot be combined",
+		},
+		{
+			name:    "config argument with a value rejected",
+			create:  func(k *Cluster) (string, error) { return k.Create(context.TODO(), "--config=kind.yaml") },
+			wantErr: "a config file and an inline config cannot be combined",
+		},
+		{
+			name: "config file rejected",
+			create: func(k *Cluster) (string, error) {
+				file := filepath.Join(t.TempDir(), "kind.yaml")
+				if err := os.WriteFile(file, nil, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return k.CreateWithConfig(context.TODO(), file)
+			},
+			wantErr: "a config file and an inline config cannot be combined",
+		},
+		{
+			name: "invalid inline config rejected",
+			create: func(k *Cluster) (string, error) {
+				WithConfig(Config{Nodes: []Node{{Role: WorkerRole}}})(k)
+				return k.Create(context.TODO())
+			},
+			wantErr: "invalid config",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			path, calls := fakeKind(t)
+			k := NewCluster("test")
+			k.WithPath(path)
+			k.WithOpts(WithConfig(config))
+			kubecfg, err := test.create(k)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", test.wantErr, err)
+				}
+				if _, err := os.Stat(calls); !os.IsNotExist(err) {
+					t.Error("expected kind not to be invoked")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { os.Remove(kubecfg) })
+
+			out, err := os.ReadFile(calls)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(out), "create cluster --name test --config ") {
+				t.Errorf("expected the inline config to be passed to kind, got calls:\n%s", out)
+			}
+		})
+	}
+}